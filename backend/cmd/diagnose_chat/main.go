@@ -36,27 +36,7 @@ func main() {
 
 	fmt.Println("=== CHAT CONTACTS DIAGNOSTIC ===\n")
 
-	// 1. Check debug endpoint for all users
-	fmt.Println("1. Checking all users in database...")
-	resp, err := http.Get(baseURL + "/debug/users")
-	if err != nil {
-		fmt.Printf("   ERROR: Cannot reach debug endpoint: %v\n", err)
-	} else {
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == 200 {
-			var users []map[string]interface{}
-			json.Unmarshal(body, &users)
-			fmt.Printf("   Found %d users:\n", len(users))
-			for _, u := range users {
-				fmt.Printf("   - %s (%s) - Role: %s\n", u["name"], u["email"], u["role"])
-			}
-		} else {
-			fmt.Printf("   Status: %d, Body: %s\n", resp.StatusCode, string(body))
-		}
-	}
-
-	fmt.Println("\n2. Testing login and GetContacts for each user...")
+	fmt.Println("1. Testing login and GetContacts for each user...")
 
 	for _, testUser := range testUsers {
 		fmt.Printf("\n--- Testing: %s ---\n", testUser.email)
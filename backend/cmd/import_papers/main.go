@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/importer"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// Imports legacy publication records from a CSV or XLSX file.
+// Runs as a dry run unless -commit is given.
+//
+//	go run ./cmd/import_papers -file papers.xlsx -mapping '{"title":"Paper Title"}'
+//	go run ./cmd/import_papers -file papers.xlsx -mapping mapping.json -commit
+func main() {
+	file := flag.String("file", "", "CSV or XLSX file to import")
+	mapping := flag.String("mapping", "", "column mapping as a JSON object or a path to a JSON file")
+	calendar := flag.String("calendar", "gregorian", "calendar the dates are written in (gregorian or ethiopian)")
+	status := flag.String("status", "published", "status for rows without a status column")
+	commit := flag.Bool("commit", false, "import the rows instead of only validating them")
+	tenant := flag.String("tenant", "default", "slug of the tenant to import into")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	opts := importer.Options{Calendar: *calendar, DefaultStatus: *status}
+	if *mapping != "" {
+		raw := []byte(*mapping)
+		if data, err := os.ReadFile(*mapping); err == nil {
+			raw = data
+		}
+		if err := json.Unmarshal(raw, &opts.Mapping); err != nil {
+			log.Fatalf("Invalid mapping: %v", err)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	table, err := importer.ReadTable(f, *file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := config.New()
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	var tenantID uuid.UUID
+	if err := db.Pool.QueryRow(context.Background(), "SELECT id FROM tenants WHERE slug = $1", *tenant).Scan(&tenantID); err != nil {
+		log.Fatalf("Unknown tenant %q", *tenant)
+	}
+	ctx := database.WithTenant(context.Background(), tenantID)
+
+	report, err := importer.ImportPapers(ctx, db, table, opts, !*commit)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, row := range report.Rows {
+		author := "new placeholder"
+		if row.AuthorExists {
+			author = "existing user"
+		}
+		fmt.Printf("row %4d  %-60.60s  %s (%s)\n", row.Row, row.Title, row.AuthorEmail, author)
+		for _, e := range row.Errors {
+			fmt.Printf("          ✗ %s\n", e)
+		}
+	}
+
+	fmt.Printf("\n%d rows: %d valid, %d invalid\n", report.TotalRows, report.ValidRows, report.InvalidRows)
+	switch {
+	case report.DryRun:
+		fmt.Println("Dry run only, nothing was imported. Re-run with -commit to import.")
+	case report.InvalidRows > 0:
+		fmt.Println("Nothing was imported because some rows are invalid.")
+		os.Exit(1)
+	default:
+		fmt.Printf("Imported %d papers, created %d placeholder authors.\n", report.Imported, report.PlaceholderAuthors)
+	}
+}
@@ -1,40 +1,57 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/database"
+
+	"github.com/joho/godotenv"
 )
 
 func main() {
-	resp, err := http.Get("http://localhost:8080/debug/users")
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		fmt.Printf("Status: %d\nBody: %s\n", resp.StatusCode, string(body))
-		return
+	db, err := database.NewConnection(config.New())
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	// Lists the users of every tenant
+	rows, err := db.Pool.Query(database.WithoutTenant(context.Background()), `
+		SELECT u.id, u.name, u.email, u.role, t.slug
+		FROM users u JOIN tenants t ON t.id = u.tenant_id
+		ORDER BY t.slug, u.name
+	`)
+	if err != nil {
+		log.Fatal("Failed to query users:", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, name, email, role, tenant string
+		if err := rows.Scan(&id, &name, &email, &role, &tenant); err != nil {
+			log.Fatal(err)
+		}
+		count++
+		fmt.Printf("%d. %s (%s)\n", count, name, role)
+		fmt.Printf("   Email: %s\n", email)
+		fmt.Printf("   Tenant: %s\n", tenant)
+		fmt.Printf("   ID: %s\n\n", id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatal(err)
 	}
 
-	var users []map[string]interface{}
-	json.Unmarshal(body, &users)
-
-	fmt.Printf("Total users in database: %d\n\n", len(users))
-
-	if len(users) == 0 {
+	if count == 0 {
 		fmt.Println("⚠ NO USERS FOUND IN DATABASE!")
 		return
 	}
-
-	for i, u := range users {
-		fmt.Printf("%d. %s (%s)\n", i+1, u["name"], u["role"])
-		fmt.Printf("   Email: %s\n", u["email"])
-		fmt.Printf("   ID: %s\n\n", u["id"])
-	}
+	fmt.Printf("Total users in database: %d\n", count)
 }
@@ -28,7 +28,8 @@ func main() {
 	}
 	defer db.Close()
 
-	ctx := context.Background()
+	// Seed data goes to the default tenant
+	ctx := database.WithoutTenant(context.Background())
 
 	// Create users
 	users := []struct {
@@ -21,7 +21,7 @@ func main() {
 	}
 	defer db.Close()
 
-	rows, err := db.Pool.Query(context.Background(), "SELECT email, role FROM users")
+	rows, err := db.Pool.Query(database.WithoutTenant(context.Background()), "SELECT email, role FROM users")
 	if err != nil {
 		log.Fatal("Failed to query users:", err)
 	}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const accountDeactivated = "This account has been deactivated; please contact an administrator"
+
+// How long the link sent after an admin resets a password stays valid
+const forcedResetTTL = 24 * time.Hour
+
+// Accounts found active are not checked again for this long. Deactivating
+// clears the entry at once; other instances catch up within the TTL.
+const activeAccountTTL = 30 * time.Second
+
+type activeCache struct {
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+func (ac *activeCache) fresh(userID string) bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return time.Now().Before(ac.checked[userID])
+}
+
+func (ac *activeCache) put(userID string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ac.checked == nil || len(ac.checked) > 10000 {
+		ac.checked = map[string]time.Time{}
+	}
+	ac.checked[userID] = time.Now().Add(activeAccountTTL)
+}
+
+func (ac *activeCache) forget(userID string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	delete(ac.checked, userID)
+}
+
+// AccountActive implements middleware.AccountChecker. Deleted accounts are
+// not active either.
+func (s *Server) AccountActive(ctx context.Context, userID string) (bool, error) {
+	if s.activeAccounts.fresh(userID) {
+		return true, nil
+	}
+	var active bool
+	err := s.db.Pool.QueryRow(ctx, "SELECT deactivated_at IS NULL FROM users WHERE id = $1", userID).Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if active {
+		s.activeAccounts.put(userID)
+	}
+	return active, nil
+}
+
+const adminUserColumns = `
+	u.id, u.email, u.name, u.role, u.roles, COALESCE(u.avatar, ''), COALESCE(u.is_verified, FALSE), COALESCE(u.is_placeholder, FALSE),
+	u.org_unit_id, u.scope_org_unit_id, u.deactivated_at, u.last_login_at, u.created_at`
+
+func scanAdminUser(row pgx.Row, u *models.AdminUser, extra ...any) error {
+	return row.Scan(append([]any{&u.ID, &u.Email, &u.Name, &u.Role, &u.Roles, &u.Avatar, &u.IsVerified, &u.IsPlaceholder,
+		&u.OrgUnitID, &u.ScopeOrgUnitID, &u.DeactivatedAt, &u.LastLoginAt, &u.CreatedAt}, extra...)...)
+}
+
+// SearchUsers lists the tenant's users page by page. Filters: q (name or
+// email), role, org_unit_id and status (active, deactivated, placeholder).
+func (s *Server) SearchUsers(c *gin.Context) {
+	page, size := pageParams(c)
+
+	where := " WHERE TRUE"
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+		p := arg(pattern)
+		where += " AND (u.name ILIKE " + p + " OR u.email ILIKE " + p + ")"
+	}
+	if role := c.Query("role"); role != "" {
+		where += " AND " + arg(role) + " = ANY(u.roles)"
+	}
+	if v := c.Query("org_unit_id"); v != "" {
+		unitID, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid org_unit_id"})
+			return
+		}
+		where += " AND u.org_unit_id = " + arg(unitID)
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		where += " AND u.deactivated_at IS NULL AND NOT COALESCE(u.is_placeholder, FALSE)"
+	case "deactivated":
+		where += " AND u.deactivated_at IS NOT NULL"
+	case "placeholder":
+		where += " AND COALESCE(u.is_placeholder, FALSE)"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, deactivated or placeholder"})
+		return
+	}
+	limit, offset := arg(size), arg((page-1)*size)
+
+	rows, err := s.db.Pool.Query(c.Request.Context(),
+		"SELECT"+adminUserColumns+", COUNT(*) OVER () FROM users u"+where+
+			" ORDER BY u.name, u.email LIMIT "+limit+" OFFSET "+offset, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	defer rows.Close()
+
+	result := models.AdminUserPage{Users: []models.AdminUser{}, Page: page, PageSize: size}
+	for rows.Next() {
+		var u models.AdminUser
+		if err := scanAdminUser(rows, &u, &result.Total); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read users"})
+			return
+		}
+		result.Users = append(result.Users, u)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAdminUser returns everything admins can see about a user
+func (s *Server) GetAdminUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var u models.AdminUserDetail
+	var tenantID uuid.UUID
+	err = scanAdminUser(s.db.Pool.QueryRow(ctx, "SELECT"+adminUserColumns+`,
+			COALESCE(u.bio, ''), COALESCE(u.orcid, ''), u.preferences, u.totp_enabled, u.tenant_id,
+			(SELECT COUNT(DISTINCT family_id) FROM refresh_tokens t
+			 WHERE t.user_id = u.id AND t.revoked_at IS NULL AND t.used_at IS NULL AND t.expires_at > NOW())
+		FROM users u
+		WHERE u.id = $1
+	`, userID), &u.AdminUser, &u.Bio, &u.ORCID, &u.Preferences, &u.TOTPEnabled, &tenantID, &u.ActiveSessions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	if wait, err := s.accountLockout.Wait(ctx, accountLockoutKey(tenantID, u.Email)); err == nil {
+		u.SignInWait = int(math.Ceil(wait.Seconds()))
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// managedUser is the account an admin action applies to
+type managedUser struct {
+	id          uuid.UUID
+	email       string
+	name        string
+	roles       []string
+	placeholder bool
+	deactivated bool
+	lastLogin   *time.Time
+}
+
+// loadManagedUser loads the user of the :id parameter for an admin action,
+// responding with an error and returning false if the caller may not act on
+// them: admins cannot act on themselves, on super admins, or on users whose
+// roles carry permissions they do not hold
+func (s *Server) loadManagedUser(c *gin.Context) (*managedUser, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+	if userID.String() == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot do this to your own account"})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	u := managedUser{id: userID}
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT email, name, roles, COALESCE(is_placeholder, FALSE), deactivated_at IS NOT NULL, last_login_at
+		FROM users WHERE id = $1
+	`, userID).Scan(&u.email, &u.name, &u.roles, &u.placeholder, &u.deactivated, &u.lastLogin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return nil, false
+	}
+
+	for _, role := range u.roles {
+		perms, _, err := s.lookupRole(ctx, role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the user's roles"})
+			return nil, false
+		}
+		if role == rbac.RoleSuperAdmin || !rbac.Covers(c.GetStringSlice("permissions"), perms) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot manage a user with permissions you do not hold"})
+			return nil, false
+		}
+	}
+	return &u, true
+}
+
+// DeactivateUser stops a user from signing in and from using the tokens they
+// hold. Their papers, reviews and messages stay.
+func (s *Server) DeactivateUser(c *gin.Context) {
+	// The body with a reason is optional
+	var req models.DeactivateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, ok := s.loadManagedUser(c)
+	if !ok {
+		return
+	}
+	if u.deactivated {
+		c.JSON(http.StatusConflict, gin.H{"error": "The account is already deactivated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.auth.SetDisabled(ctx, u.id, true); err != nil {
+		log.Printf("Failed to disable account with the auth provider: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to deactivate the account with the authentication provider"})
+		return
+	}
+	if _, err := s.db.Pool.Exec(ctx,
+		"UPDATE users SET deactivated_at = NOW(), deactivated_by = $2, updated_at = NOW() WHERE id = $1",
+		u.id, c.GetString("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate user"})
+		return
+	}
+	s.activeAccounts.forget(u.id.String())
+	if err := s.revokeUserSessions(ctx, u.id); err != nil {
+		log.Printf("Failed to sign out deactivated user: %v", err)
+	}
+	s.audit(c, auditUserDeactivate, &u.id, gin.H{"reason": req.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deactivated"})
+}
+
+// ReactivateUser lets a deactivated user sign in again
+func (s *Server) ReactivateUser(c *gin.Context) {
+	u, ok := s.loadManagedUser(c)
+	if !ok {
+		return
+	}
+	if !u.deactivated {
+		c.JSON(http.StatusConflict, gin.H{"error": "The account is not deactivated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.auth.SetDisabled(ctx, u.id, false); err != nil {
+		log.Printf("Failed to enable account with the auth provider: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reactivate the account with the authentication provider"})
+		return
+	}
+	if _, err := s.db.Pool.Exec(ctx,
+		"UPDATE users SET deactivated_at = NULL, deactivated_by = NULL, updated_at = NOW() WHERE id = $1",
+		u.id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reactivate user"})
+		return
+	}
+	s.audit(c, auditUserReactivate, &u.id, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User reactivated"})
+}
+
+// ForcePasswordReset replaces a user's password with a random one, signs
+// them out everywhere and emails them a link to choose a new password
+func (s *Server) ForcePasswordReset(c *gin.Context) {
+	u, ok := s.loadManagedUser(c)
+	if !ok {
+		return
+	}
+	if u.placeholder {
+		c.JSON(http.StatusConflict, gin.H{"error": "Placeholder accounts have no password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	// Nobody knows the random password, so the old one stops working and
+	// only the emailed link gets the user back in
+	random, _, err := auth.NewToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := s.auth.SetPassword(ctx, u.id, random); err != nil {
+		log.Printf("Failed to reset password with the auth provider: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reset the password with the authentication provider"})
+		return
+	}
+	if err := s.revokeUserSessions(ctx, u.id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset, but existing sessions could not be signed out"})
+		return
+	}
+	s.audit(c, auditUserPasswordReset, &u.id, nil)
+
+	// A deactivated user could not use the link; once reactivated they can
+	// request one themselves
+	if u.deactivated {
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+		return
+	}
+	link, err := s.createPasswordReset(ctx, u.id, forcedResetTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset, but the link could not be created"})
+		return
+	}
+	if err := s.emailSender.ForContext(mailContext(c)).SendForcedPasswordResetEmail(u.email, link, forcedResetTTL); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Password reset, but the email could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset; the user has been emailed a link to choose a new one"})
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Lifetime of API tokens created without one
+const defaultAPITokenDays = 90
+
+// Characters of a token kept to tell tokens apart
+const apiTokenPrefixLength = 12
+
+// Service accounts need a unique email like any user; this domain can
+// never receive mail
+const serviceAccountDomain = "service-accounts.invalid"
+
+const apiTokenColumns = `
+	id, user_id, name, prefix, scopes, expires_at, last_used_at, COALESCE(last_used_ip, ''), created_by, created_at`
+
+func scanAPIToken(row pgx.Row, t *models.APIToken) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scopes, &t.ExpiresAt, &t.LastUsedAt, &t.LastUsedIP, &t.CreatedBy, &t.CreatedAt)
+}
+
+// ValidateAPIToken implements middleware.APITokenValidator. Use is
+// recorded at most once a minute per token and client address.
+func (s *Server) ValidateAPIToken(ctx context.Context, token, clientIP string) (*models.APITokenAuth, error) {
+	var t models.APITokenAuth
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT t.id, t.user_id, t.scopes, t.mfa, u.email, u.role, u.roles, u.tenant_id
+		FROM api_tokens t JOIN users u ON t.user_id = u.id
+		WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+	`, auth.HashToken(token)).Scan(&t.TokenID, &t.UserID, &t.Scopes, &t.MFA, &t.Email, &t.Role, &t.Roles, &t.TenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Pool.Exec(ctx, `
+		UPDATE api_tokens SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+	`, t.TokenID, clientIP); err != nil {
+		log.Printf("Failed to record API token use: %v", err)
+	}
+	return &t, nil
+}
+
+// listAPITokens lists the tokens of a user that are not revoked, expired
+// ones included
+func (s *Server) listAPITokens(c *gin.Context, userID uuid.UUID) {
+	rows, err := s.db.Pool.Query(c.Request.Context(),
+		"SELECT"+apiTokenColumns+" FROM api_tokens WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC",
+		userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var t models.APIToken
+		if err := scanAPIToken(rows, &t); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+			return
+		}
+		tokens = append(tokens, t)
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// createAPIToken creates a token of the user from the request body. Its
+// permission scopes must be among the permissions of the caller, who cannot
+// hand out more than they hold. The token passes the second factor policy
+// only if the caller's session completed a second factor.
+func (s *Server) createAPIToken(c *gin.Context, userID uuid.UUID) {
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scopes := rbac.Normalize(req.Scopes)
+	if err := rbac.ValidateScopes(scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, scope := range scopes {
+		if rbac.Known(scope) && !middleware.HasPermission(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you cannot grant %s, which you do not hold", scope)})
+			return
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPITokenDays
+	}
+
+	token, hash, err := auth.NewAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	created := models.CreatedAPIToken{Token: token}
+	err = scanAPIToken(s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at, created_by, mfa)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING`+apiTokenColumns,
+		userID, strings.TrimSpace(req.Name), hash, token[:apiTokenPrefixLength], scopes,
+		time.Now().AddDate(0, 0, days), c.GetString("user_id"), c.GetBool("mfa")), &created.APIToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	s.audit(c, auditAPITokenCreate, &userID, gin.H{"token_id": created.ID, "name": created.Name, "scopes": scopes})
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// revokeAPIToken revokes a token of the user named by the tokenId parameter
+func (s *Server) revokeAPIToken(c *gin.Context, userID uuid.UUID) {
+	tokenID, err := uuid.Parse(c.Param("tokenId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+	tag, err := s.db.Pool.Exec(c.Request.Context(),
+		"UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		tokenID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	s.audit(c, auditAPITokenRevoke, &userID, gin.H{"token_id": tokenID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// GetAPITokens lists the current user's API tokens
+func (s *Server) GetAPITokens(c *gin.Context) {
+	if id, ok := currentUserID(c); ok {
+		s.listAPITokens(c, id)
+	}
+}
+
+// CreateAPIToken creates an API token for the current user. The token is
+// in the response and cannot be seen again.
+func (s *Server) CreateAPIToken(c *gin.Context) {
+	if id, ok := currentUserID(c); ok {
+		s.createAPIToken(c, id)
+	}
+}
+
+// RevokeAPIToken revokes one of the current user's API tokens
+func (s *Server) RevokeAPIToken(c *gin.Context) {
+	if id, ok := currentUserID(c); ok {
+		s.revokeAPIToken(c, id)
+	}
+}
+
+// GetUserAPITokens lists the API tokens of a user or service account
+func (s *Server) GetUserAPITokens(c *gin.Context) {
+	if u, ok := s.loadManagedUser(c); ok {
+		s.listAPITokens(c, u.id)
+	}
+}
+
+// RevokeUserAPIToken revokes an API token of a user or service account
+func (s *Server) RevokeUserAPIToken(c *gin.Context) {
+	if u, ok := s.loadManagedUser(c); ok {
+		s.revokeAPIToken(c, u.id)
+	}
+}
+
+// GetServiceAccounts lists the tenant's service accounts
+func (s *Server) GetServiceAccounts(c *gin.Context) {
+	rows, err := s.db.Pool.Query(c.Request.Context(), `
+		SELECT u.id, u.name, u.role, u.roles, u.deactivated_at, u.created_at,
+			(SELECT COUNT(*) FROM api_tokens t WHERE t.user_id = u.id AND t.revoked_at IS NULL AND t.expires_at > NOW())
+		FROM users u
+		WHERE u.is_service_account
+		ORDER BY u.name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []models.ServiceAccount{}
+	for rows.Next() {
+		var a models.ServiceAccount
+		if err := rows.Scan(&a.ID, &a.Name, &a.Role, &a.Roles, &a.DeactivatedAt, &a.CreatedAt, &a.ActiveTokens); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+			return
+		}
+		accounts = append(accounts, a)
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+// CreateServiceAccount creates an account for another system with roles the
+// admin could give a user. It has no password and works through API tokens.
+func (s *Server) CreateServiceAccount(c *gin.Context) {
+	var req models.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	roles := withPrimaryRole(req.Role, req.Roles)
+	for _, role := range roles {
+		if status, err := s.assignableRole(c, role); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	a := models.ServiceAccount{ID: uuid.New(), Name: strings.TrimSpace(req.Name), Role: req.Role}
+	err := s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO users (id, email, password_hash, name, role, roles, preferences, is_verified, is_service_account)
+		VALUES ($1, $2, '', $3, $4, $5, '{}', TRUE, TRUE)
+		RETURNING roles, created_at
+	`, a.ID, a.ID.String()+"@"+serviceAccountDomain, a.Name, req.Role, roles).Scan(&a.Roles, &a.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+	s.audit(c, auditServiceAccountCreate, &a.ID, gin.H{"name": a.Name, "roles": a.Roles})
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// CreateServiceAccountToken creates an API token for a service account
+func (s *Server) CreateServiceAccountToken(c *gin.Context) {
+	u, ok := s.loadManagedUser(c)
+	if !ok {
+		return
+	}
+	var isService bool
+	if err := s.db.Pool.QueryRow(c.Request.Context(),
+		"SELECT is_service_account FROM users WHERE id = $1", u.id).Scan(&isService); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service account"})
+		return
+	}
+	if !isService {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Users create their own tokens; this is not a service account"})
+		return
+	}
+	if u.deactivated {
+		c.JSON(http.StatusConflict, gin.H{"error": "The service account is deactivated"})
+		return
+	}
+	s.createAPIToken(c, u.id)
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const appealQuery = `
+	SELECT a.id, a.paper_id, p.title, a.appellant_id, COALESCE(u.name, ''), a.reason, a.status, a.rejected_at,
+		   a.panel_assigned_by, a.panel_assigned_at, a.decided_by, COALESCE(a.decision_note, ''), a.decided_at,
+		   a.created_at, a.updated_at
+	FROM paper_appeals a
+	JOIN papers p ON a.paper_id = p.id
+	LEFT JOIN users u ON a.appellant_id = u.id
+`
+
+// appealWindow is how long after a rejection the author can appeal
+func (s *Server) appealWindow() time.Duration {
+	days, err := strconv.Atoi(s.config.AppealWindowDays)
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// loadAppeals runs appealQuery with the given condition and attaches each
+// appeal's panel
+func (s *Server) loadAppeals(ctx context.Context, where string, args ...interface{}) ([]models.Appeal, error) {
+	rows, err := s.db.Pool.Query(ctx, appealQuery+where+" ORDER BY a.created_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	appeals := []models.Appeal{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		var a models.Appeal
+		if err := rows.Scan(&a.ID, &a.PaperID, &a.PaperTitle, &a.AppellantID, &a.AppellantName, &a.Reason, &a.Status, &a.RejectedAt,
+			&a.PanelAssignedBy, &a.PanelAssignedAt, &a.DecidedBy, &a.DecisionNote, &a.DecidedAt,
+			&a.CreatedAt, &a.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Panel = []models.AppealPanelMember{}
+		index[a.ID] = len(appeals)
+		appeals = append(appeals, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(appeals) == 0 {
+		return appeals, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(appeals))
+	for _, a := range appeals {
+		ids = append(ids, a.ID)
+	}
+	panelRows, err := s.db.Pool.Query(ctx, `
+		SELECT m.appeal_id, u.id, u.name, u.email, u.role
+		FROM appeal_panel_members m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.appeal_id = ANY($1)
+		ORDER BY u.name
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer panelRows.Close()
+	for panelRows.Next() {
+		var appealID uuid.UUID
+		var m models.AppealPanelMember
+		if err := panelRows.Scan(&appealID, &m.UserID, &m.Name, &m.Email, &m.Role); err != nil {
+			return nil, err
+		}
+		i := index[appealID]
+		appeals[i].Panel = append(appeals[i].Panel, m)
+	}
+	return appeals, panelRows.Err()
+}
+
+func (s *Server) loadAppeal(ctx context.Context, appealID uuid.UUID) (*models.Appeal, error) {
+	appeals, err := s.loadAppeals(ctx, " WHERE a.id = $1", appealID)
+	if err != nil {
+		return nil, err
+	}
+	if len(appeals) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &appeals[0], nil
+}
+
+// FileAppeal lets the author appeal the rejection of their paper, once per
+// rejection and only within the appeal window
+func (s *Server) FileAppeal(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.CreateAppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	owns, err := s.callerOwnsPaper(c, paperID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if !owns {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the paper's author can appeal its rejection"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var authorID uuid.UUID
+	var title, status string
+	var rejectedAt *time.Time
+	err = s.db.Pool.QueryRow(ctx, "SELECT author_id, title, status, rejected_at FROM papers WHERE id = $1", paperID).
+		Scan(&authorID, &title, &status, &rejectedAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if status != "rejected" || rejectedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only rejected papers can be appealed"})
+		return
+	}
+	if deadline := rejectedAt.Add(s.appealWindow()); time.Now().After(deadline) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("The appeal window closed on %s", deadline.Format("2 January 2006"))})
+		return
+	}
+
+	var appealID uuid.UUID
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO paper_appeals (paper_id, appellant_id, reason, rejected_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (paper_id, rejected_at) DO NOTHING
+		RETURNING id
+	`, paperID, authorID, req.Reason, *rejectedAt).Scan(&appealID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An appeal has already been filed against this rejection"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to file appeal"})
+		return
+	}
+
+	appeal, err := s.loadAppeal(ctx, appealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load appeal"})
+		return
+	}
+
+	// Notify admins so they can assign a panel
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		message := fmt.Sprintf("An appeal has been filed against the rejection of '%s'", title)
+		s.db.Pool.Exec(ctx, `
+			INSERT INTO notifications (user_id, message, paper_id)
+			SELECT id, $1, $2 FROM users WHERE 'admin' = ANY(roles) AND id <> $3
+		`, message, paperID, appeal.AppellantID)
+	}()
+
+	c.JSON(http.StatusCreated, appeal)
+}
+
+// GetPaperAppeals returns every appeal filed for a paper, decided or not, for
+// its author, office staff and the appeal panels
+func (s *Server) GetPaperAppeals(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !middleware.HasPermission(c, rbac.PaperViewAll) {
+		var allowed bool
+		err := s.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM papers WHERE id = $1 AND author_id = $2)
+				OR EXISTS (SELECT 1 FROM appeal_panel_members m JOIN paper_appeals a ON m.appeal_id = a.id
+						   WHERE a.paper_id = $1 AND m.user_id = $2)
+		`, paperID, c.GetString("user_id")).Scan(&allowed)
+		if err != nil || !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot view the appeals of this paper"})
+			return
+		}
+	}
+
+	appeals, err := s.loadAppeals(ctx, " WHERE a.paper_id = $1", paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appeals"})
+		return
+	}
+	c.JSON(http.StatusOK, appeals)
+}
+
+// GetAppeals lists appeals: every appeal for admins, otherwise the ones the
+// caller filed or sits on the panel of. ?status= filters by status.
+func (s *Server) GetAppeals(c *gin.Context) {
+	where := " WHERE ($1 = '' OR a.status = $1)"
+	args := []interface{}{c.Query("status")}
+	if !middleware.HasPermission(c, rbac.AppealManage) {
+		where += ` AND (a.appellant_id = $2 OR EXISTS (
+			SELECT 1 FROM appeal_panel_members m WHERE m.appeal_id = a.id AND m.user_id = $2))`
+		args = append(args, c.GetString("user_id"))
+	}
+
+	appeals, err := s.loadAppeals(c.Request.Context(), where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appeals"})
+		return
+	}
+	c.JSON(http.StatusOK, appeals)
+}
+
+// AssignAppealPanel sets the editors and admins who decide an appeal. The
+// panel can be changed until it has recorded a decision.
+func (s *Server) AssignAppealPanel(c *gin.Context) {
+	appealID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appeal ID"})
+		return
+	}
+
+	var req models.AssignAppealPanelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	appeal, err := s.loadAppeal(ctx, appealID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Appeal not found"})
+		return
+	}
+	if appeal.Status != models.AppealPending && appeal.Status != models.AppealPanelAssigned {
+		c.JSON(http.StatusConflict, gin.H{"error": "The appeal has already been decided"})
+		return
+	}
+
+	members := map[uuid.UUID]bool{}
+	for _, id := range req.MemberIDs {
+		if id == appeal.AppellantID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The appellant cannot sit on the panel"})
+			return
+		}
+		members[id] = true
+	}
+	ids := make([]uuid.UUID, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	deciders, err := s.rolesWithPermission(ctx, rbac.AppealDecide)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check panel members"})
+		return
+	}
+	// Authors of the paper cannot judge its appeal, whatever other roles
+	// they hold
+	var eligible int
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM users u
+		WHERE u.id = ANY($1) AND u.roles && $2
+		  AND NOT EXISTS (SELECT 1 FROM papers p WHERE p.id = $3 AND `+paperAuthoredBy+`)
+	`, ids, deciders, appeal.PaperID).Scan(&eligible)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check panel members"})
+		return
+	}
+	if eligible != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Panel members must be existing users who can decide appeals and did not write the paper"})
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign panel"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE paper_appeals
+		SET status = 'panel_assigned', panel_assigned_by = $2, panel_assigned_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'panel_assigned')
+	`, appealID, c.GetString("user_id"))
+	if err != nil || tag.RowsAffected() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The appeal has already been decided"})
+		return
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM appeal_panel_members WHERE appeal_id = $1", appealID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign panel"})
+		return
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO appeal_panel_members (appeal_id, user_id)
+		SELECT $1, unnest($2::uuid[])
+	`, appealID, ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign panel"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign panel"})
+		return
+	}
+
+	appeal, err = s.loadAppeal(ctx, appealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load appeal"})
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		message := fmt.Sprintf("You have been assigned to the appeal panel for '%s'", appeal.PaperTitle)
+		for _, id := range ids {
+			s.db.Pool.Exec(ctx,
+				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+				id, message, appeal.PaperID)
+		}
+	}()
+
+	c.JSON(http.StatusOK, appeal)
+}
+
+// DecideAppeal records the panel's decision. Reopening sends the paper back to
+// under_review; upholding leaves the rejection in place.
+func (s *Server) DecideAppeal(c *gin.Context) {
+	appealID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appeal ID"})
+		return
+	}
+
+	var req models.AppealDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var onPanel bool
+	err = s.db.Pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM appeal_panel_members WHERE appeal_id = $1 AND user_id = $2)",
+		appealID, c.GetString("user_id")).Scan(&onPanel)
+	if err != nil || !onPanel {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the appeal panel can decide the appeal"})
+		return
+	}
+
+	status := models.AppealUpheld
+	if req.Decision == "reopen" {
+		status = models.AppealReopened
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var paperID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		UPDATE paper_appeals
+		SET status = $2, decided_by = $3, decision_note = $4, decided_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'panel_assigned'
+		RETURNING paper_id
+	`, appealID, status, c.GetString("user_id"), req.Note).Scan(&paperID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The appeal has already been decided"})
+		return
+	}
+
+	if status == models.AppealReopened {
+		tag, err := tx.Exec(ctx, `
+			UPDATE papers SET status = 'under_review', updated_at = NOW()
+			WHERE id = $1 AND status = 'rejected'
+		`, paperID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reopen paper"})
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The paper is no longer rejected"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	appeal, err := s.loadAppeal(ctx, appealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load appeal"})
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		message := fmt.Sprintf("Your appeal for '%s' was not successful; the rejection stands", appeal.PaperTitle)
+		if status == models.AppealReopened {
+			message = fmt.Sprintf("Your appeal for '%s' was successful; the paper is under review again", appeal.PaperTitle)
+		}
+		s.db.Pool.Exec(ctx,
+			"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+			appeal.AppellantID, message, appeal.PaperID)
+	}()
+
+	c.JSON(http.StatusOK, appeal)
+}
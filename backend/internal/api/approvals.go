@@ -0,0 +1,479 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/orgunit"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// approverMatch is the condition under which user u may decide approval step
+// a. Users who may decide any step, such as admins, do not need it. A step
+// without a role is open to staff of its unit.
+const approverMatch = `((a.approver_role IS NULL AND u.roles <> ARRAY['author']::TEXT[]) OR a.approver_role = ANY(u.roles))
+	AND (a.org_unit_id IS NULL OR a.org_unit_id IN (u.org_unit_id, u.scope_org_unit_id))`
+
+const paperApprovalQuery = `
+	SELECT a.id, a.paper_id, p.title, COALESCE(p.type, 'Research Paper'), a.round, a.position, a.step_name,
+		   COALESCE(a.approver_role, ''), a.org_unit_id, COALESCE(o.name, ''), a.status,
+		   a.decided_by, COALESCE(d.name, ''), COALESCE(a.comment, ''), a.decided_at, a.activated_at, a.created_at
+	FROM paper_approvals a
+	JOIN papers p ON a.paper_id = p.id
+	LEFT JOIN org_units o ON a.org_unit_id = o.id
+	LEFT JOIN users d ON a.decided_by = d.id
+`
+
+func (s *Server) queryPaperApprovals(ctx context.Context, query string, args ...interface{}) ([]models.PaperApproval, error) {
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []models.PaperApproval{}
+	for rows.Next() {
+		var a models.PaperApproval
+		if err := rows.Scan(&a.ID, &a.PaperID, &a.PaperTitle, &a.PaperType, &a.Round, &a.Position, &a.StepName,
+			&a.ApproverRole, &a.OrgUnitID, &a.OrgUnitName, &a.Status,
+			&a.DecidedBy, &a.DeciderName, &a.Comment, &a.DecidedAt, &a.ActivatedAt, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+func (s *Server) loadApprovalChains(ctx context.Context, where string, args ...interface{}) ([]models.ApprovalChain, error) {
+	rows, err := s.db.Pool.Query(ctx, "SELECT id, paper_type, name, created_at, updated_at FROM approval_chains"+where+" ORDER BY paper_type", args...)
+	if err != nil {
+		return nil, err
+	}
+	chains := []models.ApprovalChain{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		var chain models.ApprovalChain
+		if err := rows.Scan(&chain.ID, &chain.PaperType, &chain.Name, &chain.CreatedAt, &chain.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		chain.Steps = []models.ApprovalChainStep{}
+		index[chain.ID] = len(chains)
+		chains = append(chains, chain)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(chains) == 0 {
+		return chains, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chains))
+	for _, chain := range chains {
+		ids = append(ids, chain.ID)
+	}
+	stepRows, err := s.db.Pool.Query(ctx, `
+		SELECT chain_id, id, position, name, COALESCE(approver_role, ''), org_unit_id, COALESCE(unit_kind, '')
+		FROM approval_chain_steps
+		WHERE chain_id = ANY($1)
+		ORDER BY chain_id, position
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer stepRows.Close()
+	for stepRows.Next() {
+		var chainID uuid.UUID
+		var step models.ApprovalChainStep
+		if err := stepRows.Scan(&chainID, &step.ID, &step.Position, &step.Name, &step.ApproverRole, &step.OrgUnitID, &step.UnitKind); err != nil {
+			return nil, err
+		}
+		i := index[chainID]
+		chains[i].Steps = append(chains[i].Steps, step)
+	}
+	return chains, stepRows.Err()
+}
+
+// approvalPending reports whether a paper has an approval round in progress
+func (s *Server) approvalPending(ctx context.Context, paperID uuid.UUID) (bool, error) {
+	var pending bool
+	err := s.db.Pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM paper_approvals WHERE paper_id = $1 AND status IN ('waiting', 'pending'))",
+		paperID).Scan(&pending)
+	return pending, err
+}
+
+// startApprovalChain opens a new approval round for a submitted paper when
+// its type has an approval chain. Steps tied to a kind of unit are resolved
+// against the paper's unit; when the paper has no such unit and the step no
+// role, the step falls to admins. Nothing happens while a round is open.
+func (s *Server) startApprovalChain(ctx context.Context, paperID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var paperType, title string
+	var unitID *uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(p.type, 'Research Paper'), p.title, COALESCE(p.org_unit_id, u.org_unit_id)
+		FROM papers p LEFT JOIN users u ON p.author_id = u.id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, paperID).Scan(&paperType, &title, &unitID)
+	if err != nil {
+		return err
+	}
+
+	var open bool
+	if err := tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM paper_approvals WHERE paper_id = $1 AND status IN ('waiting', 'pending'))",
+		paperID).Scan(&open); err != nil || open {
+		return err
+	}
+
+	chains, err := s.loadApprovalChains(ctx, " WHERE paper_type = $1", paperType)
+	if err != nil || len(chains) == 0 || len(chains[0].Steps) == 0 {
+		return err
+	}
+
+	var units []models.OrgUnit
+	for _, step := range chains[0].Steps {
+		if step.UnitKind != "" {
+			if units, err = s.loadOrgUnits(ctx); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	var round int
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(round), 0) + 1 FROM paper_approvals WHERE paper_id = $1", paperID).Scan(&round); err != nil {
+		return err
+	}
+
+	var firstID uuid.UUID
+	for i, step := range chains[0].Steps {
+		role, stepUnit := step.ApproverRole, step.OrgUnitID
+		if step.UnitKind != "" {
+			stepUnit = nil
+			if unitID != nil {
+				stepUnit = orgunit.Ancestor(units, *unitID, step.UnitKind)
+			}
+			if stepUnit == nil && role == "" {
+				role = "admin"
+			}
+		}
+
+		status := models.ApprovalWaiting
+		if i == 0 {
+			status = models.ApprovalPending
+		}
+		var id uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO paper_approvals (paper_id, round, position, step_name, approver_role, org_unit_id, status, activated_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, CASE WHEN $7 = 'pending' THEN NOW() END)
+			RETURNING id
+		`, paperID, round, i+1, step.Name, role, stepUnit, status).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			firstID = id
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	go s.notifyApprovers(context.WithoutCancel(ctx), firstID, fmt.Sprintf("Paper '%s' is awaiting your approval", title))
+	return nil
+}
+
+// notifyApprovers notifies the users who can decide an approval step. Admins
+// are only notified for steps assigned to the admin role.
+func (s *Server) notifyApprovers(ctx context.Context, approvalID uuid.UUID, message string) {
+	s.db.Pool.Exec(ctx, `
+		INSERT INTO notifications (user_id, message, paper_id)
+		SELECT u.id, $2, a.paper_id
+		FROM paper_approvals a
+		JOIN papers p ON a.paper_id = p.id
+		JOIN users u ON u.id <> p.author_id AND `+approverMatch+`
+		WHERE a.id = $1
+	`, approvalID, message)
+}
+
+// GetApprovalChains lists the configured approval chains
+func (s *Server) GetApprovalChains(c *gin.Context) {
+	chains, err := s.loadApprovalChains(c.Request.Context(), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval chains"})
+		return
+	}
+	c.JSON(http.StatusOK, chains)
+}
+
+// SaveApprovalChain creates the approval chain of a paper type, or replaces
+// its steps. Rounds already under way keep the steps they started with.
+func (s *Server) SaveApprovalChain(c *gin.Context) {
+	var req models.ApprovalChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	for i, step := range req.Steps {
+		if step.ApproverRole == "" {
+			continue
+		}
+		if _, ok, err := s.lookupRole(ctx, step.ApproverRole); err != nil || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("step %d: unknown approver role %q", i+1, step.ApproverRole)})
+			return
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save approval chain"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var chainID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO approval_chains (paper_type, name) VALUES ($1, $2)
+		ON CONFLICT (tenant_id, paper_type) DO UPDATE SET name = EXCLUDED.name, updated_at = NOW()
+		RETURNING id
+	`, req.PaperType, req.Name).Scan(&chainID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save approval chain"})
+		return
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM approval_chain_steps WHERE chain_id = $1", chainID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save approval chain"})
+		return
+	}
+	for i, step := range req.Steps {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO approval_chain_steps (chain_id, position, name, approver_role, org_unit_id, unit_kind)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''))
+		`, chainID, i+1, strings.TrimSpace(step.Name), step.ApproverRole, step.OrgUnitID, step.UnitKind); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("step %d: unit not found", i+1)})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save approval chain"})
+		return
+	}
+
+	chains, err := s.loadApprovalChains(ctx, " WHERE id = $1", chainID)
+	if err != nil || len(chains) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load approval chain"})
+		return
+	}
+	c.JSON(http.StatusOK, chains[0])
+}
+
+// DeleteApprovalChain removes a paper type's chain. Papers already in an
+// approval round finish it.
+func (s *Server) DeleteApprovalChain(c *gin.Context) {
+	chainID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval chain ID"})
+		return
+	}
+
+	tag, err := s.db.Pool.Exec(c.Request.Context(), "DELETE FROM approval_chains WHERE id = $1", chainID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete approval chain"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval chain not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Approval chain deleted successfully"})
+}
+
+// GetPaperApprovals returns every approval round of a paper, oldest first
+func (s *Server) GetPaperApprovals(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	approvals, err := s.queryPaperApprovals(c.Request.Context(),
+		paperApprovalQuery+" WHERE a.paper_id = $1 ORDER BY a.round, a.position", paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvals"})
+		return
+	}
+	c.JSON(http.StatusOK, approvals)
+}
+
+// GetApprovalQueue lists the approval steps awaiting the caller, oldest first
+func (s *Server) GetApprovalQueue(c *gin.Context) {
+	approvals, err := s.queryPaperApprovals(c.Request.Context(), paperApprovalQuery+`
+		JOIN users u ON u.id = $1
+		WHERE a.status = 'pending' AND NOT `+paperAuthoredBy+` AND ($2 OR `+approverMatch+`)
+		ORDER BY a.activated_at ASC
+	`, c.GetString("user_id"), middleware.HasPermission(c, rbac.ApprovalDecideAny))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval queue"})
+		return
+	}
+	c.JSON(http.StatusOK, approvals)
+}
+
+// DecideApproval records the caller's decision on a pending step. Approving
+// hands the paper to the next step, or on the last step sends it to review.
+// Rejecting rejects the paper; returning sends it back to the author as a
+// draft, and resubmitting starts a new round.
+func (s *Server) DecideApproval(c *gin.Context) {
+	approvalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid approval ID"})
+		return
+	}
+
+	var req models.ApprovalDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Decision != "approve" && strings.TrimSpace(req.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A comment is required to reject or return a paper"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var paperID, authorID uuid.UUID
+	var round, position int
+	var title string
+	err = tx.QueryRow(ctx, `
+		SELECT a.paper_id, a.round, a.position, p.author_id, p.title
+		FROM paper_approvals a
+		JOIN papers p ON a.paper_id = p.id
+		JOIN users u ON u.id = $2
+		WHERE a.id = $1 AND a.status = 'pending' AND NOT `+paperAuthoredBy+` AND ($3 OR `+approverMatch+`)
+		FOR UPDATE OF a
+	`, approvalID, c.GetString("user_id"), middleware.HasPermission(c, rbac.ApprovalDecideAny)).Scan(&paperID, &round, &position, &authorID, &title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending approval step for you with this ID"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	status := map[string]string{
+		"approve": models.ApprovalApproved,
+		"reject":  models.ApprovalRejected,
+		"return":  models.ApprovalReturned,
+	}[req.Decision]
+	if _, err := tx.Exec(ctx, `
+		UPDATE paper_approvals SET status = $2, decided_by = $3, comment = NULLIF($4, ''), decided_at = NOW()
+		WHERE id = $1
+	`, approvalID, status, c.GetString("user_id"), req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	var nextID *uuid.UUID
+	var authorMessage string
+	switch status {
+	case models.ApprovalApproved:
+		var id uuid.UUID
+		err := tx.QueryRow(ctx, `
+			UPDATE paper_approvals SET status = 'pending', activated_at = NOW()
+			WHERE id = (SELECT id FROM paper_approvals WHERE paper_id = $1 AND round = $2 AND position > $3 ORDER BY position LIMIT 1)
+			RETURNING id
+		`, paperID, round, position).Scan(&id)
+		switch {
+		case err == nil:
+			nextID = &id
+		case errors.Is(err, pgx.ErrNoRows):
+			// Last step: the paper goes on to review
+			if _, err := tx.Exec(ctx, `
+				UPDATE papers SET status = 'under_review', updated_at = NOW()
+				WHERE id = $1 AND status = 'submitted'
+			`, paperID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to advance paper"})
+				return
+			}
+			authorMessage = fmt.Sprintf("Your paper '%s' has been approved at every step and is now under review", title)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to advance paper"})
+			return
+		}
+	case models.ApprovalRejected, models.ApprovalReturned:
+		if _, err := tx.Exec(ctx, `
+			UPDATE paper_approvals SET status = 'cancelled'
+			WHERE paper_id = $1 AND round = $2 AND status = 'waiting'
+		`, paperID, round); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+			return
+		}
+		paperUpdate := "UPDATE papers SET status = 'draft', updated_at = NOW() WHERE id = $1"
+		authorMessage = fmt.Sprintf("Your paper '%s' was returned for changes: %s", title, req.Comment)
+		if status == models.ApprovalRejected {
+			paperUpdate = "UPDATE papers SET status = 'rejected', rejected_at = NOW(), updated_at = NOW() WHERE id = $1"
+			authorMessage = fmt.Sprintf("Your paper '%s' was rejected during approval: %s", title, req.Comment)
+		}
+		if _, err := tx.Exec(ctx, paperUpdate, paperID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update paper"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if nextID != nil {
+			s.notifyApprovers(ctx, *nextID, fmt.Sprintf("Paper '%s' is awaiting your approval", title))
+		}
+		if authorMessage != "" {
+			s.db.Pool.Exec(ctx,
+				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+				authorID, authorMessage, paperID)
+		}
+	}()
+
+	approvals, err := s.queryPaperApprovals(ctx, paperApprovalQuery+" WHERE a.id = $1", approvalID)
+	if err != nil || len(approvals) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Decision recorded, but failed to load it"})
+		return
+	}
+	c.JSON(http.StatusOK, approvals[0])
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	auditUserRoles            = "user.roles"
+	auditUserOrgUnit          = "user.org_unit"
+	auditUserUnlock           = "user.unlock"
+	auditUserDeactivate       = "user.deactivate"
+	auditUserReactivate       = "user.reactivate"
+	auditUserPasswordReset    = "user.password_reset"
+	auditUserImport           = "user.import"
+	auditUserSSOProvision     = "user.sso_provision"
+	auditUserSSOLink          = "user.sso_link"
+	auditInvitationCreate     = "invitation.create"
+	auditInvitationResend     = "invitation.resend"
+	auditInvitationRevoke     = "invitation.revoke"
+	auditInvitationAccept     = "invitation.accept"
+	auditAPITokenCreate       = "api_token.create"
+	auditAPITokenRevoke       = "api_token.revoke"
+	auditServiceAccountCreate = "service_account.create"
+	auditRoleCreate           = "role.create"
+	auditRoleUpdate           = "role.update"
+	auditRoleDelete           = "role.delete"
+)
+
+// audit records an action of the signed-in user. A failure to record is
+// logged rather than undoing the action, which has already happened.
+func (s *Server) audit(c *gin.Context, action string, target *uuid.UUID, details gin.H) {
+	if details == nil {
+		details = gin.H{}
+	}
+	var actor *uuid.UUID
+	if id, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		actor = &id
+	}
+	_, err := s.db.Pool.Exec(c.Request.Context(), `
+		INSERT INTO audit_log (actor_id, action, target_user_id, details, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+	`, actor, action, target, details, c.ClientIP())
+	if err != nil {
+		log.Printf("Failed to record %s in the audit log: %v", action, err)
+	}
+}
+
+// pageParams reads page (from 1) and page_size (up to 100, default 25)
+func pageParams(c *gin.Context) (page, size int) {
+	page, _ = strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	size, _ = strconv.Atoi(c.Query("page_size"))
+	if size < 1 {
+		size = 25
+	}
+	return page, min(size, 100)
+}
+
+// GetAuditLog lists audit entries of the tenant, newest first. Filters:
+// user_id (the account acted on), actor_id and action.
+func (s *Server) GetAuditLog(c *gin.Context) {
+	page, size := pageParams(c)
+
+	where := " WHERE TRUE"
+	var args []interface{}
+	for _, f := range []struct{ param, column string }{
+		{"user_id", "a.target_user_id"},
+		{"actor_id", "a.actor_id"},
+	} {
+		if v := c.Query(f.param); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + f.param})
+				return
+			}
+			args = append(args, id)
+			where += " AND " + f.column + " = $" + strconv.Itoa(len(args))
+		}
+	}
+	if action := c.Query("action"); action != "" {
+		args = append(args, action)
+		where += " AND a.action = $" + strconv.Itoa(len(args))
+	}
+	args = append(args, size, (page-1)*size)
+
+	rows, err := s.db.Pool.Query(c.Request.Context(), `
+		SELECT a.id, a.actor_id, COALESCE(actor.name, ''), a.action, a.target_user_id, COALESCE(target.name, ''),
+			   a.details, COALESCE(a.ip_address, ''), a.created_at, COUNT(*) OVER ()
+		FROM audit_log a
+		LEFT JOIN users actor ON a.actor_id = actor.id
+		LEFT JOIN users target ON a.target_user_id = target.id
+	`+where+`
+		ORDER BY a.created_at DESC
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	defer rows.Close()
+
+	result := models.AuditLogPage{Entries: []models.AuditEntry{}, Page: page, PageSize: size}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetUserID, &e.TargetName,
+			&e.Details, &e.IPAddress, &e.CreatedAt, &result.Total); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read audit log"})
+			return
+		}
+		result.Entries = append(result.Entries, e)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/ethcal"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestCalendar returns the calendar requested through the "calendar" query
+// parameter. Gregorian is the default.
+func requestCalendar(c *gin.Context) (string, error) {
+	cal := strings.ToLower(c.DefaultQuery("calendar", ethcal.CalendarGregorian))
+	if cal != ethcal.CalendarGregorian && cal != ethcal.CalendarEthiopian {
+		return "", fmt.Errorf("invalid calendar %q: must be gregorian or ethiopian", cal)
+	}
+	return cal, nil
+}
+
+// localizePaperDates fills the Ethiopian date fields of a paper when the
+// caller asked for the Ethiopian calendar.
+func localizePaperDates(cal string, paper *models.Paper) {
+	if cal != ethcal.CalendarEthiopian {
+		return
+	}
+	if !paper.CreatedAt.IsZero() {
+		paper.CreatedAtEthiopian = ethcal.FromGregorian(paper.CreatedAt).String()
+	}
+	if paper.PublicationDate != nil && !paper.PublicationDate.IsZero() {
+		paper.PublicationDateEthiopian = ethcal.FromGregorian(*paper.PublicationDate).String()
+	}
+}
+
+// resolvePublicationDate converts an Ethiopian publication date on the request
+// into the Gregorian PublicationDate the database stores.
+func resolvePublicationDate(cal string, req *models.UpdatePaperRequest) error {
+	if req.PublicationDateEthiopian == "" {
+		if cal == ethcal.CalendarEthiopian && !req.PublicationDate.IsZero() {
+			return fmt.Errorf("publication_date_ethiopian is required when calendar=ethiopian")
+		}
+		return nil
+	}
+	d, err := ethcal.Parse(req.PublicationDateEthiopian)
+	if err != nil {
+		return err
+	}
+	req.PublicationDate = d.ToGregorian()
+	return nil
+}
+
+// derivePaperFiscalYear returns the Ethiopian fiscal year label for a paper,
+// taken from the publication date when known and the submission date otherwise.
+func derivePaperFiscalYear(publicationDate *time.Time, submittedAt time.Time) string {
+	if publicationDate != nil && !publicationDate.IsZero() {
+		return ethcal.FiscalYearLabel(ethcal.FiscalYear(*publicationDate))
+	}
+	if submittedAt.IsZero() {
+		return ""
+	}
+	return ethcal.FiscalYearLabel(ethcal.FiscalYear(submittedAt))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"time"
 
 	"rpms-backend/internal/database"
@@ -157,47 +158,52 @@ func (h *ChatHandler) GetMessages(c *gin.Context) {
 }
 
 // GetContacts retrieves list of users the current user can chat with
+// contactRoles lists, per role, the roles its holders can chat with
+var contactRoles = map[string][]string{
+	// Author can chat with Editor and Coordinator
+	"author": {"editor", "coordinator"},
+	// Editor can chat with Author, Coordinator, and Admin
+	"editor": {"author", "coordinator", "admin"},
+	// Coordinator can chat with Author, Editor, and Admin
+	"coordinator": {"author", "editor", "admin"},
+	// Admin can chat with Editor and Coordinator
+	"admin": {"editor", "coordinator"},
+}
+
 func (h *ChatHandler) GetContacts(c *gin.Context) {
 	userID := c.GetString("user_id")
-	userRole := c.GetString("role")
-
-	logDebug("GetContacts called. UserID: %s, Role: %s", userID, userRole)
-
-	var roleFilter string
-
-	// Define allowed roles based on current user role
-	switch userRole {
-	case "author":
-		// Author can chat with Editor and Coordinator
-		roleFilter = "('editor', 'coordinator')"
-	case "editor":
-		// Editor can chat with Author, Coordinator, and Admin
-		roleFilter = "('author', 'coordinator', 'admin')"
-	case "coordinator":
-		// Coordinator can chat with Author, Editor, and Admin
-		roleFilter = "('author', 'editor', 'admin')"
-	case "admin":
-		// Admin can chat with Editor and Coordinator
-		roleFilter = "('editor', 'coordinator')"
-	default:
-		logDebug("Invalid role: %s", userRole)
+	userRoles := c.GetStringSlice("roles")
+
+	logDebug("GetContacts called. UserID: %s, Roles: %v", userID, userRoles)
+
+	// Users with several roles can chat with everyone any of them allows
+	var allowed []string
+	for _, role := range userRoles {
+		for _, r := range contactRoles[role] {
+			if !slices.Contains(allowed, r) {
+				allowed = append(allowed, r)
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		logDebug("No contacts for roles: %v", userRoles)
 		c.JSON(http.StatusOK, []models.Contact{})
 		return
 	}
 
-	logDebug("Role filter: %s", roleFilter)
+	logDebug("Contact roles: %v", allowed)
 
 	query := `
-		SELECT id, name, email, role, avatar
+		SELECT id, name, email, role, roles, avatar
 		FROM users
-		WHERE role IN ` + roleFilter + ` AND id != $1
+		WHERE roles && $2 AND id != $1
 		ORDER BY name ASC
 	`
 
 	logDebug("Executing query: %s", query)
 	logDebug("With userID parameter: %s", userID)
 
-	rows, err := h.db.Query(c.Request.Context(), query, userID)
+	rows, err := h.db.Query(c.Request.Context(), query, userID, allowed)
 	if err != nil {
 		logDebug("Query failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
@@ -210,7 +216,7 @@ func (h *ChatHandler) GetContacts(c *gin.Context) {
 	for rows.Next() {
 		rowCount++
 		var contact models.Contact
-		if err := rows.Scan(&contact.ID, &contact.Name, &contact.Email, &contact.Role, &contact.Avatar); err != nil {
+		if err := rows.Scan(&contact.ID, &contact.Name, &contact.Email, &contact.Role, &contact.Roles, &contact.Avatar); err != nil {
 			logDebug("Scan failed for row %d: %v", rowCount, err)
 			continue
 		}
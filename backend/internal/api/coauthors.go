@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/orcid"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// callerOwnsPaper reports whether the authenticated user may manage the paper:
+// admins may manage any paper, everybody else only their own.
+func (s *Server) callerOwnsPaper(c *gin.Context, paperID uuid.UUID) (bool, error) {
+	if middleware.HasPermission(c, rbac.PaperManageAll) {
+		return true, nil
+	}
+	var authorID uuid.UUID
+	err := s.db.Pool.QueryRow(c.Request.Context(), "SELECT author_id FROM papers WHERE id = $1", paperID).Scan(&authorID)
+	if err != nil {
+		return false, err
+	}
+	return authorID.String() == c.GetString("user_id"), nil
+}
+
+// paperAuthoredBy is the condition under which user u wrote paper p, as its
+// author or a linked co-author. Nobody reviews, approves or judges an appeal
+// of their own paper, whatever other roles they hold.
+const paperAuthoredBy = `(p.author_id = u.id OR EXISTS (
+	SELECT 1 FROM paper_coauthors pc WHERE pc.paper_id = p.id AND pc.user_id = u.id))`
+
+// isPaperAuthor reports whether the user wrote the paper
+func (s *Server) isPaperAuthor(ctx context.Context, paperID uuid.UUID, userID string) (bool, error) {
+	var authored bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM papers p JOIN users u ON u.id = $2 WHERE p.id = $1 AND `+paperAuthoredBy+`)
+	`, paperID, userID).Scan(&authored)
+	return authored, err
+}
+
+func (s *Server) loadCoAuthors(ctx context.Context, paperID uuid.UUID) ([]models.CoAuthor, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, paper_id, user_id, name, COALESCE(email, ''), COALESCE(affiliation, ''), COALESCE(orcid, ''), position, created_at
+		FROM paper_coauthors
+		WHERE paper_id = $1
+		ORDER BY position ASC
+	`, paperID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coAuthors := []models.CoAuthor{}
+	for rows.Next() {
+		var ca models.CoAuthor
+		if err := rows.Scan(&ca.ID, &ca.PaperID, &ca.UserID, &ca.Name, &ca.Email, &ca.Affiliation, &ca.ORCID, &ca.Position, &ca.CreatedAt); err != nil {
+			return nil, err
+		}
+		coAuthors = append(coAuthors, ca)
+	}
+	return coAuthors, rows.Err()
+}
+
+// GetCoAuthors lists the co-authors of a paper in author order
+func (s *Server) GetCoAuthors(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	coAuthors, err := s.loadCoAuthors(c.Request.Context(), paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch co-authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coAuthors)
+}
+
+// SetCoAuthors replaces the co-author list of a paper. Co-authors whose email
+// matches an RPMS account are linked to it and inherit its ORCID iD when none is given.
+func (s *Server) SetCoAuthors(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.SetCoAuthorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range req.CoAuthors {
+		if req.CoAuthors[i].ORCID == "" {
+			continue
+		}
+		req.CoAuthors[i].ORCID, err = orcid.Normalize(req.CoAuthors[i].ORCID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	owns, err := s.callerOwnsPaper(c, paperID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if !owns {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit co-authors of your own papers"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update co-authors"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM paper_coauthors WHERE paper_id = $1", paperID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update co-authors"})
+		return
+	}
+
+	for i, input := range req.CoAuthors {
+		var userID *uuid.UUID
+		if input.Email != "" {
+			var id uuid.UUID
+			var userORCID string
+			err := tx.QueryRow(ctx, "SELECT id, COALESCE(orcid, '') FROM users WHERE LOWER(email) = LOWER($1)", input.Email).Scan(&id, &userORCID)
+			if err == nil {
+				userID = &id
+				if input.ORCID == "" {
+					input.ORCID = userORCID
+				}
+			} else if err != pgx.ErrNoRows {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up co-author"})
+				return
+			}
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO paper_coauthors (paper_id, user_id, name, email, affiliation, orcid, position)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7)
+		`, paperID, userID, strings.TrimSpace(input.Name), strings.ToLower(input.Email), input.Affiliation, input.ORCID, i+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save co-author"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update co-authors"})
+		return
+	}
+
+	coAuthors, err := s.loadCoAuthors(ctx, paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch co-authors"})
+		return
+	}
+	c.JSON(http.StatusOK, coAuthors)
+}
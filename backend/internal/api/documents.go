@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/documents"
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const generatedDocumentColumns = `id, serial_number, kind, paper_id, recipient_id, recipient_name, paper_title, issued_by, issued_at,
+	revoked_at, COALESCE(revocation_reason, '')`
+
+func scanGeneratedDocument(row interface{ Scan(...interface{}) error }, doc *models.GeneratedDocument) error {
+	return row.Scan(&doc.ID, &doc.SerialNumber, &doc.Kind, &doc.PaperID, &doc.RecipientID, &doc.RecipientName, &doc.PaperTitle, &doc.IssuedBy, &doc.IssuedAt,
+		&doc.RevokedAt, &doc.RevocationReason)
+}
+
+// canViewPaperDocuments reports whether the caller may see documents issued for
+// a paper: office staff always, authors for their own papers, recipients for theirs.
+func (s *Server) canViewPaperDocuments(ctx context.Context, c *gin.Context, paperID uuid.UUID) bool {
+	if middleware.HasPermission(c, rbac.PaperViewAll) {
+		return true
+	}
+	var allowed bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM papers WHERE id = $1 AND author_id = $2)
+			OR EXISTS (SELECT 1 FROM generated_documents WHERE paper_id = $1 AND recipient_id = $2)
+	`, paperID, c.GetString("user_id")).Scan(&allowed)
+	return err == nil && allowed
+}
+
+// GenerateDocument issues an acceptance letter or publication certificate for a
+// paper, stores the rendered PDF and returns its record.
+func (s *Server) GenerateDocument(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.GenerateDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tmpl := documents.Templates[req.Kind]
+
+	issuerID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var status string
+	var recipientID *uuid.UUID
+	data := documents.Data{Kind: req.Kind, IssuedAt: time.Now()}
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT p.status, p.title, COALESCE(p.publication_title_amharic, ''), COALESCE(p.publication_id, ''),
+			   COALESCE(p.journal_name, ''), p.publication_date, p.author_id, COALESCE(u.name, '')
+		FROM papers p
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE p.id = $1
+	`, paperID).Scan(&status, &data.PaperTitle, &data.PaperTitleAmharic, &data.PublicationID,
+		&data.JournalName, &data.PublicationDate, &recipientID, &data.RecipientName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+
+	if !tmpl.AllowedFor(status) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A %s cannot be issued for a paper that is %s", tmpl.Title, status)})
+		return
+	}
+
+	if req.CoAuthorID != nil {
+		err := s.db.Pool.QueryRow(ctx,
+			"SELECT user_id, name FROM paper_coauthors WHERE id = $1 AND paper_id = $2",
+			*req.CoAuthorID, paperID,
+		).Scan(&recipientID, &data.RecipientName)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Co-author not found on this paper"})
+			return
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue document"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var seq int64
+	if err := tx.QueryRow(ctx, "SELECT nextval('document_serial_seq')").Scan(&seq); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate serial number"})
+		return
+	}
+	data.Serial, err = documents.Serial(s.config.Documents.SerialPrefix, req.Kind, data.IssuedAt, seq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	signature := documents.Sign([]byte(s.config.Documents.SigningKey), req.Kind, data.Serial, data.RecipientName, data.PaperTitle, data.IssuedAt)
+	data.VerifyURL = documents.VerifyURL(s.config.Documents.VerifyURL, data.Serial, signature)
+
+	var pdf bytes.Buffer
+	if err := documents.Render(&pdf, data, s.documentBranding(c)); err != nil {
+		log.Printf("Failed to render document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render document"})
+		return
+	}
+
+	var doc models.GeneratedDocument
+	err = scanGeneratedDocument(tx.QueryRow(ctx, `
+		INSERT INTO generated_documents (serial_number, kind, paper_id, recipient_id, recipient_name, paper_title, issued_by, issued_at, content, signature)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+generatedDocumentColumns,
+		data.Serial, req.Kind, paperID, recipientID, data.RecipientName, data.PaperTitle, issuerID, data.IssuedAt, pdf.Bytes(), signature,
+	), &doc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue document"})
+		return
+	}
+
+	if recipientID != nil {
+		go func(userID uuid.UUID) {
+			ctx := context.WithoutCancel(ctx)
+			s.db.Pool.Exec(ctx,
+				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+				userID, "Your "+tmpl.Title+" for \""+data.PaperTitle+"\" is ready to download", paperID)
+		}(*recipientID)
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+// GetPaperDocuments lists the documents issued for a paper
+func (s *Server) GetPaperDocuments(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !s.canViewPaperDocuments(ctx, c, paperID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	rows, err := s.db.Pool.Query(ctx, "SELECT "+generatedDocumentColumns+" FROM generated_documents WHERE paper_id = $1 ORDER BY issued_at DESC", paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		return
+	}
+	defer rows.Close()
+
+	docs := []models.GeneratedDocument{}
+	for rows.Next() {
+		var doc models.GeneratedDocument
+		if err := scanGeneratedDocument(rows, &doc); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan document"})
+			return
+		}
+		docs = append(docs, doc)
+	}
+
+	c.JSON(http.StatusOK, docs)
+}
+
+// DownloadPaperDocument serves the stored PDF of an issued document
+func (s *Server) DownloadPaperDocument(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+	docID, err := uuid.Parse(c.Param("docId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !s.canViewPaperDocuments(ctx, c, paperID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var serial string
+	var content []byte
+	err = s.db.Pool.QueryRow(ctx,
+		"SELECT serial_number, content FROM generated_documents WHERE id = $1 AND paper_id = $2",
+		docID, paperID,
+	).Scan(&serial, &content)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, serial))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,7 +14,11 @@ import (
 	"rpms-backend/internal/config"
 	"rpms-backend/internal/database"
 	"rpms-backend/internal/email"
+	"rpms-backend/internal/ethcal"
+	"rpms-backend/internal/lockout"
 	"rpms-backend/internal/models"
+	"rpms-backend/internal/orcid"
+	"rpms-backend/internal/sso"
 	"rpms-backend/internal/supabase"
 
 	"github.com/gin-gonic/gin"
@@ -25,16 +31,100 @@ type Server struct {
 	jwtManager  *auth.JWTManager
 	config      *config.Config
 	emailSender *email.EmailSender
-	supabase    *supabase.Client
+	auth        auth.AuthProvider
+	tenants     *tenantCache
+	roles       *roleCache
+	// Accounts recently found not deactivated
+	activeAccounts *activeCache
+
+	// Password reset requests per client IP and per email address
+	resetsByIP    *rateLimiter
+	resetsByEmail *rateLimiter
+	// Failed sign-ins per account and per client address, and wrong second
+	// factor codes per user
+	accountLockout lockout.Tracker
+	ipLockout      lockout.Tracker
+	mfaLockout     lockout.Tracker
+
+	// Single sign-on; nil when not configured
+	oidc       *sso.OIDC
+	ldap       *sso.LDAP
+	groupRoles sso.GroupRoles
 }
 
 func NewServer(db *database.Database, cfg *config.Config) *Server {
+	emailSender := email.NewEmailSender(cfg)
+	provider, err := auth.NewProvider(cfg, db, emailSender)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var accountLockout, ipLockout, mfaLockout lockout.Tracker
+	switch cfg.LoginTracker {
+	case "", "memory":
+		accountLockout = lockout.NewMemoryTracker(lockout.AccountPolicy)
+		ipLockout = lockout.NewMemoryTracker(lockout.IPPolicy)
+		mfaLockout = lockout.NewMemoryTracker(lockout.MFAPolicy)
+	case "postgres":
+		accountLockout = lockout.NewPostgresTracker(db, "account", lockout.AccountPolicy)
+		ipLockout = lockout.NewPostgresTracker(db, "ip", lockout.IPPolicy)
+		mfaLockout = lockout.NewPostgresTracker(db, "mfa", lockout.MFAPolicy)
+	default:
+		log.Fatalf("unknown LOGIN_TRACKER %q (use memory or postgres)", cfg.LoginTracker)
+	}
+
+	groupRoles, err := sso.ParseGroupRoles(cfg.SSO.GroupRoles)
+	if err != nil {
+		log.Fatalf("invalid SSO_GROUP_ROLES: %v", err)
+	}
+	var oidc *sso.OIDC
+	if cfg.SSO.OIDCIssuer != "" {
+		oidc = sso.NewOIDC(sso.OIDCConfig{
+			Issuer:               cfg.SSO.OIDCIssuer,
+			ClientID:             cfg.SSO.OIDCClientID,
+			ClientSecret:         cfg.SSO.OIDCClientSecret,
+			RedirectURL:          cfg.SSO.OIDCRedirectURL,
+			Scopes:               strings.Fields(cfg.SSO.OIDCScopes),
+			GroupsClaim:          cfg.SSO.OIDCGroupsClaim,
+			TrustUnverifiedEmail: cfg.SSO.OIDCTrustUnverifiedEmail,
+		}, nil)
+	}
+	var ldap *sso.LDAP
+	if cfg.SSO.LDAPURL != "" {
+		ldap = sso.NewLDAP(sso.LDAPConfig{
+			URL:            cfg.SSO.LDAPURL,
+			StartTLS:       cfg.SSO.LDAPStartTLS,
+			BindDN:         cfg.SSO.LDAPBindDN,
+			BindPassword:   cfg.SSO.LDAPBindPassword,
+			BaseDN:         cfg.SSO.LDAPBaseDN,
+			UserFilter:     cfg.SSO.LDAPUserFilter,
+			EmailAttribute: cfg.SSO.LDAPEmailAttribute,
+			NameAttribute:  cfg.SSO.LDAPNameAttribute,
+			GroupAttribute: cfg.SSO.LDAPGroupAttribute,
+		})
+	}
+
 	return &Server{
 		db:          db,
 		jwtManager:  auth.NewJWTManager(cfg),
 		config:      cfg,
-		emailSender: email.NewEmailSender(cfg),
-		supabase:    supabase.NewClient(cfg),
+		emailSender: emailSender,
+		auth:        provider,
+		tenants:     &tenantCache{},
+		roles:       &roleCache{},
+
+		activeAccounts: &activeCache{},
+
+		resetsByIP:    newRateLimiter(10, time.Hour),
+		resetsByEmail: newRateLimiter(3, time.Hour),
+
+		accountLockout: accountLockout,
+		ipLockout:      ipLockout,
+		mfaLockout:     mfaLockout,
+
+		oidc:       oidc,
+		ldap:       ldap,
+		groupRoles: groupRoles,
 	}
 }
 
@@ -47,14 +137,7 @@ func (s *Server) Register(c *gin.Context) {
 		return
 	}
 
-	// Hash password (we might still want to store it locally later, or just rely on Supabase)
-	// hashedPassword, err := auth.HashPassword(req.Password)
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-	// 	return
-	// }
-
-	// Prepare user metadata for Supabase
+	// Profile fields travel with the registration until it is verified
 	metadata := map[string]interface{}{
 		"name":            req.Name,
 		"role":            "author",
@@ -68,17 +151,23 @@ func (s *Server) Register(c *gin.Context) {
 		"date_of_birth":   req.DateOfBirth,
 	}
 
-	// Register with Supabase
-	_, err := s.supabase.SignUp(req.Email, req.Password, metadata)
+	err := s.auth.SignUp(mailContext(c), req.Email, req.Password, metadata)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to register with Supabase: %v", err)})
+		switch {
+		case errors.Is(err, auth.ErrEmailTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrTooSoon):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to register: %v", err)})
+		}
 		return
 	}
 
 	// We DO NOT insert into local DB yet. We wait for verification.
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Registration successful. Please check your email for the verification code from Supabase.",
+		"message": "Registration successful. Please check your email for the verification code.",
 		"email":   req.Email,
 	})
 }
@@ -90,10 +179,9 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	// Verify with Supabase
-	sbUser, err := s.supabase.Verify(req.Email, strings.TrimSpace(req.Code))
+	identity, err := s.auth.Verify(c.Request.Context(), req.Email, strings.TrimSpace(req.Code))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Supabase verification failed: %v", err)})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Verification failed: %v", err)})
 		return
 	}
 
@@ -102,19 +190,19 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 
 	// Check if user exists in local DB
 	query := `
-		SELECT id, email, password_hash, name, role, avatar, bio, preferences, created_at, updated_at
+		SELECT id, email, password_hash, name, role, roles, avatar, bio, preferences, created_at, updated_at, tenant_id
 		FROM users
-		WHERE email = $1
+		WHERE LOWER(email) = LOWER($1)
 	`
 
 	err = s.db.Pool.QueryRow(ctx, query, req.Email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.Roles, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt, &user.TenantID,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			// User not found, insert them now
-			meta := sbUser.UserMetadata
+			meta := identity.Metadata
 
 			// Helper to safely get string from metadata
 			getString := func(key string) string {
@@ -124,8 +212,8 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 				return ""
 			}
 
-			user.ID = uuid.MustParse(sbUser.ID)
-			user.Email = sbUser.Email
+			user.ID = identity.ID
+			user.Email = identity.Email
 			user.Name = getString("name")
 			user.Role = getString("role")
 			if user.Role == "" {
@@ -153,18 +241,16 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 					academic_year, author_type, author_category, academic_rank, qualification, employment_type, gender, date_of_birth
 				)
 				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
-				RETURNING created_at, updated_at
+				RETURNING created_at, updated_at, tenant_id
 			`
 
-			// Note: password_hash is empty since we don't have it here.
-			// If we want to keep it, we'd need to pass it in metadata or just rely on Supabase.
-			// For now, we'll store an empty string or a placeholder.
-			user.PasswordHash = ""
+			// Empty unless the provider keeps passwords locally
+			user.PasswordHash = identity.PasswordHash
 
 			err = s.db.Pool.QueryRow(ctx, insertQuery,
 				user.ID, user.Email, user.PasswordHash, user.Name, user.Role, user.Avatar, user.Bio, user.Preferences, user.IsVerified, user.VerificationCode,
 				user.AcademicYear, user.AuthorType, user.AuthorCategory, user.AcademicRank, user.Qualification, user.EmploymentType, user.Gender, user.DateOfBirth,
-			).Scan(&user.CreatedAt, &user.UpdatedAt)
+			).Scan(&user.CreatedAt, &user.UpdatedAt, &user.TenantID)
 
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create user in local database: %v", err)})
@@ -175,13 +261,14 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 			return
 		}
 	} else {
-		// User exists, just update verification status
+		// User exists (e.g. a placeholder), just update verification status
 		updateQuery := `
 			UPDATE users
-			SET is_verified = TRUE, verification_code = ''
+			SET is_verified = TRUE, verification_code = '', is_placeholder = FALSE,
+				password_hash = COALESCE(NULLIF($2, ''), password_hash)
 			WHERE id = $1
 		`
-		_, err = s.db.Pool.Exec(ctx, updateQuery, user.ID)
+		_, err = s.db.Pool.Exec(ctx, updateQuery, user.ID, identity.PasswordHash)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status in local database"})
 			return
@@ -189,18 +276,13 @@ func (s *Server) VerifyEmail(c *gin.Context) {
 		user.IsVerified = true
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(&user)
+	// Start a session: an access token and the first refresh token of a family
+	response, err := s.issueSession(ctx, &user, false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	response := models.LoginResponse{
-		User:  user,
-		Token: token,
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
@@ -211,10 +293,14 @@ func (s *Server) ResendVerificationCode(c *gin.Context) {
 		return
 	}
 
-	// Resend with Supabase
-	err := s.supabase.Resend(req.Email)
+	err := s.auth.ResendCode(mailContext(c), req.Email)
 	if err != nil {
-		if sbErr, ok := err.(*supabase.SupabaseError); ok {
+		if errors.Is(err, auth.ErrTooSoon) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait a few seconds before requesting another code."})
+			return
+		}
+		var sbErr *supabase.SupabaseError
+		if errors.As(err, &sbErr) {
 			if sbErr.StatusCode == 429 {
 				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait a few seconds before requesting another code."})
 				return
@@ -222,11 +308,11 @@ func (s *Server) ResendVerificationCode(c *gin.Context) {
 			c.JSON(sbErr.StatusCode, gin.H{"error": sbErr.Message})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to resend code via Supabase: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to resend code: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Verification code resent successfully via Supabase"})
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code resent successfully"})
 }
 
 func (s *Server) Login(c *gin.Context) {
@@ -236,46 +322,83 @@ func (s *Server) Login(c *gin.Context) {
 		return
 	}
 
-	// Authenticate with Supabase
-	fmt.Printf("Attempting Supabase SignIn for: %s\n", req.Email)
-	sbResp, err := s.supabase.SignIn(req.Email, req.Password)
-	if err != nil {
-		fmt.Printf("Supabase SignIn failed: %v\n", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials (Supabase)"})
+	ctx := c.Request.Context()
+	accountKey := accountLockoutKey(currentTenant(c).ID, req.Email)
+	if !s.loginAllowed(c, accountKey) {
+		return
+	}
+	if _, err := s.auth.SignIn(ctx, req.Email, req.Password); err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			s.recordLoginFailure(c, accountKey, req.Email)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		// An outage is not a failed attempt and is not counted
+		if errors.Is(err, auth.ErrUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sign-in is unavailable at the moment; please try again shortly"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
 		return
 	}
-	fmt.Printf("Supabase SignIn successful. User ID: %s\n", sbResp.User.ID)
 
-	ctx := c.Request.Context()
 	var user models.User
+	var totpEnabled, deactivated bool
+	err := scanSignInUser(s.db.Pool.QueryRow(ctx, signInUserQuery+" WHERE LOWER(email) = LOWER($1)", req.Email), &user, &totpEnabled, &deactivated)
+	if err != nil {
+		fmt.Printf("Local DB lookup failed: %v\n", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in local database"})
+		return
+	}
+	if err := s.accountLockout.Reset(ctx, accountKey); err != nil {
+		log.Printf("Failed to reset sign-in failures: %v", err)
+	}
 
-	// Fetch user details from local DB
-	query := `
-		SELECT id, email, password_hash, name, role, avatar, bio, preferences, created_at, updated_at
-		FROM users
-		WHERE email = $1
-	`
+	s.completeSignIn(c, &user, totpEnabled, deactivated)
+}
 
-	err = s.db.Pool.QueryRow(ctx, query, req.Email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt,
+// signInUserQuery loads what signing a user in needs
+const signInUserQuery = `
+	SELECT id, email, password_hash, name, role, roles, avatar, bio, preferences, created_at, updated_at, tenant_id, totp_enabled,
+		deactivated_at IS NOT NULL
+	FROM users`
+
+func scanSignInUser(row pgx.Row, user *models.User, totpEnabled, deactivated *bool) error {
+	return row.Scan(
+		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.Roles, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt, &user.TenantID,
+		totpEnabled, deactivated,
 	)
+}
 
-	if err != nil {
-		fmt.Printf("Local DB lookup failed: %v\n", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in local database"})
+// completeSignIn responds to a user who proved who they are, by password or
+// through single sign-on, with a session or the second factor challenge
+func (s *Server) completeSignIn(c *gin.Context, user *models.User, totpEnabled, deactivated bool) {
+	if deactivated {
+		c.JSON(http.StatusForbidden, gin.H{"error": accountDeactivated})
 		return
 	}
 
-	// Generate JWT token (local)
-	token, err := s.jwtManager.GenerateToken(&user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// With two-factor authentication on, the password only earns a
+	// challenge that POST /auth/mfa/verify trades for a session
+	if totpEnabled {
+		challenge, err := s.jwtManager.GenerateMFAChallenge(user.ID.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, models.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+			ExpiresIn:   int(s.jwtManager.MFAChallengeExpiry().Seconds()),
+		})
 		return
 	}
 
-	response := models.LoginResponse{
-		User:  user,
-		Token: token,
+	// Start a session: an access token and the first refresh token of a family
+	response, err := s.issueSession(c.Request.Context(), user, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -288,13 +411,14 @@ func (s *Server) GetProfile(c *gin.Context) {
 	var user models.User
 
 	query := `
-		SELECT id, email, name, role, avatar, bio, preferences, created_at, updated_at
+		SELECT id, email, name, role, roles, avatar, bio, preferences, created_at, updated_at, COALESCE(orcid, ''), org_unit_id, scope_org_unit_id, tenant_id
 		FROM users
 		WHERE id = $1
 	`
 
 	err := s.db.Pool.QueryRow(ctx, query, userID).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Role, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Roles, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt, &user.ORCID,
+		&user.OrgUnitID, &user.ScopeOrgUnitID, &user.TenantID,
 	)
 
 	if err != nil {
@@ -319,17 +443,25 @@ func (s *Server) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	if req.ORCID != "" {
+		req.ORCID, err = orcid.Normalize(req.ORCID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	ctx := c.Request.Context()
 	query := `
 		UPDATE users
-		SET name = $1, avatar = $2, bio = $3, preferences = $4, updated_at = NOW()
-		WHERE id = $5
-		RETURNING id, email, name, role, avatar, bio, preferences, created_at, updated_at
+		SET name = $1, avatar = $2, bio = $3, preferences = $4, orcid = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $6
+		RETURNING id, email, name, role, avatar, bio, preferences, created_at, updated_at, COALESCE(orcid, '')
 	`
 
 	var user models.User
-	err = s.db.Pool.QueryRow(ctx, query, req.Name, req.Avatar, req.Bio, req.Preferences, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Role, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt,
+	err = s.db.Pool.QueryRow(ctx, query, req.Name, req.Avatar, req.Bio, req.Preferences, req.ORCID, id).Scan(
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Avatar, &user.Bio, &user.Preferences, &user.CreatedAt, &user.UpdatedAt, &user.ORCID,
 	)
 
 	if err != nil {
@@ -354,33 +486,24 @@ func (s *Server) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	// Get current password hash
-	var currentHash string
-	err = s.db.Pool.QueryRow(ctx, "SELECT password_hash FROM users WHERE id = $1", id).Scan(&currentHash)
+	userEmail, _ := c.Get("email")
+	err = s.auth.ChangePassword(c.Request.Context(), id, userEmail.(string), req.OldPassword, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	// Verify old password
-	if !auth.CheckPassword(req.OldPassword, currentHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid old password"})
-		return
-	}
-
-	// Hash new password
-	newHash, err := auth.HashPassword(req.NewPassword)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash new password"})
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid old password"})
+			return
+		}
+		if errors.Is(err, auth.ErrUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sign-in is unavailable at the moment; please try again shortly"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
-	// Update password
-	_, err = s.db.Pool.Exec(ctx, "UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", newHash, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	// Sign out every other session
+	if err := s.revokeUserSessions(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password updated, but existing sessions could not be signed out"})
 		return
 	}
 
@@ -409,15 +532,23 @@ func (s *Server) DeleteAccount(c *gin.Context) {
 func (s *Server) GetPapers(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	cal, err := requestCalendar(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	query := `
 		SELECT p.id, p.title, COALESCE(p.abstract, ''), COALESCE(p.content, ''), COALESCE(p.file_url, ''), p.author_id, p.status, COALESCE(p.type, 'Research Paper'), p.created_at, p.updated_at,
-			   COALESCE(p.institution_code, ''), COALESCE(p.publication_id, ''), COALESCE(p.publication_isced_band, ''), COALESCE(p.publication_title_amharic, ''),
-			   p.publication_date, COALESCE(p.publication_type, ''), COALESCE(p.journal_type, ''), COALESCE(p.journal_name, ''), COALESCE(p.indigenous_knowledge, false),
+			   COALESCE(p.org_unit_id, u.org_unit_id), COALESCE(p.institution_code, ''), COALESCE(p.publication_id, ''), COALESCE(p.publication_isced_band, ''), COALESCE(p.publication_title_amharic, ''),
+			   p.publication_date, COALESCE(p.publication_type, ''), COALESCE(p.journal_type, ''), COALESCE(p.journal_name, ''), COALESCE(p.indigenous_knowledge, false), COALESCE(p.doi, ''),
 			   COALESCE(p.fiscal_year, ''), COALESCE(p.allocated_budget, 0), COALESCE(p.external_budget, 0), COALESCE(p.nrf_fund, 0),
 			   COALESCE(p.research_type, ''), COALESCE(p.completion_status, ''), COALESCE(p.female_researchers, 0), COALESCE(p.male_researchers, 0),
 			   COALESCE(p.outside_female_researchers, 0), COALESCE(p.outside_male_researchers, 0), COALESCE(p.benefited_industry, ''),
 			   COALESCE(p.ethical_clearance, ''), COALESCE(p.pi_name, ''), COALESCE(p.pi_gender, ''), COALESCE(p.co_investigators, ''),
 			   COALESCE(p.produced_prototype, ''), COALESCE(p.hetril_collaboration, ''), COALESCE(p.submitted_to_incubator, ''),
+			   p.scheduled_publish_at, p.embargo_until,
+			   p.rejected_at, p.withdrawn_at, COALESCE(p.withdrawal_reason, ''), p.retracted_at, COALESCE(p.retraction_notice, ''),
 			   COALESCE(u.name, 'Unknown'), COALESCE(u.email, ''), COALESCE(u.academic_year, ''),
 			   COALESCE(u.author_type, ''), COALESCE(u.author_category, ''),
 			   COALESCE(u.academic_rank, ''), COALESCE(u.qualification, ''),
@@ -425,10 +556,22 @@ func (s *Server) GetPapers(c *gin.Context) {
 			   COALESCE(u.bio, ''), COALESCE(u.avatar, '')
 		FROM papers p
 		LEFT JOIN users u ON p.author_id = u.id
-		ORDER BY p.created_at DESC
 	`
 
-	rows, err := s.db.Pool.Query(ctx, query)
+	// Scoped editors and coordinators only see papers of their units
+	var args []interface{}
+	scope, scoped, err := s.scopeUnits(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your scope"})
+		return
+	}
+	if scoped {
+		query += " WHERE COALESCE(p.org_unit_id, u.org_unit_id) = ANY($1)"
+		args = append(args, scope)
+	}
+	query += " ORDER BY p.created_at DESC"
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
 		return
@@ -441,13 +584,15 @@ func (s *Server) GetPapers(c *gin.Context) {
 		err := rows.Scan(
 			&paper.ID, &paper.Title, &paper.Abstract, &paper.Content, &paper.FileUrl, &paper.AuthorID,
 			&paper.Status, &paper.Type, &paper.CreatedAt, &paper.UpdatedAt,
-			&paper.InstitutionCode, &paper.PublicationID, &paper.PublicationISCEDBand, &paper.PublicationTitleAmharic,
-			&paper.PublicationDate, &paper.PublicationType, &paper.JournalType, &paper.JournalName, &paper.IndigenousKnowledge,
+			&paper.OrgUnitID, &paper.InstitutionCode, &paper.PublicationID, &paper.PublicationISCEDBand, &paper.PublicationTitleAmharic,
+			&paper.PublicationDate, &paper.PublicationType, &paper.JournalType, &paper.JournalName, &paper.IndigenousKnowledge, &paper.DOI,
 			&paper.FiscalYear, &paper.AllocatedBudget, &paper.ExternalBudget, &paper.NRFFund,
 			&paper.ResearchType, &paper.CompletionStatus, &paper.FemaleResearchers, &paper.MaleResearchers,
 			&paper.OutsideFemaleResearchers, &paper.OutsideMaleResearchers, &paper.BenefitedIndustry,
 			&paper.EthicalClearance, &paper.PIName, &paper.PIGender, &paper.CoInvestigators,
 			&paper.ProducedPrototype, &paper.HetrilCollaboration, &paper.SubmittedToIncubator,
+			&paper.ScheduledPublishAt, &paper.EmbargoUntil,
+			&paper.RejectedAt, &paper.WithdrawnAt, &paper.WithdrawalReason, &paper.RetractedAt, &paper.RetractionNotice,
 			&paper.AuthorName, &paper.AuthorEmail, &paper.AuthorAcademicYear,
 			&paper.AuthorType, &paper.AuthorCategory, &paper.AuthorAcademicRank, &paper.AuthorQualification,
 			&paper.AuthorEmploymentType, &paper.AuthorGender, &paper.AuthorDateOfBirth, &paper.AuthorBio, &paper.AuthorAvatar,
@@ -457,6 +602,7 @@ func (s *Server) GetPapers(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan paper"})
 			return
 		}
+		localizePaperDates(cal, &paper.Paper)
 		papers = append(papers, paper)
 	}
 
@@ -496,28 +642,37 @@ func (s *Server) CreatePaper(c *gin.Context) {
 		paper.Type = "Research Paper" // Default
 	}
 
+	cal, err := requestCalendar(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The fiscal year follows the submission date until a publication date is set
+	paper.FiscalYear = derivePaperFiscalYear(nil, time.Now())
+
 	ctx := c.Request.Context()
 	query := `
 		INSERT INTO papers (
 			title, abstract, content, file_url, author_id, status, type,
 			publication_title_amharic, publication_isced_band, publication_type,
-			journal_type, journal_name
+			journal_type, journal_name, fiscal_year, org_unit_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, title, COALESCE(abstract, ''), COALESCE(content, ''), COALESCE(file_url, ''), author_id, status, type, created_at, updated_at,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, (SELECT org_unit_id FROM users WHERE id = $5))
+		RETURNING id, title, COALESCE(abstract, ''), COALESCE(content, ''), COALESCE(file_url, ''), author_id, status, type, created_at, updated_at, org_unit_id,
 				  COALESCE(publication_title_amharic, ''), COALESCE(publication_isced_band, ''), COALESCE(publication_type, ''),
-				  COALESCE(journal_type, ''), COALESCE(journal_name, '')
+				  COALESCE(journal_type, ''), COALESCE(journal_name, ''), COALESCE(fiscal_year, '')
 	`
 
 	err = s.db.Pool.QueryRow(ctx, query,
 		paper.Title, paper.Abstract, paper.Content, paper.FileUrl, paper.AuthorID, paper.Status, paper.Type,
 		paper.PublicationTitleAmharic, paper.PublicationISCEDBand, paper.PublicationType,
-		paper.JournalType, paper.JournalName,
+		paper.JournalType, paper.JournalName, paper.FiscalYear,
 	).Scan(
 		&paper.ID, &paper.Title, &paper.Abstract, &paper.Content, &paper.FileUrl, &paper.AuthorID,
-		&paper.Status, &paper.Type, &paper.CreatedAt, &paper.UpdatedAt,
+		&paper.Status, &paper.Type, &paper.CreatedAt, &paper.UpdatedAt, &paper.OrgUnitID,
 		&paper.PublicationTitleAmharic, &paper.PublicationISCEDBand, &paper.PublicationType,
-		&paper.JournalType, &paper.JournalName,
+		&paper.JournalType, &paper.JournalName, &paper.FiscalYear,
 	)
 
 	if err != nil {
@@ -525,23 +680,16 @@ func (s *Server) CreatePaper(c *gin.Context) {
 		return
 	}
 
-	// Create notifications for all editors
-	go func() {
-		// Find all editors
-		rows, err := s.db.Pool.Query(context.Background(), "SELECT id FROM users WHERE role = 'editor'")
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var editorID uuid.UUID
-				if err := rows.Scan(&editorID); err == nil {
-					// Create notification
-					s.db.Pool.Exec(context.Background(),
-						"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
-						editorID, "New paper submitted: "+paper.Title, paper.ID)
-				}
-			}
-		}
-	}()
+	// Papers of a type with an approval chain go through it before review
+	if err := s.startApprovalChain(ctx, paper.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Paper created, but its approval chain could not be started"})
+		return
+	}
+
+	localizePaperDates(cal, &paper)
+
+	// Notify the editors whose scope covers the paper's unit
+	go s.notifyStaffInScope(context.WithoutCancel(ctx), "editor", paper.OrgUnitID, "New paper submitted: "+paper.Title, paper.ID)
 
 	c.JSON(http.StatusCreated, paper)
 }
@@ -560,9 +708,25 @@ func (s *Server) UpdatePaper(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+
+	// A paper in an approval round only moves on once every step has approved
+	switch req.Status {
+	case "under_review", "approved", "recommended_for_publication", "published":
+		pending, err := s.approvalPending(ctx, paperID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check approvals"})
+			return
+		}
+		if pending {
+			c.JSON(http.StatusConflict, gin.H{"error": "The paper is still awaiting approval"})
+			return
+		}
+	}
+
 	query := `
 		UPDATE papers
-		SET title = $1, abstract = $2, content = $3, file_url = $4, status = $5, updated_at = NOW()
+		SET title = $1, abstract = $2, content = $3, file_url = $4, status = $5, updated_at = NOW(),
+			rejected_at = CASE WHEN $5 = 'rejected' AND status <> 'rejected' THEN NOW() ELSE rejected_at END
 		WHERE id = $6
 		RETURNING id, title, COALESCE(abstract, ''), COALESCE(content, ''), COALESCE(file_url, ''), author_id, status, created_at, updated_at
 	`
@@ -578,13 +742,22 @@ func (s *Server) UpdatePaper(c *gin.Context) {
 		return
 	}
 
+	// Resubmitting a paper returned for changes starts a new approval round
+	if req.Status == "submitted" {
+		if err := s.startApprovalChain(ctx, paper.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Paper updated, but its approval chain could not be started"})
+			return
+		}
+	}
+
 	// If admin is publishing, approving or rejecting a recommended paper, notify the editor and author
 	if req.Status == "published" || req.Status == "rejected" || req.Status == "approved" {
 		go func() {
+			ctx := context.WithoutCancel(ctx)
 			statusText := req.Status
 
 			// Notify all reviewers (editors)
-			rows, err := s.db.Pool.Query(context.Background(),
+			rows, err := s.db.Pool.Query(ctx,
 				"SELECT reviewer_id FROM reviews WHERE paper_id = $1",
 				paper.ID)
 			if err == nil {
@@ -593,7 +766,7 @@ func (s *Server) UpdatePaper(c *gin.Context) {
 					var reviewerID uuid.UUID
 					if err := rows.Scan(&reviewerID); err == nil {
 						message := fmt.Sprintf("Admin decision: Paper '%s' has been %s", paper.Title, statusText)
-						s.db.Pool.Exec(context.Background(),
+						s.db.Pool.Exec(ctx,
 							"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 							reviewerID, message, paper.ID)
 					}
@@ -602,7 +775,7 @@ func (s *Server) UpdatePaper(c *gin.Context) {
 
 			// Also notify the author
 			message := fmt.Sprintf("Your paper '%s' has been %s", paper.Title, statusText)
-			s.db.Pool.Exec(context.Background(),
+			s.db.Pool.Exec(ctx,
 				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 				paper.AuthorID, message, paper.ID)
 		}()
@@ -619,6 +792,20 @@ func (s *Server) RecommendPaperForPublication(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	own, err := s.isPaperAuthor(ctx, paperID, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the paper's authors"})
+		return
+	}
+	if own {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot recommend your own paper"})
+		return
+	}
+	if pending, err := s.approvalPending(ctx, paperID); err != nil || pending {
+		c.JSON(http.StatusConflict, gin.H{"error": "The paper is still awaiting approval"})
+		return
+	}
+
 	// Update paper status to recommended_for_publication
 	query := `
 		UPDATE papers
@@ -640,14 +827,15 @@ func (s *Server) RecommendPaperForPublication(c *gin.Context) {
 
 	// Notify all admins and the author
 	go func() {
-		rows, err := s.db.Pool.Query(context.Background(), "SELECT id FROM users WHERE role = 'admin'")
+		ctx := context.WithoutCancel(ctx)
+		rows, err := s.db.Pool.Query(ctx, "SELECT id FROM users WHERE 'admin' = ANY(roles) AND id <> $1", paper.AuthorID)
 		if err == nil {
 			defer rows.Close()
 			for rows.Next() {
 				var adminID uuid.UUID
 				if err := rows.Scan(&adminID); err == nil {
 					message := fmt.Sprintf("Paper '%s' has been recommended for publication by an editor", paper.Title)
-					s.db.Pool.Exec(context.Background(),
+					s.db.Pool.Exec(ctx,
 						"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 						adminID, message, paper.ID)
 				}
@@ -656,7 +844,7 @@ func (s *Server) RecommendPaperForPublication(c *gin.Context) {
 
 		// Notify the author
 		authorMessage := fmt.Sprintf("Your paper '%s' has been recommended for publication by an editor", paper.Title)
-		s.db.Pool.Exec(context.Background(),
+		s.db.Pool.Exec(ctx,
 			"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 			paper.AuthorID, authorMessage, paper.ID)
 	}()
@@ -664,7 +852,8 @@ func (s *Server) RecommendPaperForPublication(c *gin.Context) {
 	// Store editor ID for later notification (we'll add a column for this)
 	// For now, we'll query reviews to find the editor
 	go func() {
-		s.db.Pool.Exec(context.Background(),
+		ctx := context.WithoutCancel(ctx)
+		s.db.Pool.Exec(ctx,
 			"UPDATE papers SET updated_at = NOW() WHERE id = $1",
 			paper.ID)
 	}()
@@ -685,12 +874,32 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 		return
 	}
 
+	cal, err := requestCalendar(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := resolvePublicationDate(cal, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx := c.Request.Context()
 
+	// Derive the Ethiopian fiscal year from the publication or submission date when not given
+	if req.FiscalYear == "" {
+		var submittedAt time.Time
+		if err := s.db.Pool.QueryRow(ctx, "SELECT created_at FROM papers WHERE id = $1", paperID).Scan(&submittedAt); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+			return
+		}
+		req.FiscalYear = derivePaperFiscalYear(&req.PublicationDate, submittedAt)
+	}
+
 	// Generate Publication ID if not provided and status is being set to something that implies publication or if it's just missing
 	// For now, we'll generate it if it's empty.
 	if req.PublicationID == "" {
-		req.PublicationID, err = s.generatePublicationID(ctx)
+		req.PublicationID, err = s.generatePublicationID(ctx, currentTenant(c).PublicationIDPrefix)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Publication ID"})
 			return
@@ -707,16 +916,19 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 			outside_female_researchers = $18, outside_male_researchers = $19, benefited_industry = $20,
 			ethical_clearance = $21, pi_name = $22, pi_gender = $23, co_investigators = $24,
 			produced_prototype = $25, hetril_collaboration = $26, submitted_to_incubator = $27,
-			updated_at = NOW()
-		WHERE id = $28
+			doi = NULLIF($28, ''), updated_at = NOW(),
+			org_unit_id = COALESCE((SELECT id FROM org_units WHERE code = NULLIF($1, '')), org_unit_id)
+		WHERE id = $29
 		RETURNING id, title, COALESCE(abstract, ''), COALESCE(content, ''), COALESCE(file_url, ''), author_id, status, created_at, updated_at,
+				  COALESCE(org_unit_id, (SELECT org_unit_id FROM users WHERE id = papers.author_id)),
 				  COALESCE(institution_code, ''), COALESCE(publication_id, ''), COALESCE(publication_isced_band, ''), COALESCE(publication_title_amharic, ''),
 				  publication_date, COALESCE(publication_type, ''), COALESCE(journal_type, ''), COALESCE(journal_name, ''), COALESCE(indigenous_knowledge, false),
 				  COALESCE(fiscal_year, ''), COALESCE(allocated_budget, 0), COALESCE(external_budget, 0), COALESCE(nrf_fund, 0),
 				  COALESCE(research_type, ''), COALESCE(completion_status, ''), COALESCE(female_researchers, 0), COALESCE(male_researchers, 0),
 				  COALESCE(outside_female_researchers, 0), COALESCE(outside_male_researchers, 0), COALESCE(benefited_industry, ''),
 				  COALESCE(ethical_clearance, ''), COALESCE(pi_name, ''), COALESCE(pi_gender, ''), COALESCE(co_investigators, ''),
-				  COALESCE(produced_prototype, ''), COALESCE(hetril_collaboration, ''), COALESCE(submitted_to_incubator, '')
+				  COALESCE(produced_prototype, ''), COALESCE(hetril_collaboration, ''), COALESCE(submitted_to_incubator, ''),
+				  COALESCE(doi, '')
 	`
 
 	var paper models.Paper
@@ -729,10 +941,10 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 		req.OutsideFemaleResearchers, req.OutsideMaleResearchers, req.BenefitedIndustry,
 		req.EthicalClearance, req.PIName, req.PIGender, req.CoInvestigators,
 		req.ProducedPrototype, req.HetrilCollaboration, req.SubmittedToIncubator,
-		paperID,
+		req.DOI, paperID,
 	).Scan(
 		&paper.ID, &paper.Title, &paper.Abstract, &paper.Content, &paper.FileUrl, &paper.AuthorID,
-		&paper.Status, &paper.CreatedAt, &paper.UpdatedAt,
+		&paper.Status, &paper.CreatedAt, &paper.UpdatedAt, &paper.OrgUnitID,
 		&paper.InstitutionCode, &paper.PublicationID, &paper.PublicationISCEDBand,
 		&paper.PublicationTitleAmharic, &paper.PublicationDate, &paper.PublicationType,
 		&paper.JournalType, &paper.JournalName, &paper.IndigenousKnowledge,
@@ -741,6 +953,7 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 		&paper.OutsideFemaleResearchers, &paper.OutsideMaleResearchers, &paper.BenefitedIndustry,
 		&paper.EthicalClearance, &paper.PIName, &paper.PIGender, &paper.CoInvestigators,
 		&paper.ProducedPrototype, &paper.HetrilCollaboration, &paper.SubmittedToIncubator,
+		&paper.DOI,
 	)
 
 	if err != nil {
@@ -748,41 +961,33 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 		return
 	}
 
+	localizePaperDates(cal, &paper)
+
 	// Notify Admin, Coordinator, and Author
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		// Notify Admins
-		rows, err := s.db.Pool.Query(context.Background(), "SELECT id FROM users WHERE role = 'admin'")
+		rows, err := s.db.Pool.Query(ctx, "SELECT id FROM users WHERE 'admin' = ANY(roles) AND id <> $1", paper.AuthorID)
 		if err == nil {
 			defer rows.Close()
 			for rows.Next() {
 				var adminID uuid.UUID
 				if err := rows.Scan(&adminID); err == nil {
 					message := fmt.Sprintf("Paper details updated for '%s' by Editor", paper.Title)
-					s.db.Pool.Exec(context.Background(),
+					s.db.Pool.Exec(ctx,
 						"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 						adminID, message, paper.ID)
 				}
 			}
 		}
 
-		// Notify Coordinators
-		rowsCoord, err := s.db.Pool.Query(context.Background(), "SELECT id FROM users WHERE role = 'coordinator'")
-		if err == nil {
-			defer rowsCoord.Close()
-			for rowsCoord.Next() {
-				var coordID uuid.UUID
-				if err := rowsCoord.Scan(&coordID); err == nil {
-					message := fmt.Sprintf("Paper details updated for '%s' by Editor. Please validate.", paper.Title)
-					s.db.Pool.Exec(context.Background(),
-						"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
-						coordID, message, paper.ID)
-				}
-			}
-		}
+		// Notify the coordinators whose scope covers the paper's unit
+		message := fmt.Sprintf("Paper details updated for '%s' by Editor. Please validate.", paper.Title)
+		s.notifyStaffInScope(ctx, "coordinator", paper.OrgUnitID, message, paper.ID)
 
 		// Notify the author
 		authorMessage := fmt.Sprintf("Publication details for your paper '%s' have been updated by an editor", paper.Title)
-		s.db.Pool.Exec(context.Background(),
+		s.db.Pool.Exec(ctx,
 			"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 			paper.AuthorID, authorMessage, paper.ID)
 	}()
@@ -790,44 +995,26 @@ func (s *Server) UpdatePaperDetails(c *gin.Context) {
 	c.JSON(http.StatusOK, paper)
 }
 
-func (s *Server) generatePublicationID(ctx context.Context) (string, error) {
-	// Format: SMU_P201817001
-	// We need to find the last ID and increment it.
-	// Assuming the format is fixed and numeric part is at the end.
+// generatePublicationID returns the next publication ID of the tenant, e.g.
+// SMU_P201817001 for the prefix SMU_P
+func (s *Server) generatePublicationID(ctx context.Context, prefix string) (string, error) {
+	first := prefix + "201817001"
 
+	// Row level security keeps the lookup within the tenant
 	var lastID string
-	err := s.db.Pool.QueryRow(ctx, "SELECT publication_id FROM papers WHERE publication_id LIKE 'SMU_P%' ORDER BY publication_id DESC LIMIT 1").Scan(&lastID)
+	err := s.db.Pool.QueryRow(ctx,
+		"SELECT publication_id FROM papers WHERE starts_with(publication_id, $1) ORDER BY publication_id DESC LIMIT 1",
+		prefix).Scan(&lastID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return "SMU_P201817001", nil
-		}
-		// If it's NULL (which it might be for existing papers), we might get here or Scan might fail depending on driver.
-		// pgx Scan returns error if NULL is scanned into string without NullString? No, Scan handles it if we use *string or NullString.
-		// But here we scan into string. If NULL, it errors.
-		// Let's handle it safely.
-		return "SMU_P201817001", nil // Default start if error or no rows
-	}
-
-	if lastID == "" {
-		return "SMU_P201817001", nil
-	}
-
-	// Parse the number
-	// SMU_P201817001 -> 201817001
-	if len(lastID) < 6 {
-		return "SMU_P201817001", nil
+		// No rows, or only NULL IDs so far
+		return first, nil
 	}
 
-	prefix := "SMU_P"
-	numStr := lastID[len(prefix):]
 	var num int
-	_, err = fmt.Sscanf(numStr, "%d", &num)
-	if err != nil {
-		return "SMU_P201817001", nil // Fallback
+	if _, err := fmt.Sscanf(lastID[len(prefix):], "%d", &num); err != nil {
+		return first, nil
 	}
-
-	newNum := num + 1
-	return fmt.Sprintf("%s%d", prefix, newNum), nil
+	return fmt.Sprintf("%s%d", prefix, num+1), nil
 }
 
 func (s *Server) DeletePaper(c *gin.Context) {
@@ -887,8 +1074,20 @@ func (s *Server) GetReviews(c *gin.Context) {
 			FROM reviews r
 			LEFT JOIN users reviewer ON r.reviewer_id = reviewer.id
 			LEFT JOIN papers p ON r.paper_id = p.id
-			ORDER BY r.created_at DESC
+			LEFT JOIN users author ON p.author_id = author.id
 		`
+
+		// Scoped editors and coordinators only see reviews of their units' papers
+		scope, scoped, err := s.scopeUnits(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your scope"})
+			return
+		}
+		if scoped {
+			query += " WHERE COALESCE(p.org_unit_id, author.org_unit_id) = ANY($1)"
+			args = append(args, scope)
+		}
+		query += " ORDER BY r.created_at DESC"
 	}
 
 	rows, err := s.db.Pool.Query(ctx, query, args...)
@@ -951,6 +1150,15 @@ func (s *Server) CreateReview(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	own, err := s.isPaperAuthor(ctx, review.PaperID, reviewerID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the paper's authors"})
+		return
+	}
+	if own {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot review your own paper"})
+		return
+	}
 	query := `
 		INSERT INTO reviews (paper_id, reviewer_id, rating, problem_statement, literature_review, methodology, results, conclusion, originality, clarity_organization, contribution_knowledge, technical_quality, comments, recommendation)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
@@ -977,10 +1185,11 @@ func (s *Server) CreateReview(c *gin.Context) {
 
 	// Send notification to paper author
 	go func() {
+		ctx := context.WithoutCancel(ctx)
 		// Get paper details to find author
 		var authorID uuid.UUID
 		var paperTitle string
-		err := s.db.Pool.QueryRow(context.Background(),
+		err := s.db.Pool.QueryRow(ctx,
 			"SELECT author_id, title FROM papers WHERE id = $1",
 			review.PaperID).Scan(&authorID, &paperTitle)
 
@@ -989,7 +1198,7 @@ func (s *Server) CreateReview(c *gin.Context) {
 			message := fmt.Sprintf("Your paper '%s' has been reviewed. Rating: %d/5, Recommendation: %s",
 				paperTitle, review.Rating, review.Recommendation)
 
-			s.db.Pool.Exec(context.Background(),
+			s.db.Pool.Exec(ctx,
 				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
 				authorID, message, review.PaperID)
 		}
@@ -1003,6 +1212,12 @@ func (s *Server) GetEvents(c *gin.Context) {
 	ctx := c.Request.Context()
 	status := c.Query("status")
 
+	cal, err := requestCalendar(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	query := `
 		SELECT e.id, e.title, e.description, e.category, e.status, COALESCE(e.image_url, ''), COALESCE(e.video_url, ''), e.date, e.location, e.coordinator_id, e.created_at, e.updated_at,
 			   c.name as coordinator_name, c.email as coordinator_email
@@ -1036,6 +1251,9 @@ func (s *Server) GetEvents(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan event"})
 			return
 		}
+		if cal == ethcal.CalendarEthiopian {
+			event.DateEthiopian = ethcal.FromGregorian(event.Date).String()
+		}
 		events = append(events, event)
 	}
 
@@ -1178,7 +1396,7 @@ func (s *Server) GetAdminUsers(c *gin.Context) {
 	query := `
 		SELECT id, email, name, role
 		FROM users
-		WHERE role = 'admin'
+		WHERE 'admin' = ANY(roles)
 		LIMIT 1
 	`
 
@@ -1322,71 +1540,12 @@ func (s *Server) MarkNotificationRead(c *gin.Context) {
 
 // Admin User Management Handlers
 
-func (s *Server) AdminCreateUser(c *gin.Context) {
-	var req models.CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate role (must be editor or coordinator)
-	if req.Role != "editor" && req.Role != "coordinator" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Only editor and coordinator can be created by admin."})
-		return
-	}
-
-	// Create user in Supabase (confirmed)
-	metadata := map[string]interface{}{
-		"name": req.Name,
-		"role": req.Role,
-	}
-
-	sbUser, err := s.supabase.AdminCreateUser(req.Email, req.Password, metadata)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create user in Supabase: %v", err)})
-		return
-	}
-
-	ctx := c.Request.Context()
-	user := models.User{
-		ID:          uuid.MustParse(sbUser.ID),
-		Email:       sbUser.Email,
-		Name:        req.Name,
-		Role:        req.Role,
-		IsVerified:  true,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		Preferences: map[string]interface{}{},
-	}
-
-	// Insert into local DB
-	query := `
-		INSERT INTO users (id, email, password_hash, name, role, is_verified, created_at, updated_at, preferences)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
-	// We assume simple password hash storage isn't needed locally if we rely on Supabase,
-	// but we can store a placeholder or hash it if we want local fallback.
-	// For now, empty string.
-	_, err = s.db.Pool.Exec(ctx, query,
-		user.ID, user.Email, "", user.Name, user.Role, user.IsVerified, user.CreatedAt, user.UpdatedAt, user.Preferences,
-	)
-
-	if err != nil {
-		// Try to delete from Supabase if local insert fails?
-		// For now just error out.
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create user in local DB: %v", err)})
-		return
-	}
-
-	c.JSON(http.StatusCreated, user)
-}
-
 func (s *Server) GetAdminStaff(c *gin.Context) {
 	ctx := c.Request.Context()
 	query := `
-		SELECT id, email, name, role, created_at, is_verified
+		SELECT id, email, name, role, roles, created_at, is_verified
 		FROM users
-		WHERE role IN ('editor', 'coordinator')
+		WHERE NOT (roles <@ ARRAY['author', 'admin', 'super_admin']::TEXT[])
 		ORDER BY created_at DESC
 	`
 
@@ -1401,10 +1560,11 @@ func (s *Server) GetAdminStaff(c *gin.Context) {
 	for rows.Next() {
 		var id uuid.UUID
 		var email, name, role string
+		var roles []string
 		var createdAt time.Time
 		var isVerified bool
 
-		if err := rows.Scan(&id, &email, &name, &role, &createdAt, &isVerified); err != nil {
+		if err := rows.Scan(&id, &email, &name, &role, &roles, &createdAt, &isVerified); err != nil {
 			continue
 		}
 
@@ -1413,6 +1573,7 @@ func (s *Server) GetAdminStaff(c *gin.Context) {
 			"email":       email,
 			"name":        name,
 			"role":        role,
+			"roles":       roles,
 			"created_at":  createdAt,
 			"is_verified": isVerified,
 		})
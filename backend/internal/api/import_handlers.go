@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"rpms-backend/internal/importer"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportPapers imports legacy publication records from a CSV or XLSX upload.
+// Form fields: file, mapping (JSON object of field -> column), calendar,
+// default_status and dry_run (defaults to true so a preview comes first).
+func (s *Server) ImportPapers(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	table, err := importer.ReadTable(file, header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := importer.Options{
+		Calendar:      c.PostForm("calendar"),
+		DefaultStatus: c.PostForm("default_status"),
+	}
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping: " + err.Error()})
+			return
+		}
+	}
+
+	dryRun := true
+	if raw := c.PostForm("dry_run"); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run value"})
+			return
+		}
+	}
+
+	report, err := importer.ImportPapers(c.Request.Context(), s.db, table, opts, dryRun)
+	if err != nil {
+		if errors.Is(err, importer.ErrInvalidOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import papers: " + err.Error()})
+		return
+	}
+
+	if !dryRun && report.InvalidRows > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ImportUsers provisions staff and authors from a CSV or XLSX upload. Form
+// fields: file, mapping (JSON object of field -> column), calendar (of dates
+// of birth), default_role, dry_run (defaults to true), send_invitations
+// (invite the new users) and format (json or csv for a downloadable report).
+func (s *Server) ImportUsers(c *gin.Context) {
+	format := c.DefaultPostForm("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	table, err := importer.ReadTable(file, header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checked := map[string]error{}
+	opts := importer.UserOptions{
+		Calendar:    c.PostForm("calendar"),
+		DefaultRole: c.PostForm("default_role"),
+		CheckRoles: func(roles []string) error {
+			for _, role := range roles {
+				err, ok := checked[role]
+				if !ok {
+					_, err = s.assignableRole(c, role)
+					checked[role] = err
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping: " + err.Error()})
+			return
+		}
+	}
+
+	dryRun := true
+	if raw := c.PostForm("dry_run"); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run value"})
+			return
+		}
+	}
+	invite := false
+	if raw := c.PostForm("send_invitations"); raw != "" {
+		invite, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid send_invitations value"})
+			return
+		}
+	}
+
+	report, err := importer.ImportUsers(c.Request.Context(), s.db, table, opts, dryRun)
+	if err != nil {
+		if errors.Is(err, importer.ErrInvalidOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import users: " + err.Error()})
+		return
+	}
+
+	if !dryRun {
+		if invite {
+			s.inviteImportedUsers(c, report)
+		}
+		s.audit(c, auditUserImport, nil, gin.H{
+			"file": header.Filename, "created": report.Created, "updated": report.Updated,
+			"failed": report.Failed, "invited": report.Invited,
+		})
+	}
+
+	if format == "csv" {
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write report"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="user-import-report.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// inviteImportedUsers invites the placeholder accounts an import created or
+// updated, with the roles and unit the import gave them. The emails are
+// sent in the background; admins can resend any that did not arrive.
+func (s *Server) inviteImportedUsers(c *gin.Context, report *importer.UserReport) {
+	ctx := c.Request.Context()
+	var invitations []*models.Invitation
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Action == importer.UserFailed || !row.Placeholder {
+			continue
+		}
+		tx, err := s.db.BeginTx(ctx)
+		if err != nil {
+			log.Printf("Failed to invite imported user %s: %v", row.Email, err)
+			continue
+		}
+		inv, err := insertInvitation(ctx, tx, models.InvitationRequest{
+			Email: row.Email, Name: row.Name, Role: row.Role, OrgUnitID: row.OrgUnitID,
+		}, row.Roles, c.GetString("user_id"))
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		tx.Rollback(ctx)
+		if err != nil {
+			log.Printf("Failed to invite imported user %s: %v", row.Email, err)
+			continue
+		}
+		row.Invited = true
+		report.Invited++
+		invitations = append(invitations, inv)
+	}
+
+	go func(ctx context.Context) {
+		for _, inv := range invitations {
+			if err := s.sendInvitation(ctx, inv); err != nil {
+				log.Printf("Failed to send invitation email to %s: %v", inv.Email, err)
+			}
+		}
+	}(context.WithoutCancel(mailContext(c)))
+}
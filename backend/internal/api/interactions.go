@@ -70,7 +70,7 @@ func (s *Server) LikePost(c *gin.Context) {
 	}
 
 	// Create notification for coordinator if it's news or event
-	go s.createEngagementNotification(req.PostType, req.PostID, userID, "like")
+	go s.createEngagementNotification(context.WithoutCancel(ctx), req.PostType, req.PostID, userID, "like")
 
 	c.JSON(http.StatusOK, gin.H{"message": "Liked successfully", "liked": true, "like": like})
 }
@@ -178,7 +178,7 @@ func (s *Server) AddComment(c *gin.Context) {
 	comment.UserAvatar = userAvatar
 
 	// Create notification for coordinator
-	go s.createEngagementNotification(req.PostType, req.PostID, userID, "comment")
+	go s.createEngagementNotification(context.WithoutCancel(ctx), req.PostType, req.PostID, userID, "comment")
 
 	c.JSON(http.StatusOK, gin.H{"message": "Comment added successfully", "comment": comment})
 }
@@ -346,12 +346,10 @@ func (s *Server) GetEngagementStats(c *gin.Context) {
 }
 
 // Helper function to create engagement notifications
-func (s *Server) createEngagementNotification(postType string, postID uuid.UUID, userID uuid.UUID, action string) {
-	ctx := context.Background()
-
+func (s *Server) createEngagementNotification(ctx context.Context, postType string, postID uuid.UUID, userID uuid.UUID, action string) {
 	// Get coordinator user ID
 	var coordinatorID uuid.UUID
-	err := s.db.Pool.QueryRow(ctx, "SELECT id FROM users WHERE role = 'coordinator' LIMIT 1").Scan(&coordinatorID)
+	err := s.db.Pool.QueryRow(ctx, "SELECT id FROM users WHERE 'coordinator' = ANY(roles) LIMIT 1").Scan(&coordinatorID)
 	if err != nil {
 		return // No coordinator found
 	}
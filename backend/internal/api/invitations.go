@@ -0,0 +1,462 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// How long an invitation stays valid
+const inviteTTL = 7 * 24 * time.Hour
+
+const invitationStatus = `
+	CASE
+		WHEN i.accepted_at IS NOT NULL THEN 'accepted'
+		WHEN i.revoked_at IS NOT NULL THEN 'revoked'
+		WHEN i.expires_at < NOW() THEN 'expired'
+		ELSE 'pending'
+	END`
+
+const invitationQuery = `
+	SELECT i.id, i.email, COALESCE(i.name, ''), i.role, i.roles, i.org_unit_id, COALESCE(ou.name, ''), i.scope_org_unit_id,
+		   i.invited_by, COALESCE(inviter.name, ''),` + invitationStatus + `,
+		   i.expires_at, i.accepted_at, i.accepted_user_id, i.revoked_at, i.created_at
+	FROM invitations i
+	LEFT JOIN org_units ou ON i.org_unit_id = ou.id
+	LEFT JOIN users inviter ON i.invited_by = inviter.id`
+
+func scanInvitation(row pgx.Row, inv *models.Invitation) error {
+	return row.Scan(&inv.ID, &inv.Email, &inv.Name, &inv.Role, &inv.Roles, &inv.OrgUnitID, &inv.OrgUnitName, &inv.ScopeOrgUnitID,
+		&inv.InvitedBy, &inv.InvitedByName, &inv.Status,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.AcceptedUserID, &inv.RevokedAt, &inv.CreatedAt)
+}
+
+// emailRegistered reports whether a real (not placeholder) account in any
+// tenant uses the address
+func (s *Server) emailRegistered(ctx context.Context, address string) (bool, error) {
+	var taken bool
+	err := s.db.Pool.QueryRow(database.WithoutTenant(ctx), `
+		SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = $1 AND NOT COALESCE(is_placeholder, FALSE))
+	`, address).Scan(&taken)
+	return taken, err
+}
+
+// sendInvitation signs a link to the invitation and emails it
+func (s *Server) sendInvitation(ctx context.Context, inv *models.Invitation) error {
+	token, err := s.jwtManager.GenerateInvitation(inv.ID.String(), inv.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	link := s.config.InvitationURL + "?token=" + url.QueryEscape(token)
+	return s.emailSender.ForContext(ctx).SendInvitationEmail(inv.Email, inv.Name, link, time.Until(inv.ExpiresAt).Round(time.Hour))
+}
+
+// insertInvitation stores an invitation to the (lowercase) address of the
+// request, revoking the address's pending ones
+func insertInvitation(ctx context.Context, tx pgx.Tx, req models.InvitationRequest, roles []string, invitedBy string) (*models.Invitation, error) {
+	if _, err := tx.Exec(ctx, `
+		UPDATE invitations SET revoked_at = NOW()
+		WHERE LOWER(email) = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, req.Email); err != nil {
+		return nil, err
+	}
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `
+		INSERT INTO invitations (email, name, role, roles, org_unit_id, scope_org_unit_id, invited_by, expires_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, req.Email, req.Name, req.Role, roles, req.OrgUnitID, req.ScopeOrgUnitID,
+		invitedBy, time.Now().Add(inviteTTL)).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	var inv models.Invitation
+	if err := scanInvitation(tx.QueryRow(ctx, invitationQuery+" WHERE i.id = $1", id), &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// CreateInvitation invites someone to join as staff with preset roles and
+// unit. Pending invitations to the same address are replaced.
+func (s *Server) CreateInvitation(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	address := strings.ToLower(strings.TrimSpace(req.Email))
+
+	roles := withPrimaryRole(req.Role, req.Roles)
+	for _, role := range roles {
+		if status, err := s.assignableRole(c, role); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if req.ScopeOrgUnitID != nil {
+		scopeable, err := s.scopeableRoles(ctx, roles)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the roles"})
+			return
+		}
+		if !scopeable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errNotScopeable})
+			return
+		}
+	}
+	if taken, err := s.emailRegistered(ctx, address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the email address"})
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": auth.ErrEmailTaken.Error()})
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	req.Email, req.Name = address, strings.TrimSpace(req.Name)
+	inv, err := insertInvitation(ctx, tx, req, roles, c.GetString("user_id"))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	s.audit(c, auditInvitationCreate, nil, gin.H{"invitation_id": inv.ID, "email": inv.Email, "roles": inv.Roles})
+
+	if err := s.sendInvitation(mailContext(c), inv); err != nil {
+		log.Printf("Failed to send invitation email: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Invitation created, but the email could not be sent; resend it"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, inv)
+}
+
+// GetInvitations lists the tenant's invitations, newest first. status
+// filters by pending (the default), accepted, revoked, expired or all.
+func (s *Server) GetInvitations(c *gin.Context) {
+	status := c.DefaultQuery("status", models.InvitationPending)
+	where := ""
+	var args []interface{}
+	switch status {
+	case "all":
+	case models.InvitationPending, models.InvitationAccepted, models.InvitationRevoked, models.InvitationExpired:
+		where = " WHERE" + invitationStatus + " = $1"
+		args = append(args, status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, accepted, revoked, expired or all"})
+		return
+	}
+
+	rows, err := s.db.Pool.Query(c.Request.Context(), invitationQuery+where+" ORDER BY i.created_at DESC", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		var inv models.Invitation
+		if err := scanInvitation(rows, &inv); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read invitations"})
+			return
+		}
+		invitations = append(invitations, inv)
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// loadInvitation loads the invitation of the :id parameter, responding with
+// an error and returning false if there is none
+func (s *Server) loadInvitation(c *gin.Context) (*models.Invitation, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return nil, false
+	}
+	var inv models.Invitation
+	err = scanInvitation(s.db.Pool.QueryRow(c.Request.Context(), invitationQuery+" WHERE i.id = $1", id), &inv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return nil, false
+	}
+	return &inv, true
+}
+
+// RevokeInvitation stops an invitation from being accepted
+func (s *Server) RevokeInvitation(c *gin.Context) {
+	inv, ok := s.loadInvitation(c)
+	if !ok {
+		return
+	}
+	switch inv.Status {
+	case models.InvitationAccepted:
+		c.JSON(http.StatusConflict, gin.H{"error": "The invitation has already been accepted"})
+		return
+	case models.InvitationRevoked:
+		c.JSON(http.StatusConflict, gin.H{"error": "The invitation has already been revoked"})
+		return
+	}
+
+	if _, err := s.db.Pool.Exec(c.Request.Context(),
+		"UPDATE invitations SET revoked_at = NOW() WHERE id = $1 AND accepted_at IS NULL", inv.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	s.audit(c, auditInvitationRevoke, nil, gin.H{"invitation_id": inv.ID, "email": inv.Email})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// ResendInvitation emails a pending or expired invitation again, valid for
+// another inviteTTL
+func (s *Server) ResendInvitation(c *gin.Context) {
+	inv, ok := s.loadInvitation(c)
+	if !ok {
+		return
+	}
+	switch inv.Status {
+	case models.InvitationAccepted:
+		c.JSON(http.StatusConflict, gin.H{"error": "The invitation has already been accepted"})
+		return
+	case models.InvitationRevoked:
+		c.JSON(http.StatusConflict, gin.H{"error": "The invitation was revoked; create a new one"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := s.db.Pool.QueryRow(ctx, `
+		UPDATE invitations SET expires_at = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+		RETURNING expires_at
+	`, inv.ID, time.Now().Add(inviteTTL)).Scan(&inv.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to renew invitation"})
+		return
+	}
+	inv.Status = models.InvitationPending
+	s.audit(c, auditInvitationResend, nil, gin.H{"invitation_id": inv.ID, "email": inv.Email})
+
+	if err := s.sendInvitation(mailContext(c), inv); err != nil {
+		log.Printf("Failed to send invitation email: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// pendingInvitation loads the still pending invitation a token was signed
+// for, responding with an error and returning false otherwise
+func (s *Server) pendingInvitation(c *gin.Context, q pgx.Tx, token string) (*models.Invitation, bool) {
+	id, err := s.jwtManager.ValidateInvitation(token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation link"})
+		return nil, false
+	}
+
+	query := invitationQuery + " WHERE i.id = $1"
+	var row pgx.Row
+	if q != nil {
+		row = q.QueryRow(c.Request.Context(), query+" FOR UPDATE OF i", id)
+	} else {
+		row = s.db.Pool.QueryRow(c.Request.Context(), query, id)
+	}
+	var inv models.Invitation
+	err = scanInvitation(row, &inv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation link"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return nil, false
+	}
+	switch inv.Status {
+	case models.InvitationAccepted:
+		c.JSON(http.StatusConflict, gin.H{"error": "The invitation has already been accepted; please sign in"})
+		return nil, false
+	case models.InvitationRevoked, models.InvitationExpired:
+		c.JSON(http.StatusGone, gin.H{"error": "The invitation is no longer valid; ask an administrator for a new one"})
+		return nil, false
+	}
+	return &inv, true
+}
+
+// GetInvitationPreview shows the invitee what they were invited as before
+// they accept
+func (s *Server) GetInvitationPreview(c *gin.Context) {
+	inv, ok := s.pendingInvitation(c, nil, c.Param("token"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.InvitationPreview{
+		Email:       inv.Email,
+		Name:        inv.Name,
+		Role:        inv.Role,
+		Roles:       inv.Roles,
+		OrgUnitName: inv.OrgUnitName,
+		ExpiresAt:   inv.ExpiresAt,
+	})
+}
+
+// AcceptInvitation creates the invitee's account with the password and
+// profile they chose and the roles and unit of the invitation, then signs
+// them in. A placeholder account with the address is completed instead.
+func (s *Server) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	inv, ok := s.pendingInvitation(c, tx, req.Token)
+	if !ok {
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"name":            req.Name,
+		"role":            inv.Role,
+		"academic_rank":   req.AcademicRank,
+		"qualification":   req.Qualification,
+		"employment_type": req.EmploymentType,
+		"gender":          req.Gender,
+		"date_of_birth":   req.DateOfBirth,
+	}
+	identity, err := s.auth.CreateUser(ctx, inv.Email, req.Password, metadata)
+	if err != nil {
+		if errors.Is(err, auth.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Failed to create invited account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create your account"})
+		return
+	}
+
+	user := models.User{
+		ID:             identity.ID,
+		Email:          identity.Email,
+		Name:           strings.TrimSpace(req.Name),
+		Role:           inv.Role,
+		Roles:          inv.Roles,
+		Bio:            req.Bio,
+		Preferences:    map[string]interface{}{},
+		IsVerified:     true,
+		AcademicRank:   req.AcademicRank,
+		Qualification:  req.Qualification,
+		EmploymentType: req.EmploymentType,
+		Gender:         req.Gender,
+		DateOfBirth:    req.DateOfBirth,
+		OrgUnitID:      inv.OrgUnitID,
+		ScopeOrgUnitID: inv.ScopeOrgUnitID,
+	}
+
+	// A placeholder (say, an imported co-author or staff member) keeps its
+	// papers, and profile fields the form leaves empty
+	var placeholderID uuid.UUID
+	err = tx.QueryRow(ctx,
+		"SELECT id FROM users WHERE LOWER(email) = $1 AND COALESCE(is_placeholder, FALSE) FOR UPDATE",
+		inv.Email).Scan(&placeholderID)
+	switch {
+	case err == nil:
+		user.ID = placeholderID
+		err = tx.QueryRow(ctx, `
+			UPDATE users
+			SET password_hash = $2, name = $3, role = $4, roles = $5, bio = $6, is_verified = TRUE, is_placeholder = FALSE,
+				academic_rank = COALESCE(NULLIF($7, ''), academic_rank), qualification = COALESCE(NULLIF($8, ''), qualification),
+				employment_type = COALESCE(NULLIF($9, ''), employment_type), gender = COALESCE(NULLIF($10, ''), gender),
+				date_of_birth = COALESCE(NULLIF($11, ''), date_of_birth),
+				org_unit_id = COALESCE($12, org_unit_id), scope_org_unit_id = $13, updated_at = NOW()
+			WHERE id = $1
+			RETURNING COALESCE(academic_rank, ''), COALESCE(qualification, ''), COALESCE(employment_type, ''),
+				COALESCE(gender, ''), COALESCE(date_of_birth, ''), org_unit_id,
+				created_at, updated_at, tenant_id
+		`, user.ID, identity.PasswordHash, user.Name, user.Role, user.Roles, user.Bio,
+			user.AcademicRank, user.Qualification, user.EmploymentType, user.Gender, user.DateOfBirth,
+			user.OrgUnitID, user.ScopeOrgUnitID).Scan(
+			&user.AcademicRank, &user.Qualification, &user.EmploymentType, &user.Gender, &user.DateOfBirth, &user.OrgUnitID,
+			&user.CreatedAt, &user.UpdatedAt, &user.TenantID)
+	case errors.Is(err, pgx.ErrNoRows):
+		// The hash is empty unless the provider keeps passwords locally
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (
+				id, email, password_hash, name, role, roles, bio, preferences, is_verified,
+				academic_rank, qualification, employment_type, gender, date_of_birth, org_unit_id, scope_org_unit_id
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE, $9, $10, $11, $12, $13, $14, $15)
+			RETURNING created_at, updated_at, tenant_id
+		`, user.ID, user.Email, identity.PasswordHash, user.Name, user.Role, user.Roles, user.Bio, user.Preferences,
+			user.AcademicRank, user.Qualification, user.EmploymentType, user.Gender, user.DateOfBirth,
+			user.OrgUnitID, user.ScopeOrgUnitID).Scan(&user.CreatedAt, &user.UpdatedAt, &user.TenantID)
+	}
+	if err != nil {
+		log.Printf("Failed to store invited account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create your account"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE invitations SET accepted_at = NOW(), accepted_user_id = $2 WHERE id = $1",
+		inv.ID, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	s.audit(c, auditInvitationAccept, &user.ID, gin.H{"invitation_id": inv.ID, "invited_by": inv.InvitedBy, "roles": user.Roles})
+
+	response, err := s.issueSession(ctx, &user, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Your account was created; please sign in"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
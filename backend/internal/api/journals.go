@@ -0,0 +1,494 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const journalColumns = `id, title, COALESCE(title_amharic, ''), COALESCE(issn, ''), COALESCE(e_issn, ''), COALESCE(journal_type, 'Local'),
+	COALESCE(publisher, ''), COALESCE(description, ''), created_at, updated_at`
+
+const issueColumns = `i.id, i.volume_id, i.issue_number, COALESCE(i.title, ''), i.status, i.publication_date::timestamptz, i.published_at, i.created_at, i.updated_at`
+
+func scanJournal(row pgx.Row, j *models.Journal) error {
+	return row.Scan(&j.ID, &j.Title, &j.TitleAmharic, &j.ISSN, &j.EISSN, &j.JournalType, &j.Publisher, &j.Description, &j.CreatedAt, &j.UpdatedAt)
+}
+
+func scanIssue(row pgx.Row, i *models.JournalIssue) error {
+	return row.Scan(&i.ID, &i.VolumeID, &i.Number, &i.Title, &i.Status, &i.PublicationDate, &i.PublishedAt, &i.CreatedAt, &i.UpdatedAt)
+}
+
+// GetJournals lists the journals
+func (s *Server) GetJournals(c *gin.Context) {
+	rows, err := s.db.Pool.Query(c.Request.Context(), "SELECT "+journalColumns+" FROM journals ORDER BY title")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journals"})
+		return
+	}
+	defer rows.Close()
+
+	journals := []models.Journal{}
+	for rows.Next() {
+		var j models.Journal
+		if err := scanJournal(rows, &j); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan journal"})
+			return
+		}
+		journals = append(journals, j)
+	}
+	c.JSON(http.StatusOK, journals)
+}
+
+// GetJournal returns a journal with its volumes and their issues
+func (s *Server) GetJournal(c *gin.Context) {
+	journalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid journal ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var journal models.Journal
+	if err := scanJournal(s.db.Pool.QueryRow(ctx, "SELECT "+journalColumns+" FROM journals WHERE id = $1", journalID), &journal); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal not found"})
+		return
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, journal_id, volume_number, year, created_at
+		FROM journal_volumes
+		WHERE journal_id = $1
+		ORDER BY volume_number DESC
+	`, journalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch volumes"})
+		return
+	}
+	volumeIndex := map[uuid.UUID]int{}
+	journal.Volumes = []models.JournalVolume{}
+	for rows.Next() {
+		var v models.JournalVolume
+		if err := rows.Scan(&v.ID, &v.JournalID, &v.Number, &v.Year, &v.CreatedAt); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan volume"})
+			return
+		}
+		v.Issues = []models.JournalIssue{}
+		volumeIndex[v.ID] = len(journal.Volumes)
+		journal.Volumes = append(journal.Volumes, v)
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT `+issueColumns+`
+		FROM journal_issues i
+		JOIN journal_volumes v ON i.volume_id = v.id
+		WHERE v.journal_id = $1
+		ORDER BY i.issue_number ASC
+	`, journalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch issues"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issue models.JournalIssue
+		if err := scanIssue(rows, &issue); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan issue"})
+			return
+		}
+		idx := volumeIndex[issue.VolumeID]
+		journal.Volumes[idx].Issues = append(journal.Volumes[idx].Issues, issue)
+	}
+
+	c.JSON(http.StatusOK, journal)
+}
+
+func (s *Server) CreateJournal(c *gin.Context) {
+	var req models.JournalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.JournalType == "" {
+		req.JournalType = "Local"
+	}
+
+	var journal models.Journal
+	err := scanJournal(s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO journals (title, title_amharic, issn, e_issn, journal_type, publisher, description)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING `+journalColumns,
+		req.Title, req.TitleAmharic, req.ISSN, req.EISSN, req.JournalType, req.Publisher, req.Description,
+	), &journal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create journal"})
+		return
+	}
+	c.JSON(http.StatusCreated, journal)
+}
+
+func (s *Server) UpdateJournal(c *gin.Context) {
+	journalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid journal ID"})
+		return
+	}
+
+	var req models.JournalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.JournalType == "" {
+		req.JournalType = "Local"
+	}
+
+	var journal models.Journal
+	err = scanJournal(s.db.Pool.QueryRow(c.Request.Context(), `
+		UPDATE journals
+		SET title = $1, title_amharic = NULLIF($2, ''), issn = NULLIF($3, ''), e_issn = NULLIF($4, ''), journal_type = $5,
+			publisher = NULLIF($6, ''), description = NULLIF($7, ''), updated_at = NOW()
+		WHERE id = $8
+		RETURNING `+journalColumns,
+		req.Title, req.TitleAmharic, req.ISSN, req.EISSN, req.JournalType, req.Publisher, req.Description, journalID,
+	), &journal)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal not found"})
+		return
+	}
+	c.JSON(http.StatusOK, journal)
+}
+
+func (s *Server) CreateVolume(c *gin.Context) {
+	journalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid journal ID"})
+		return
+	}
+
+	var req models.CreateVolumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var v models.JournalVolume
+	err = s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO journal_volumes (journal_id, volume_number, year)
+		VALUES ($1, $2, $3)
+		RETURNING id, journal_id, volume_number, year, created_at
+	`, journalID, req.Number, req.Year).Scan(&v.ID, &v.JournalID, &v.Number, &v.Year, &v.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create volume: the journal may not exist or the volume number is taken"})
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+func (s *Server) CreateIssue(c *gin.Context) {
+	volumeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid volume ID"})
+		return
+	}
+
+	var req models.CreateIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var issue models.JournalIssue
+	err = scanIssue(s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO journal_issues AS i (volume_id, issue_number, title, publication_date)
+		VALUES ($1, $2, NULLIF($3, ''), $4::date)
+		RETURNING `+issueColumns,
+		volumeID, req.Number, req.Title, req.PublicationDate,
+	), &issue)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create issue: the volume may not exist or the issue number is taken"})
+		return
+	}
+	c.JSON(http.StatusCreated, issue)
+}
+
+func (s *Server) loadIssue(ctx context.Context, issueID uuid.UUID) (*models.JournalIssue, error) {
+	var issue models.JournalIssue
+	var journalID uuid.UUID
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT `+issueColumns+`, j.id, j.title, v.volume_number
+		FROM journal_issues i
+		JOIN journal_volumes v ON i.volume_id = v.id
+		JOIN journals j ON v.journal_id = j.id
+		WHERE i.id = $1
+	`, issueID).Scan(
+		&issue.ID, &issue.VolumeID, &issue.Number, &issue.Title, &issue.Status, &issue.PublicationDate, &issue.PublishedAt,
+		&issue.CreatedAt, &issue.UpdatedAt, &journalID, &issue.JournalTitle, &issue.VolumeNumber,
+	)
+	if err != nil {
+		return nil, err
+	}
+	issue.JournalID = &journalID
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT p.id, COALESCE(p.toc_position, 0), p.title, COALESCE(u.name, 'Unknown'), p.status, p.page_start, p.page_end
+		FROM papers p
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE p.issue_id = $1
+		ORDER BY p.toc_position ASC NULLS LAST, p.title
+	`, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issue.Contents = []models.IssuePaper{}
+	for rows.Next() {
+		var entry models.IssuePaper
+		if err := rows.Scan(&entry.PaperID, &entry.Position, &entry.Title, &entry.AuthorName, &entry.Status, &entry.PageStart, &entry.PageEnd); err != nil {
+			return nil, err
+		}
+		issue.Contents = append(issue.Contents, entry)
+	}
+	return &issue, rows.Err()
+}
+
+// GetIssue returns an issue with its table of contents
+func (s *Server) GetIssue(c *gin.Context) {
+	issueID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issue ID"})
+		return
+	}
+
+	issue, err := s.loadIssue(c.Request.Context(), issueID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+	c.JSON(http.StatusOK, issue)
+}
+
+// SetIssueContents composes a draft issue from approved papers. The order of
+// the request is the table-of-contents order; papers no longer listed are
+// released from the issue.
+func (s *Server) SetIssueContents(c *gin.Context) {
+	issueID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issue ID"})
+		return
+	}
+
+	var req models.SetIssueContentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	if err := tx.QueryRow(ctx, "SELECT status FROM journal_issues WHERE id = $1 FOR UPDATE", issueID).Scan(&status); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+	if status != "draft" {
+		c.JSON(http.StatusConflict, gin.H{"error": "A published issue cannot be changed"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE papers SET issue_id = NULL, toc_position = NULL, page_start = NULL, page_end = NULL, updated_at = NOW()
+		WHERE issue_id = $1
+	`, issueID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+		return
+	}
+
+	for i, entry := range req.Papers {
+		var paperStatus, title string
+		var currentIssue *uuid.UUID
+		err := tx.QueryRow(ctx, "SELECT status, title, issue_id FROM papers WHERE id = $1 FOR UPDATE", entry.PaperID).Scan(&paperStatus, &title, &currentIssue)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Paper %s not found", entry.PaperID)})
+			return
+		}
+		if paperStatus != "approved" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Paper '%s' is %s; only approved papers can be added to an issue", title, paperStatus)})
+			return
+		}
+		if currentIssue != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Paper '%s' is already assigned to another issue", title)})
+			return
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE papers SET issue_id = $1, toc_position = $2, page_start = $3, page_end = $4, updated_at = NOW()
+			WHERE id = $5
+		`, issueID, i+1, entry.PageStart, entry.PageEnd, entry.PaperID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE journal_issues SET updated_at = NOW() WHERE id = $1", issueID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update issue"})
+		return
+	}
+
+	issue, err := s.loadIssue(ctx, issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch issue"})
+		return
+	}
+	c.JSON(http.StatusOK, issue)
+}
+
+// PublishIssue publishes a draft issue and every paper in it in one
+// transaction. All papers get the same publication date and journal name.
+func (s *Server) PublishIssue(c *gin.Context) {
+	issueID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issue ID"})
+		return
+	}
+
+	var req models.PublishIssueRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish issue"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status, journalTitle, journalType string
+	var plannedDate *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT i.status, i.publication_date::timestamptz, j.title, COALESCE(j.journal_type, 'Local')
+		FROM journal_issues i
+		JOIN journal_volumes v ON i.volume_id = v.id
+		JOIN journals j ON v.journal_id = j.id
+		WHERE i.id = $1
+		FOR UPDATE OF i
+	`, issueID).Scan(&status, &plannedDate, &journalTitle, &journalType)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+	if status != "draft" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Issue is already published"})
+		return
+	}
+
+	publicationDate := time.Now()
+	if req.PublicationDate != nil {
+		publicationDate = *req.PublicationDate
+	} else if plannedDate != nil {
+		publicationDate = *plannedDate
+	}
+
+	var total, approved int
+	if err := tx.QueryRow(ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'approved') FROM papers WHERE issue_id = $1",
+		issueID,
+	).Scan(&total, &approved); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish issue"})
+		return
+	}
+	if total == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot publish an empty issue"})
+		return
+	}
+	if approved != total {
+		c.JSON(http.StatusConflict, gin.H{"error": "Every paper in the issue must be approved before publishing"})
+		return
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE papers
+		SET status = 'published', publication_date = $2, journal_name = $3, journal_type = $4,
+			publication_type = 'Journal Article', fiscal_year = $5, scheduled_publish_at = NULL, updated_at = NOW()
+		WHERE issue_id = $1
+		RETURNING id, author_id, title
+	`, issueID, publicationDate, journalTitle, journalType, derivePaperFiscalYear(&publicationDate, time.Time{}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish papers"})
+		return
+	}
+	type publishedPaper struct {
+		id, authorID uuid.UUID
+		title        string
+	}
+	var published []publishedPaper
+	for rows.Next() {
+		var p publishedPaper
+		if err := rows.Scan(&p.id, &p.authorID, &p.title); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish papers"})
+			return
+		}
+		published = append(published, p)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE journal_issues SET status = 'published', publication_date = $2::date, published_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, issueID, publicationDate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish issue"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish issue"})
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		for _, p := range published {
+			message := fmt.Sprintf("Your paper '%s' has been published in %s", p.title, journalTitle)
+			s.db.Pool.Exec(ctx,
+				"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+				p.authorID, message, p.id)
+		}
+	}()
+
+	issue, err := s.loadIssue(ctx, issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch issue"})
+		return
+	}
+	c.JSON(http.StatusOK, issue)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// accountLockoutKey identifies an account for failure counting. It does not
+// need the account to exist, so responses do not reveal whether it does.
+func accountLockoutKey(tenantID uuid.UUID, address string) string {
+	return tenantID.String() + ":" + strings.ToLower(strings.TrimSpace(address))
+}
+
+// loginAllowed responds with 429 and returns false while the account or the
+// client address has to wait after failed sign-ins
+func (s *Server) loginAllowed(c *gin.Context, accountKey string) bool {
+	ctx := c.Request.Context()
+	accountWait, err := s.accountLockout.Wait(ctx, accountKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return false
+	}
+	ipWait, err := s.ipLockout.Wait(ctx, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return false
+	}
+
+	wait := max(accountWait, ipWait)
+	if wait <= 0 {
+		return true
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Too many failed sign-in attempts; try again in %s", (time.Duration(seconds) * time.Second).String()),
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// mfaAllowed responds with 429 and returns false while the user has to wait
+// after wrong second factor codes
+func (s *Server) mfaAllowed(c *gin.Context, userID string) bool {
+	wait, err := s.mfaLockout.Wait(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the authentication code"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Too many wrong authentication codes; try again in %s", (time.Duration(seconds) * time.Second).String()),
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// recordMFAResult counts a wrong second factor code against the user and
+// forgets earlier ones after a right code
+func (s *Server) recordMFAResult(c *gin.Context, userID string, ok bool) {
+	ctx := c.Request.Context()
+	var err error
+	if ok {
+		err = s.mfaLockout.Reset(ctx, userID)
+	} else {
+		_, err = s.mfaLockout.Fail(ctx, userID)
+	}
+	if err != nil {
+		log.Printf("Failed to record second factor attempt: %v", err)
+	}
+}
+
+// recordLoginFailure counts a failed sign-in against the account and the
+// client address, and tells the owner when the account gets locked
+func (s *Server) recordLoginFailure(c *gin.Context, accountKey, address string) {
+	ctx := c.Request.Context()
+	if _, err := s.ipLockout.Fail(ctx, c.ClientIP()); err != nil {
+		log.Printf("Failed to record sign-in failure: %v", err)
+	}
+	status, err := s.accountLockout.Fail(ctx, accountKey)
+	if err != nil {
+		log.Printf("Failed to record sign-in failure: %v", err)
+		return
+	}
+	if status.Locked {
+		go s.notifyAccountLocked(context.WithoutCancel(mailContext(c)), address, status.LockedUntil)
+	}
+}
+
+func (s *Server) notifyAccountLocked(ctx context.Context, address string, until time.Time) {
+	var userID uuid.UUID
+	var userEmail string
+	err := s.db.Pool.QueryRow(ctx,
+		"SELECT id, email FROM users WHERE LOWER(email) = LOWER($1) AND NOT COALESCE(is_placeholder, FALSE)",
+		address).Scan(&userID, &userEmail)
+	if err != nil {
+		return
+	}
+
+	log.Printf("Account %s locked after repeated failed sign-ins", userEmail)
+	s.db.Pool.Exec(ctx,
+		"INSERT INTO notifications (user_id, message) VALUES ($1, $2)",
+		userID, "Your account was locked for a while after repeated failed sign-in attempts. If this was not you, change your password.")
+	if err := s.emailSender.ForContext(ctx).SendAccountLockedEmail(userEmail, until); err != nil {
+		log.Printf("Failed to send account locked email: %v", err)
+	}
+}
+
+// UnlockUser lifts a sign-in lockout of a user in the admin's tenant
+func (s *Server) UnlockUser(c *gin.Context) {
+	ctx := c.Request.Context()
+	var userEmail string
+	var tenantID uuid.UUID
+	err := s.db.Pool.QueryRow(ctx,
+		"SELECT email, tenant_id FROM users WHERE id = $1",
+		c.Param("id")).Scan(&userEmail, &tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := s.accountLockout.Reset(ctx, accountLockoutKey(tenantID, userEmail)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	if userID, err := uuid.Parse(c.Param("id")); err == nil {
+		s.audit(c, auditUserUnlock, &userID, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
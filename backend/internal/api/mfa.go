@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+// Recovery codes issued when two-factor authentication is turned on
+const recoveryCodeCount = 10
+
+// mfaAccount is a user with their second factor settings
+type mfaAccount struct {
+	user        models.User
+	secret      *string
+	enabled     bool
+	deactivated bool
+}
+
+func (s *Server) loadMFAAccount(ctx context.Context, userID string) (*mfaAccount, error) {
+	var a mfaAccount
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT id, email, name, role, roles, avatar, bio, preferences, created_at, updated_at, tenant_id,
+			totp_secret, totp_enabled, deactivated_at IS NOT NULL
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(
+		&a.user.ID, &a.user.Email, &a.user.Name, &a.user.Role, &a.user.Roles, &a.user.Avatar, &a.user.Bio, &a.user.Preferences,
+		&a.user.CreatedAt, &a.user.UpdatedAt, &a.user.TenantID,
+		&a.secret, &a.enabled, &a.deactivated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// mfaRequired reports whether any of the roles has to use a second factor
+func (s *Server) mfaRequired(roles []string) bool {
+	required := s.config.GetMFARequiredRoles()
+	return slices.ContainsFunc(roles, func(role string) bool {
+		return slices.Contains(required, role)
+	})
+}
+
+// checkTOTP accepts an authenticator code once. The matched time step is
+// recorded so the same code cannot be replayed within its window.
+func (s *Server) checkTOTP(ctx context.Context, userID uuid.UUID, secret, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// checkSecondFactor accepts an authenticator code or an unused recovery
+// code, which is used up
+func (s *Server) checkSecondFactor(ctx context.Context, a *mfaAccount, code string) (bool, error) {
+	if a.secret == nil {
+		return false, nil
+	}
+	if ok, err := s.checkTOTP(ctx, a.user.ID, *a.secret, code); ok || err != nil {
+		return ok, err
+	}
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, a.user.ID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// replaceRecoveryCodes issues a fresh set of recovery codes, voiding the old
+func replaceRecoveryCodes(ctx context.Context, db execer, userID uuid.UUID) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if _, err := db.Exec(ctx,
+			"INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, auth.HashToken(auth.NormalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// GetMFAStatus reports whether the user has two-factor authentication on
+func (s *Server) GetMFAStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	a, err := s.loadMFAAccount(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	status := models.MFAStatus{Enabled: a.enabled, Required: s.mfaRequired(a.user.AllRoles())}
+	if err := s.db.Pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		a.user.ID).Scan(&status.RecoveryCodesRemaining); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTOTP creates a new authenticator secret. It takes effect once
+// EnableTOTP confirms a code from it.
+func (s *Server) SetupTOTP(c *gin.Context) {
+	ctx := c.Request.Context()
+	a, err := s.loadMFAAccount(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if a.enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+	if _, err := s.db.Pool.Exec(ctx,
+		"UPDATE users SET totp_secret = $2, totp_last_step = NULL WHERE id = $1",
+		a.user.ID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	issuer := "RPMS"
+	if name := currentTenant(c).Name; name != "" {
+		issuer = name
+	}
+	uri := auth.TOTPProvisioningURI(issuer, a.user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TOTPSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// EnableTOTP turns two-factor authentication on with a code from the new
+// secret. Other sessions, signed in with the password alone, are ended and
+// a new session with the second factor is returned with the recovery codes.
+func (s *Server) EnableTOTP(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	if !s.mfaAllowed(c, userID) {
+		return
+	}
+	a, err := s.loadMFAAccount(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if a.enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if a.secret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set up an authenticator first"})
+		return
+	}
+
+	ok, err := s.checkTOTP(ctx, a.user.ID, *a.secret, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	s.recordMFAResult(c, userID, ok)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE users SET totp_enabled = TRUE, updated_at = NOW() WHERE id = $1", a.user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	codes, err := replaceRecoveryCodes(ctx, tx, a.user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	if err := s.revokeUserSessions(ctx, a.user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end other sessions"})
+		return
+	}
+	session, err := s.issueSession(ctx, &a.user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe; they are not shown again.",
+		"recovery_codes": codes,
+		"session":        session,
+	})
+}
+
+// DisableTOTP turns two-factor authentication off, unless the user's role
+// requires it
+func (s *Server) DisableTOTP(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	if !s.mfaAllowed(c, userID) {
+		return
+	}
+	a, err := s.loadMFAAccount(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !a.enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if s.mfaRequired(a.user.AllRoles()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is mandatory for your role"})
+		return
+	}
+
+	ok, err := s.checkSecondFactor(ctx, a, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	s.recordMFAResult(c, userID, ok)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, a.user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", a.user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+func (s *Server) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	if !s.mfaAllowed(c, userID) {
+		return
+	}
+	a, err := s.loadMFAAccount(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !a.enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ok, err := s.checkSecondFactor(ctx, a, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	s.recordMFAResult(c, userID, ok)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, s.db.Pool, a.user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// VerifyMFA completes a login that passed the password step, trading the
+// challenge token and an authenticator or recovery code for a session
+func (s *Server) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := s.jwtManager.ValidateMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in attempt expired; please sign in again"})
+		return
+	}
+	if !s.mfaAllowed(c, userID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	a, err := s.loadMFAAccount(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !a.enabled) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in attempt expired; please sign in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if a.deactivated {
+		c.JSON(http.StatusForbidden, gin.H{"error": accountDeactivated})
+		return
+	}
+
+	ok, err := s.checkSecondFactor(ctx, a, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	s.recordMFAResult(c, userID, ok)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	response, err := s.issueSession(ctx, &a.user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/orcid"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SkippedWork is an ORCID work the import left out, with the reason why
+type SkippedWork struct {
+	PutCode int64  `json:"put_code"`
+	Title   string `json:"title"`
+	Reason  string `json:"reason"`
+}
+
+// ImportORCIDWorks turns an uploaded ORCID works JSON export into draft papers
+// for the author. Only the uploaded file is read; the ORCID API is never called.
+// Works already imported (same put code or DOI) are skipped.
+func (s *Server) ImportORCIDWorks(c *gin.Context) {
+	authorID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if id := c.PostForm("author_id"); id != "" {
+		if !middleware.HasPermission(c, rbac.PaperManageAll) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can import works for another author"})
+			return
+		}
+		if authorID, err = uuid.Parse(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+			return
+		}
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ORCID works JSON file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	works, err := orcid.ParseWorks(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var authorName string
+	if err := s.db.Pool.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", authorID).Scan(&authorName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	// Collect what this author already has so re-importing the same file is harmless
+	seenPutCodes := map[int64]bool{}
+	seenDOIs := map[string]bool{}
+	rows, err := s.db.Pool.Query(ctx, "SELECT COALESCE(orcid_put_code, 0), COALESCE(doi, '') FROM papers WHERE author_id = $1", authorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing papers"})
+		return
+	}
+	for rows.Next() {
+		var putCode int64
+		var doi string
+		if err := rows.Scan(&putCode, &doi); err == nil {
+			if putCode != 0 {
+				seenPutCodes[putCode] = true
+			}
+			if doi != "" {
+				seenDOIs[strings.ToLower(doi)] = true
+			}
+		}
+	}
+	rows.Close()
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import works"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	created := []models.Paper{}
+	skipped := []SkippedWork{}
+	for _, work := range works {
+		switch {
+		case work.Title == "":
+			skipped = append(skipped, SkippedWork{PutCode: work.PutCode, Reason: "work has no title"})
+			continue
+		case work.PutCode != 0 && seenPutCodes[work.PutCode]:
+			skipped = append(skipped, SkippedWork{PutCode: work.PutCode, Title: work.Title, Reason: "already imported"})
+			continue
+		case work.DOI != "" && seenDOIs[strings.ToLower(work.DOI)]:
+			skipped = append(skipped, SkippedWork{PutCode: work.PutCode, Title: work.Title, Reason: "a paper with this DOI already exists"})
+			continue
+		}
+
+		paper := models.Paper{
+			Title:           work.Title,
+			Abstract:        work.Abstract,
+			AuthorID:        authorID,
+			Status:          "draft",
+			Type:            "Research Paper",
+			PublicationType: orcid.PublicationType(work.Type),
+			JournalName:     work.JournalTitle,
+			PublicationDate: work.Published,
+			DOI:             work.DOI,
+			FiscalYear:      derivePaperFiscalYear(work.Published, time.Now()),
+		}
+
+		var putCode *int64
+		if work.PutCode != 0 {
+			putCode = &work.PutCode
+		}
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO papers (
+				title, abstract, author_id, status, type, publication_type,
+				journal_name, publication_date, doi, orcid_put_code, fiscal_year
+			)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), $10, $11)
+			RETURNING id, created_at, updated_at
+		`, paper.Title, paper.Abstract, paper.AuthorID, paper.Status, paper.Type, paper.PublicationType,
+			paper.JournalName, paper.PublicationDate, paper.DOI, putCode, paper.FiscalYear,
+		).Scan(&paper.ID, &paper.CreatedAt, &paper.UpdatedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import work: " + work.Title})
+			return
+		}
+
+		// Contributors other than the author become external co-authors
+		position := 0
+		for _, name := range work.Contributors {
+			if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(authorName)) {
+				continue
+			}
+			position++
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO paper_coauthors (paper_id, name, position) VALUES ($1, $2, $3)",
+				paper.ID, strings.TrimSpace(name), position); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import co-authors of: " + work.Title})
+				return
+			}
+		}
+
+		if work.PutCode != 0 {
+			seenPutCodes[work.PutCode] = true
+		}
+		if work.DOI != "" {
+			seenDOIs[strings.ToLower(work.DOI)] = true
+		}
+		created = append(created, paper)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import works"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created": created,
+		"skipped": skipped,
+	})
+}
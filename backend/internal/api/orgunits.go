@@ -0,0 +1,496 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/orgunit"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// orgSubtreeQuery selects unit $1 and every unit below it
+const orgSubtreeQuery = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM org_units WHERE id = $1
+		UNION ALL
+		SELECT o.id FROM org_units o JOIN subtree s ON o.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
+// staffInScopeQuery selects the users with role $1 whose scope covers unit $2:
+// unscoped staff, and staff scoped to the unit or one of its ancestors. With a
+// null unit only unscoped staff are selected. The author of paper $3 is left
+// out, as they hear about their paper as its author.
+const staffInScopeQuery = `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM org_units WHERE id = $2
+		UNION ALL
+		SELECT o.id, o.parent_id FROM org_units o JOIN ancestors a ON o.id = a.parent_id
+	)
+	SELECT id FROM users
+	WHERE $1 = ANY(roles) AND (scope_org_unit_id IS NULL OR scope_org_unit_id IN (SELECT id FROM ancestors))
+		AND NOT EXISTS (SELECT 1 FROM papers WHERE id = $3 AND author_id = users.id)`
+
+// scopeUnits returns the units staff such as editors and coordinators are
+// limited to: their scope unit and everything below it. scoped is false for
+// users who see every unit or see no one else's papers anyway.
+func (s *Server) scopeUnits(ctx context.Context, c *gin.Context) (units []uuid.UUID, scoped bool, err error) {
+	if !middleware.HasPermission(c, rbac.PaperViewAll) || middleware.HasPermission(c, rbac.ScopeAllUnits) {
+		return nil, false, nil
+	}
+
+	var scope *uuid.UUID
+	if err := s.db.Pool.QueryRow(ctx, "SELECT scope_org_unit_id FROM users WHERE id = $1", c.GetString("user_id")).Scan(&scope); err != nil {
+		return nil, false, err
+	}
+	if scope == nil {
+		return nil, false, nil
+	}
+
+	rows, err := s.db.Pool.Query(ctx, orgSubtreeQuery, *scope)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, false, err
+		}
+		units = append(units, id)
+	}
+	return units, true, rows.Err()
+}
+
+// notifyStaffInScope notifies every user with the role whose scope covers the unit
+func (s *Server) notifyStaffInScope(ctx context.Context, role string, unitID *uuid.UUID, message string, paperID uuid.UUID) {
+	rows, err := s.db.Pool.Query(ctx, staffInScopeQuery, role, unitID, paperID)
+	if err != nil {
+		return
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		s.db.Pool.Exec(ctx,
+			"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+			id, message, paperID)
+	}
+}
+
+func containsUnit(units []uuid.UUID, id uuid.UUID) bool {
+	for _, u := range units {
+		if u == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) loadOrgUnits(ctx context.Context) ([]models.OrgUnit, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, parent_id, kind, name, COALESCE(name_amharic, ''), COALESCE(code, ''), created_at, updated_at
+		FROM org_units
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []models.OrgUnit{}
+	for rows.Next() {
+		var u models.OrgUnit
+		if err := rows.Scan(&u.ID, &u.ParentID, &u.Kind, &u.Name, &u.NameAmharic, &u.Code, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// checkOrgUnitPlacement validates the parent of a new or moved unit. A unit
+// cannot be moved under itself or one of its descendants.
+func checkOrgUnitPlacement(units []models.OrgUnit, self *uuid.UUID, kind string, parentID *uuid.UUID) error {
+	parentKind := ""
+	if parentID != nil {
+		found := false
+		for _, u := range units {
+			if u.ID == *parentID {
+				parentKind, found = u.Kind, true
+				break
+			}
+		}
+		if !found {
+			return errors.New("parent unit not found")
+		}
+		if self != nil && containsUnit(orgunit.Subtree(units, *self), *parentID) {
+			return errors.New("a unit cannot be placed under itself or one of its sub-units")
+		}
+	}
+	return orgunit.CheckParent(kind, parentKind)
+}
+
+// GetOrgUnits returns the organizational hierarchy as a tree
+func (s *Server) GetOrgUnits(c *gin.Context) {
+	units, err := s.loadOrgUnits(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizational units"})
+		return
+	}
+	c.JSON(http.StatusOK, orgunit.Tree(units))
+}
+
+func (s *Server) CreateOrgUnit(c *gin.Context) {
+	var req models.OrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	units, err := s.loadOrgUnits(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizational units"})
+		return
+	}
+	if err := checkOrgUnitPlacement(units, nil, req.Kind, req.ParentID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u models.OrgUnit
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO org_units (parent_id, kind, name, name_amharic, code)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING id, parent_id, kind, name, COALESCE(name_amharic, ''), COALESCE(code, ''), created_at, updated_at
+	`, req.ParentID, req.Kind, req.Name, req.NameAmharic, req.Code).Scan(
+		&u.ID, &u.ParentID, &u.Kind, &u.Name, &u.NameAmharic, &u.Code, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create unit, the code may already be in use"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, u)
+}
+
+// UpdateOrgUnit renames or moves a unit. Children of a unit whose kind changes
+// must still be allowed under the new kind.
+func (s *Server) UpdateOrgUnit(c *gin.Context) {
+	unitID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	var req models.OrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	units, err := s.loadOrgUnits(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizational units"})
+		return
+	}
+	if err := checkOrgUnitPlacement(units, &unitID, req.Kind, req.ParentID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, u := range units {
+		if u.ParentID != nil && *u.ParentID == unitID {
+			if err := orgunit.CheckParent(u.Kind, req.Kind); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	var u models.OrgUnit
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE org_units
+		SET parent_id = $1, kind = $2, name = $3, name_amharic = NULLIF($4, ''), code = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $6
+		RETURNING id, parent_id, kind, name, COALESCE(name_amharic, ''), COALESCE(code, ''), created_at, updated_at
+	`, req.ParentID, req.Kind, req.Name, req.NameAmharic, req.Code, unitID).Scan(
+		&u.ID, &u.ParentID, &u.Kind, &u.Name, &u.NameAmharic, &u.Code, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to update unit, the code may already be in use"})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// DeleteOrgUnit removes a unit without sub-units. Its users and papers are
+// left without a unit.
+func (s *Server) DeleteOrgUnit(c *gin.Context) {
+	unitID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var hasChildren bool
+	if err := s.db.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM org_units WHERE parent_id = $1)", unitID).Scan(&hasChildren); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete unit"})
+		return
+	}
+	if hasChildren {
+		c.JSON(http.StatusConflict, gin.H{"error": "Move or delete the unit's sub-units first"})
+		return
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, "DELETE FROM org_units WHERE id = $1", unitID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete unit"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unit deleted successfully"})
+}
+
+const errNotScopeable = "Only staff such as editors and coordinators can be scoped to a unit"
+
+// scopeableRoles reports whether users with the roles can be scoped to a
+// unit: they see every paper, and no role lets them see every unit
+func (s *Server) scopeableRoles(ctx context.Context, roles []string) (bool, error) {
+	var perms []string
+	for _, role := range roles {
+		p, _, err := s.lookupRole(ctx, role)
+		if err != nil {
+			return false, err
+		}
+		perms = append(perms, p...)
+	}
+	return slices.Contains(perms, rbac.PaperViewAll) && !slices.Contains(perms, rbac.ScopeAllUnits), nil
+}
+
+// SetUserOrgUnit assigns a user to a unit and sets the scope of editors and coordinators
+func (s *Server) SetUserOrgUnit(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.SetUserOrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var roles []string
+	if err := s.db.Pool.QueryRow(ctx, "SELECT roles FROM users WHERE id = $1", userID).Scan(&roles); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if req.ScopeOrgUnitID != nil {
+		scopeable, err := s.scopeableRoles(ctx, roles)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the user's roles"})
+			return
+		}
+		if !scopeable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errNotScopeable})
+			return
+		}
+	}
+
+	var user models.User
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE users SET org_unit_id = $1, scope_org_unit_id = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING id, email, name, role, roles, org_unit_id, scope_org_unit_id
+	`, req.OrgUnitID, req.ScopeOrgUnitID, userID).Scan(
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Roles, &user.OrgUnitID, &user.ScopeOrgUnitID,
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit not found"})
+		return
+	}
+	s.audit(c, auditUserOrgUnit, &user.ID, gin.H{"org_unit_id": user.OrgUnitID, "scope_org_unit_id": user.ScopeOrgUnitID})
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SetPaperOrgUnit moves a paper to another unit. Scoped staff can only move
+// papers within their scope.
+func (s *Server) SetPaperOrgUnit(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.SetPaperOrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	scope, scoped, err := s.scopeUnits(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your scope"})
+		return
+	}
+	if scoped {
+		var current *uuid.UUID
+		err := s.db.Pool.QueryRow(ctx, `
+			SELECT COALESCE(p.org_unit_id, u.org_unit_id)
+			FROM papers p LEFT JOIN users u ON p.author_id = u.id
+			WHERE p.id = $1
+		`, paperID).Scan(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+			return
+		}
+		if current == nil || !containsUnit(scope, *current) || req.OrgUnitID == nil || !containsUnit(scope, *req.OrgUnitID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The paper and the new unit must both be within your scope"})
+			return
+		}
+	}
+
+	var paper models.Paper
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE papers SET org_unit_id = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, title, status, org_unit_id, updated_at
+	`, req.OrgUnitID, paperID).Scan(&paper.ID, &paper.Title, &paper.Status, &paper.OrgUnitID, &paper.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paper)
+}
+
+// GetOrgUnitReport reports researchers, papers and budgets per unit, each unit
+// with its own figures and the figures rolled up from its sub-units.
+// ?org_unit_id= reports on one branch, ?fiscal_year= limits the papers counted.
+// Scoped staff only see their branch.
+func (s *Server) GetOrgUnitReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	units, err := s.loadOrgUnits(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizational units"})
+		return
+	}
+
+	scope, scoped, err := s.scopeUnits(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your scope"})
+		return
+	}
+	var branch []uuid.UUID
+	if scoped {
+		branch = scope
+	}
+	if raw := c.Query("org_unit_id"); raw != "" {
+		root, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid org_unit_id"})
+			return
+		}
+		if scoped && !containsUnit(scope, root) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The unit is outside your scope"})
+			return
+		}
+		branch = orgunit.Subtree(units, root)
+	}
+	if branch != nil {
+		filtered := units[:0:0]
+		for _, u := range units {
+			if containsUnit(branch, u.ID) {
+				filtered = append(filtered, u)
+			}
+		}
+		units = filtered
+	}
+
+	own := map[uuid.UUID]orgunit.Totals{}
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT org_unit_id, COUNT(*) FROM users
+		WHERE org_unit_id IS NOT NULL AND 'author' = ANY(roles)
+		GROUP BY org_unit_id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count researchers"})
+		return
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count researchers"})
+			return
+		}
+		t := own[id]
+		t.Researchers = n
+		own[id] = t
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT COALESCE(p.org_unit_id, u.org_unit_id) AS unit, COUNT(*), COUNT(*) FILTER (WHERE p.status = 'published'),
+			   COALESCE(SUM(p.allocated_budget), 0), COALESCE(SUM(p.external_budget), 0), COALESCE(SUM(p.nrf_fund), 0)
+		FROM papers p
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE COALESCE(p.org_unit_id, u.org_unit_id) IS NOT NULL AND ($1 = '' OR p.fiscal_year = $1)
+		GROUP BY unit
+	`, c.Query("fiscal_year"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var papers, published int
+		var allocated, external, nrf float64
+		if err := rows.Scan(&id, &papers, &published, &allocated, &external, &nrf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
+			return
+		}
+		t := own[id]
+		t.Papers, t.Published = papers, published
+		t.AllocatedBudget, t.ExternalBudget, t.NRFFund = allocated, external, nrf
+		own[id] = t
+	}
+
+	c.JSON(http.StatusOK, orgunit.RollUp(units, own))
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// How long a password reset link stays valid
+const passwordResetTTL = time.Hour
+
+const passwordResetSent = "If an account exists for this email, a password reset link has been sent."
+
+// RequestPasswordReset emails a reset link. The response is the same whether
+// or not the account exists, and the lookup and email happen after replying
+// so the timing does not tell either.
+func (s *Server) RequestPasswordReset(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := strings.ToLower(strings.TrimSpace(req.Email))
+	if !s.resetsByIP.allow(c.ClientIP()) || !s.resetsByEmail.allow(address) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password reset requests; please try again later"})
+		return
+	}
+
+	go s.sendPasswordReset(context.WithoutCancel(mailContext(c)), address)
+
+	c.JSON(http.StatusOK, gin.H{"message": passwordResetSent})
+}
+
+func (s *Server) sendPasswordReset(ctx context.Context, address string) {
+	// Placeholder accounts have nobody behind them yet, and deactivated ones
+	// cannot sign in anyway
+	var userID uuid.UUID
+	err := s.db.Pool.QueryRow(ctx,
+		"SELECT id FROM users WHERE LOWER(email) = $1 AND NOT COALESCE(is_placeholder, FALSE) AND deactivated_at IS NULL",
+		address).Scan(&userID)
+	if err != nil {
+		return
+	}
+
+	link, err := s.createPasswordReset(ctx, userID, passwordResetTTL)
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return
+	}
+	if err := s.emailSender.ForContext(ctx).SendPasswordResetEmail(address, link, passwordResetTTL); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
+// createPasswordReset stores a reset token valid for ttl and returns the link
+// that uses it
+func (s *Server) createPasswordReset(ctx context.Context, userID uuid.UUID, ttl time.Duration) (string, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Pool.Exec(ctx,
+		"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, hash, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return s.config.PasswordResetURL + "?token=" + url.QueryEscape(token), nil
+}
+
+// ConfirmPasswordReset sets a new password with a reset token. The token and
+// every other outstanding one of the account are used up, and all sessions
+// are signed out.
+func (s *Server) ConfirmPasswordReset(c *gin.Context) {
+	var req models.ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	var expiresAt time.Time
+	var usedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT r.user_id, r.expires_at, r.used_at
+		FROM password_resets r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.token_hash = $1
+		FOR UPDATE OF r
+	`, auth.HashToken(req.Token)).Scan(&userID, &expiresAt, &usedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err != nil || usedAt != nil || time.Now().After(expiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This reset link is invalid or has expired"})
+		return
+	}
+
+	// Use the tokens up before the password changes, which the provider may
+	// do outside the database. Should that fail, the user asks for a new link
+	// rather than holding one that still works.
+	if _, err := tx.Exec(ctx,
+		"UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL",
+		userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := s.auth.SetPassword(ctx, userID, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password; please request a new reset link"})
+		return
+	}
+
+	if err := s.revokeUserSessions(ctx, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset, but existing sessions could not be signed out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your password has been reset; please sign in"})
+}
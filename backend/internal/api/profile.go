@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/profile"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// loadResearcherProfile assembles a researcher's profile. The public version
+// only includes published papers, projects and events, reviews of published
+// papers, and no personal details.
+func (s *Server) loadResearcherProfile(ctx context.Context, userID uuid.UUID, public bool) (*models.ResearcherProfile, error) {
+	p := &models.ResearcherProfile{ID: userID, Public: public, GeneratedAt: time.Now()}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT name, email, COALESCE(avatar, ''), COALESCE(bio, ''), COALESCE(academic_rank, ''), COALESCE(qualification, ''),
+			   COALESCE(author_category, ''), COALESCE(orcid, ''), COALESCE(employment_type, ''), COALESCE(gender, ''), COALESCE(date_of_birth, '')
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(
+		&p.Name, &p.Email, &p.Avatar, &p.Bio, &p.AcademicRank, &p.Qualification,
+		&p.AuthorCategory, &p.ORCID, &p.EmploymentType, &p.Gender, &p.DateOfBirth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if public {
+		p.Email, p.EmploymentType, p.Gender, p.DateOfBirth = "", "", "", ""
+	}
+
+	publishedOnly := ""
+	if public {
+		publishedOnly = " AND p.status = 'published'"
+	}
+
+	// Retracted papers stay on the public record, flagged as retracted
+	publicationStatuses := publishedOnly
+	if public {
+		publicationStatuses = " AND p.status IN ('published', 'retracted')"
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT p.id, p.title, p.status, COALESCE(p.publication_id, ''), COALESCE(p.publication_type, ''), COALESCE(p.journal_name, ''),
+			   p.publication_date, COALESCE(p.doi, ''), p.created_at
+		FROM papers p
+		WHERE p.author_id = $1`+publicationStatuses+`
+		ORDER BY COALESCE(p.publication_date, p.created_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Publications = []models.ProfilePublication{}
+	for rows.Next() {
+		var pub models.ProfilePublication
+		if err := rows.Scan(&pub.ID, &pub.Title, &pub.Status, &pub.PublicationID, &pub.PublicationType, &pub.JournalName,
+			&pub.PublicationDate, &pub.DOI, &pub.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Publications = append(p.Publications, pub)
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT p.id, p.title, p.status, COALESCE(p.fiscal_year, ''), COALESCE(p.research_type, ''), COALESCE(p.completion_status, ''),
+			   COALESCE(p.allocated_budget, 0), COALESCE(p.external_budget, 0), COALESCE(p.nrf_fund, 0)
+		FROM papers p
+		WHERE p.author_id = $1
+		  AND COALESCE(p.allocated_budget, 0) + COALESCE(p.external_budget, 0) + COALESCE(p.nrf_fund, 0) > 0`+publishedOnly+`
+		ORDER BY p.fiscal_year DESC, p.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Projects = []models.ProfileProject{}
+	for rows.Next() {
+		var proj models.ProfileProject
+		if err := rows.Scan(&proj.ID, &proj.Title, &proj.Status, &proj.FiscalYear, &proj.ResearchType, &proj.CompletionStatus,
+			&proj.AllocatedBudget, &proj.ExternalBudget, &proj.NRFFund); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Projects = append(p.Projects, proj)
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT p.id, p.title, COALESCE(r.recommendation, ''), r.created_at
+		FROM reviews r
+		JOIN papers p ON r.paper_id = p.id
+		WHERE r.reviewer_id = $1`+publishedOnly+`
+		ORDER BY r.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Reviews = []models.ProfileReview{}
+	for rows.Next() {
+		var rev models.ProfileReview
+		if err := rows.Scan(&rev.PaperID, &rev.PaperTitle, &rev.Recommendation, &rev.ReviewedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if public {
+			// Review outcomes are confidential
+			rev.Recommendation = ""
+		}
+		p.Reviews = append(p.Reviews, rev)
+	}
+	rows.Close()
+
+	eventFilter := ""
+	if public {
+		eventFilter = " AND e.status = 'published'"
+	}
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT e.id, e.title, COALESCE(e.category, ''), COALESCE(e.status, ''), e.date, COALESCE(e.location, '')
+		FROM events e
+		WHERE e.coordinator_id = $1`+eventFilter+`
+		ORDER BY e.date DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	p.Events = []models.ProfileEvent{}
+	for rows.Next() {
+		var ev models.ProfileEvent
+		if err := rows.Scan(&ev.ID, &ev.Title, &ev.Category, &ev.Status, &ev.Date, &ev.Location); err != nil {
+			return nil, err
+		}
+		p.Events = append(p.Events, ev)
+	}
+	return p, rows.Err()
+}
+
+// GetResearcherProfile serves the public profile of a researcher (?format=json|html|pdf)
+func (s *Server) GetResearcherProfile(c *gin.Context) {
+	s.serveResearcherProfile(c, true)
+}
+
+// GetResearcherDossier serves the complete profile for promotion dossiers.
+// Researchers can fetch their own; admins can fetch anyone's.
+func (s *Server) GetResearcherDossier(c *gin.Context) {
+	if !middleware.HasPermission(c, rbac.UserManage) && c.GetString("user_id") != c.Param("id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only generate your own dossier"})
+		return
+	}
+	s.serveResearcherProfile(c, false)
+}
+
+func (s *Server) serveResearcherProfile(c *gin.Context, public bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, html or pdf"})
+		return
+	}
+
+	p, err := s.loadResearcherProfile(c.Request.Context(), userID, public)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Researcher not found"})
+		return
+	}
+
+	filename := "cv-" + strings.ReplaceAll(strings.ToLower(p.Name), " ", "-")
+	if !public {
+		filename += "-dossier"
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, p)
+		return
+	case "html":
+		if err := profile.RenderHTML(&buf, p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render profile"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	case "pdf":
+		if err := profile.RenderPDF(&buf, p, fetchAvatar(c.Request.Context(), p.Avatar)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render profile"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+	}
+}
+
+// fetchAvatar downloads the avatar for embedding in a PDF. Any failure just
+// leaves the picture out.
+func fetchAvatar(ctx context.Context, url string) *profile.Avatar {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return nil
+	}
+	return profile.AvatarFromBytes(data)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows up to limit events per key within a sliding window. It
+// lives in memory, so each server instance counts on its own.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, events: map[string][]time.Time{}}
+}
+
+// allow records an event for key and reports whether it is within the limit
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+	recent := l.events[key][:0]
+	for _, t := range l.events[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.events[key] = recent
+		return false
+	}
+	l.events[key] = append(recent, now)
+
+	// Drop keys that have gone quiet so the map does not grow forever
+	if len(l.events) > 10000 {
+		for k, ts := range l.events {
+			if len(ts) == 0 || !ts[len(ts)-1].After(cutoff) {
+				delete(l.events, k)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"rpms-backend/internal/ethcal"
+	"rpms-backend/internal/orgunit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FiscalYearReport aggregates papers and budgets for one Ethiopian fiscal year
+type FiscalYearReport struct {
+	FiscalYear      string    `json:"fiscal_year"`
+	StartsOn        time.Time `json:"starts_on"`
+	EndsBefore      time.Time `json:"ends_before"`
+	Papers          int       `json:"papers"`
+	Published       int       `json:"published"`
+	AllocatedBudget float64   `json:"allocated_budget"`
+	ExternalBudget  float64   `json:"external_budget"`
+	NRFFund         float64   `json:"nrf_fund"`
+}
+
+// GetFiscalYearReport groups papers by Ethiopian fiscal year.
+// Papers without a recognisable fiscal_year are placed by their publication or submission date.
+// ?status= and ?org_unit_id= filter the papers counted.
+func (s *Server) GetFiscalYearReport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := `
+		SELECT COALESCE(p.fiscal_year, ''), p.status, p.publication_date, p.created_at,
+			   COALESCE(p.allocated_budget, 0), COALESCE(p.external_budget, 0), COALESCE(p.nrf_fund, 0)
+		FROM papers p
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE ($1 = '' OR p.status = $1)
+	`
+	args := []interface{}{c.Query("status")}
+
+	// ?org_unit_id= limits the report to a branch of the hierarchy; scoped
+	// editors and coordinators are always limited to their own branch
+	scope, scoped, err := s.scopeUnits(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check your scope"})
+		return
+	}
+	if raw := c.Query("org_unit_id"); raw != "" {
+		root, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid org_unit_id"})
+			return
+		}
+		if scoped && !containsUnit(scope, root) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The unit is outside your scope"})
+			return
+		}
+		units, err := s.loadOrgUnits(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizational units"})
+			return
+		}
+		scope, scoped = orgunit.Subtree(units, root), true
+	}
+	if scoped {
+		query += " AND COALESCE(p.org_unit_id, u.org_unit_id) = ANY($2)"
+		args = append(args, scope)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
+		return
+	}
+	defer rows.Close()
+
+	byYear := map[int]*FiscalYearReport{}
+	for rows.Next() {
+		var fiscalYear, status string
+		var publicationDate *time.Time
+		var createdAt time.Time
+		var allocated, external, nrf float64
+		if err := rows.Scan(&fiscalYear, &status, &publicationDate, &createdAt, &allocated, &external, &nrf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan paper"})
+			return
+		}
+
+		var year int
+		if _, err := fmt.Sscanf(fiscalYear, "%d EFY", &year); err != nil {
+			if publicationDate != nil && !publicationDate.IsZero() {
+				year = ethcal.FiscalYear(*publicationDate)
+			} else {
+				year = ethcal.FiscalYear(createdAt)
+			}
+		}
+
+		report, ok := byYear[year]
+		if !ok {
+			start, end := ethcal.FiscalYearBounds(year)
+			report = &FiscalYearReport{FiscalYear: ethcal.FiscalYearLabel(year), StartsOn: start, EndsBefore: end}
+			byYear[year] = report
+		}
+		report.Papers++
+		if status == "published" {
+			report.Published++
+		}
+		report.AllocatedBudget += allocated
+		report.ExternalBudget += external
+		report.NRFFund += nrf
+	}
+
+	years := make([]int, 0, len(byYear))
+	for year := range byYear {
+		years = append(years, year)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+
+	reports := make([]FiscalYearReport, 0, len(years))
+	for _, year := range years {
+		reports = append(reports, *byYear[year])
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
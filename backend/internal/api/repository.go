@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/export"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/orcid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Published papers are visible in the public repository. Retracted papers
+// stay listed with their retraction notice.
+const repositoryQuery = `
+	SELECT p.id, COALESCE(p.publication_id, ''), p.title, COALESCE(p.publication_title_amharic, ''), COALESCE(p.abstract, ''),
+		   COALESCE(p.type, 'Research Paper'), COALESCE(p.publication_type, ''), COALESCE(p.journal_name, ''), p.publication_date,
+		   COALESCE(p.doi, ''), COALESCE(p.publication_isced_band, ''), COALESCE(p.file_url, ''),
+		   COALESCE(u.name, ''), COALESCE(u.orcid, ''),
+		   COALESCE(v.volume_number::text, ''), COALESCE(i.issue_number::text, ''),
+		   CASE WHEN p.page_start IS NULL THEN '' ELSE p.page_start || '-' || p.page_end END,
+		   p.embargo_until, p.retracted_at, COALESCE(p.retraction_notice, '')
+	FROM papers p
+	LEFT JOIN users u ON p.author_id = u.id
+	LEFT JOIN journal_issues i ON p.issue_id = i.id
+	LEFT JOIN journal_volumes v ON i.volume_id = v.id
+	WHERE p.status IN ('published', 'retracted')
+`
+
+// loadRepositoryRecords returns published and retracted papers with their
+// author first and co-authors after, in author order. A nil paperID loads
+// every paper in the repository. Papers under embargo are listed without
+// their full-text link.
+func (s *Server) loadRepositoryRecords(ctx context.Context, paperID *uuid.UUID) ([]models.RepositoryRecord, error) {
+	var rows pgx.Rows
+	var err error
+	if paperID != nil {
+		rows, err = s.db.Pool.Query(ctx, repositoryQuery+" AND p.id = $1", *paperID)
+	} else {
+		rows, err = s.db.Pool.Query(ctx, repositoryQuery+" ORDER BY p.publication_date DESC NULLS LAST, p.created_at DESC")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	records := []models.RepositoryRecord{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		var rec models.RepositoryRecord
+		var author models.RecordAuthor
+		if err := rows.Scan(
+			&rec.ID, &rec.PublicationID, &rec.Title, &rec.PublicationTitleAmharic, &rec.Abstract,
+			&rec.Type, &rec.PublicationType, &rec.JournalName, &rec.PublicationDate,
+			&rec.DOI, &rec.ISCEDBand, &rec.FileUrl,
+			&author.Name, &author.ORCID,
+			&rec.Volume, &rec.Issue, &rec.Pages,
+			&rec.EmbargoUntil, &rec.RetractedAt, &rec.RetractionNotice,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if rec.EmbargoUntil != nil && rec.EmbargoUntil.After(now) {
+			// Metadata stays public during an embargo, the full text does not
+			rec.FileUrl = ""
+		} else {
+			rec.EmbargoUntil = nil
+		}
+		rec.Retracted = rec.RetractedAt != nil
+		if author.Name != "" {
+			author.ORCIDURI = orcid.URI(author.ORCID)
+			rec.Authors = append(rec.Authors, author)
+		}
+		index[rec.ID] = len(records)
+		records = append(records, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return records, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(records))
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	coRows, err := s.db.Pool.Query(ctx, `
+		SELECT paper_id, name, COALESCE(affiliation, ''), COALESCE(orcid, '')
+		FROM paper_coauthors
+		WHERE paper_id = ANY($1)
+		ORDER BY paper_id, position ASC
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer coRows.Close()
+	for coRows.Next() {
+		var id uuid.UUID
+		var author models.RecordAuthor
+		if err := coRows.Scan(&id, &author.Name, &author.Affiliation, &author.ORCID); err != nil {
+			return nil, err
+		}
+		author.ORCIDURI = orcid.URI(author.ORCID)
+		i := index[id]
+		records[i].Authors = append(records[i].Authors, author)
+	}
+	return records, coRows.Err()
+}
+
+// GetRepositoryPapers lists the published papers of the public repository
+func (s *Server) GetRepositoryPapers(c *gin.Context) {
+	records, err := s.loadRepositoryRecords(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repository"})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// GetRepositoryPaper returns a single published paper
+func (s *Server) GetRepositoryPaper(c *gin.Context) {
+	rec, ok := s.repositoryRecord(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// GetPaperCitation renders a published paper as a citation (?format=bibtex|ris|csl-json)
+func (s *Server) GetPaperCitation(c *gin.Context) {
+	rec, ok := s.repositoryRecord(c)
+	if !ok {
+		return
+	}
+	s.writeCitations(c, "citation-"+rec.ID.String(), []models.RepositoryRecord{rec})
+}
+
+// ExportRepository renders every published paper in one citation file
+func (s *Server) ExportRepository(c *gin.Context) {
+	records, err := s.loadRepositoryRecords(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repository"})
+		return
+	}
+	s.writeCitations(c, "rpms-repository", records)
+}
+
+func (s *Server) repositoryRecord(c *gin.Context) (models.RepositoryRecord, bool) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return models.RepositoryRecord{}, false
+	}
+	records, err := s.loadRepositoryRecords(c.Request.Context(), &paperID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper"})
+		return models.RepositoryRecord{}, false
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return models.RepositoryRecord{}, false
+	}
+	return records[0], true
+}
+
+func (s *Server) writeCitations(c *gin.Context, filename string, records []models.RepositoryRecord) {
+	format := c.DefaultQuery("format", export.FormatBibTeX)
+	data, err := export.Citations(format, records)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+export.FileExtension(format)+`"`)
+	c.Data(http.StatusOK, export.ContentType(format), data)
+}
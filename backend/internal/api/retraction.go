@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"rpms-backend/internal/documents"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Statuses a paper can be withdrawn from: submitted but not yet decided on
+var withdrawableStatuses = map[string]bool{
+	"submitted":                   true,
+	"under_review":                true,
+	"recommended_for_publication": true,
+}
+
+// WithdrawPaper lets the author take a submission back before a decision is
+// made. Unlike DeletePaper the paper, its reviews and notifications are kept.
+func (s *Server) WithdrawPaper(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.WithdrawPaperRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	owns, err := s.callerOwnsPaper(c, paperID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if !owns {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the paper's author can withdraw it"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var status string
+	if err := s.db.Pool.QueryRow(ctx, "SELECT status FROM papers WHERE id = $1", paperID).Scan(&status); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+	if !withdrawableStatuses[status] {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A paper with status %s cannot be withdrawn", status)})
+		return
+	}
+
+	var paper models.Paper
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE papers
+		SET status = 'withdrawn', withdrawn_at = NOW(), withdrawal_reason = NULLIF($2, ''), scheduled_publish_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = $3
+		RETURNING id, title, author_id, status, withdrawn_at, COALESCE(withdrawal_reason, ''), updated_at
+	`, paperID, req.Reason, status).Scan(
+		&paper.ID, &paper.Title, &paper.AuthorID, &paper.Status, &paper.WithdrawnAt, &paper.WithdrawalReason, &paper.UpdatedAt,
+	)
+	if err != nil {
+		// The status changed between the check and the update
+		c.JSON(http.StatusConflict, gin.H{"error": "Paper status changed, please reload and try again"})
+		return
+	}
+
+	// Let the editors who reviewed the paper know it is no longer under consideration
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		rows, err := s.db.Pool.Query(ctx,
+			"SELECT DISTINCT reviewer_id FROM reviews WHERE paper_id = $1", paper.ID)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var reviewerID uuid.UUID
+			if err := rows.Scan(&reviewerID); err == nil {
+				message := fmt.Sprintf("Paper '%s' has been withdrawn by its author", paper.Title)
+				s.db.Pool.Exec(ctx,
+					"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+					reviewerID, message, paper.ID)
+			}
+		}
+	}()
+
+	c.JSON(http.StatusOK, paper)
+}
+
+// RetractPaper retracts a published paper. The paper stays in the repository,
+// marked as retracted with the public notice, and the publication
+// certificates issued for it are revoked.
+func (s *Server) RetractPaper(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.RetractPaperRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retract paper"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var paper models.Paper
+	err = tx.QueryRow(ctx, `
+		UPDATE papers
+		SET status = 'retracted', retracted_at = NOW(), retracted_by = $2, retraction_notice = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'published'
+		RETURNING id, title, author_id, status, retracted_at, retraction_notice, updated_at
+	`, paperID, c.GetString("user_id"), req.Notice).Scan(
+		&paper.ID, &paper.Title, &paper.AuthorID, &paper.Status, &paper.RetractedAt, &paper.RetractionNotice, &paper.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found or not published"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE generated_documents
+		SET revoked_at = NOW(), revoked_by = $2, revocation_reason = 'The paper has been retracted'
+		WHERE paper_id = $1 AND kind = $3 AND revoked_at IS NULL
+	`, paperID, c.GetString("user_id"), documents.KindPublicationCertificate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke publication certificates"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retract paper"})
+		return
+	}
+
+	// Notify the author and the co-authors with an account
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		message := fmt.Sprintf("Your paper '%s' has been retracted: %s", paper.Title, paper.RetractionNotice)
+		s.db.Pool.Exec(ctx,
+			"INSERT INTO notifications (user_id, message, paper_id) VALUES ($1, $2, $3)",
+			paper.AuthorID, message, paper.ID)
+		s.db.Pool.Exec(ctx, `
+			INSERT INTO notifications (user_id, message, paper_id)
+			SELECT DISTINCT user_id, $2, paper_id FROM paper_coauthors
+			WHERE paper_id = $1 AND user_id IS NOT NULL AND user_id <> $3
+		`, paper.ID, message, paper.AuthorID)
+	}()
+
+	c.JSON(http.StatusOK, paper)
+}
+
+// GetRetractionNotice serves the public retraction notice of a retracted
+// paper as a PDF watermarked RETRACTED
+func (s *Server) GetRetractionNotice(c *gin.Context) {
+	rec, ok := s.repositoryRecord(c)
+	if !ok {
+		return
+	}
+	if !rec.Retracted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper has not been retracted"})
+		return
+	}
+
+	notice := documents.RetractionNotice{
+		PaperTitle:      rec.Title,
+		PublicationID:   rec.PublicationID,
+		JournalName:     rec.JournalName,
+		DOI:             rec.DOI,
+		PublicationDate: rec.PublicationDate,
+		RetractedAt:     *rec.RetractedAt,
+		Notice:          rec.RetractionNotice,
+	}
+	for _, a := range rec.Authors {
+		notice.Authors = append(notice.Authors, a.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := documents.RenderRetractionNotice(&buf, notice, s.documentBranding(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render retraction notice"})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="retraction-`+rec.ID.String()+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Custom role permissions are cached like tenants; changes made through the
+// API clear the cache at once, other instances catch up within a minute
+const roleCacheTTL = time.Minute
+
+type roleCache struct {
+	mu      sync.Mutex
+	entries map[string]roleCacheEntry
+}
+
+type roleCacheEntry struct {
+	permissions []string
+	exists      bool
+	expires     time.Time
+}
+
+func (rc *roleCache) get(key string) (roleCacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return roleCacheEntry{}, false
+	}
+	return e, true
+}
+
+func (rc *roleCache) put(key string, e roleCacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.entries == nil {
+		rc.entries = map[string]roleCacheEntry{}
+	}
+	e.expires = time.Now().Add(roleCacheTTL)
+	rc.entries[key] = e
+}
+
+func (rc *roleCache) clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries = nil
+}
+
+// lookupRole returns the permissions of a built-in role or a custom role of
+// the context's tenant, and whether the role exists
+func (s *Server) lookupRole(ctx context.Context, role string) ([]string, bool, error) {
+	if perms, ok := rbac.BuiltinRoles[role]; ok {
+		return perms, true, nil
+	}
+
+	tenant, _ := database.TenantFrom(ctx)
+	key := tenant.String() + "|" + role
+	if e, ok := s.roles.get(key); ok {
+		return e.permissions, e.exists, nil
+	}
+
+	var perms []string
+	err := s.db.Pool.QueryRow(ctx, "SELECT permissions FROM roles WHERE name = $1", role).Scan(&perms)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.roles.put(key, roleCacheEntry{})
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.roles.put(key, roleCacheEntry{permissions: perms, exists: true})
+	return perms, true, nil
+}
+
+// RolePermissions implements middleware.PermissionResolver. A role that no
+// longer exists grants nothing.
+func (s *Server) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	perms, _, err := s.lookupRole(ctx, role)
+	return perms, err
+}
+
+// rolesWithPermission lists the built-in and custom roles granting the
+// permission, for queries that pick users by what they may do
+func (s *Server) rolesWithPermission(ctx context.Context, permission string) ([]string, error) {
+	var roles []string
+	for role, perms := range rbac.BuiltinRoles {
+		if slices.Contains(perms, permission) {
+			roles = append(roles, role)
+		}
+	}
+	rows, err := s.db.Pool.Query(ctx, "SELECT name FROM roles WHERE $1 = ANY(permissions)", permission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		roles = append(roles, name)
+	}
+	return roles, rows.Err()
+}
+
+// assignableRole checks that the caller may give users the role: it has to
+// exist, and carry no permission the caller lacks
+func (s *Server) assignableRole(c *gin.Context, role string) (int, error) {
+	if role == rbac.RoleSuperAdmin {
+		return http.StatusForbidden, errors.New("super admins are created by the operators of the deployment")
+	}
+	perms, ok, err := s.lookupRole(c.Request.Context(), role)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("failed to check the role")
+	}
+	if !ok {
+		return http.StatusBadRequest, fmt.Errorf("unknown role %q", role)
+	}
+	if !rbac.Covers(c.GetStringSlice("permissions"), perms) {
+		return http.StatusForbidden, errors.New("you cannot assign a role with permissions you do not hold")
+	}
+	return 0, nil
+}
+
+// withPrimaryRole lists the primary role first, then the other roles
+// without repeats
+func withPrimaryRole(primary string, others []string) []string {
+	roles := []string{primary}
+	for _, role := range others {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// GetPermissions lists every permission a role can grant
+func (s *Server) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, rbac.Permissions)
+}
+
+// GetRoles lists the built-in roles and the tenant's custom roles
+func (s *Server) GetRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	counts := map[string]int{}
+	rows, err := s.db.Pool.Query(ctx, "SELECT role, COUNT(*) FROM users, unnest(roles) AS role GROUP BY role")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	for rows.Next() {
+		var role string
+		var n int
+		if err := rows.Scan(&role, &n); err == nil {
+			counts[role] = n
+		}
+	}
+	rows.Close()
+
+	roles := []models.Role{}
+	for _, name := range []string{rbac.RoleAuthor, rbac.RoleEditor, rbac.RoleCoordinator, rbac.RoleAdmin, rbac.RoleSuperAdmin} {
+		if name == rbac.RoleSuperAdmin && !middleware.HasPermission(c, rbac.TenantManage) {
+			continue
+		}
+		roles = append(roles, models.Role{
+			Name:        name,
+			Label:       rbac.BuiltinLabels[name],
+			Permissions: rbac.BuiltinRoles[name],
+			BuiltIn:     true,
+			UserCount:   counts[name],
+		})
+	}
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT id, name, label, COALESCE(description, ''), permissions, created_at, updated_at
+		FROM roles
+		ORDER BY label
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.Role
+		if err := rows.Scan(&r.ID, &r.Name, &r.Label, &r.Description, &r.Permissions, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read roles"})
+			return
+		}
+		r.UserCount = counts[r.Name]
+		roles = append(roles, r)
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// checkRoleRequest validates a custom role and that the caller holds every
+// permission it grants
+func checkRoleRequest(c *gin.Context, name string, req *models.RoleRequest) bool {
+	req.Permissions = rbac.Normalize(req.Permissions)
+	if err := rbac.ValidateRole(name, req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if !rbac.Covers(c.GetStringSlice("permissions"), req.Permissions) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant permissions you do not hold"})
+		return false
+	}
+	return true
+}
+
+// CreateRole adds a custom role to the tenant
+func (s *Server) CreateRole(c *gin.Context) {
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if !checkRoleRequest(c, req.Name, &req) {
+		return
+	}
+
+	var r models.Role
+	err := s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO roles (name, label, description, permissions)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id, name, label, COALESCE(description, ''), permissions, created_at, updated_at
+	`, req.Name, strings.TrimSpace(req.Label), req.Description, req.Permissions).Scan(
+		&r.ID, &r.Name, &r.Label, &r.Description, &r.Permissions, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "A role with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	s.roles.clear()
+	s.audit(c, auditRoleCreate, nil, gin.H{"role": r.Name, "permissions": r.Permissions})
+
+	c.JSON(http.StatusCreated, r)
+}
+
+// UpdateRole changes the label, description and permissions of a custom
+// role. Its users get the new permissions within a minute.
+func (s *Server) UpdateRole(c *gin.Context) {
+	name := c.Param("name")
+	if rbac.IsBuiltin(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be changed"})
+		return
+	}
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkRoleRequest(c, name, &req) {
+		return
+	}
+
+	var r models.Role
+	err := s.db.Pool.QueryRow(c.Request.Context(), `
+		UPDATE roles SET label = $2, description = NULLIF($3, ''), permissions = $4, updated_at = NOW()
+		WHERE name = $1
+		RETURNING id, name, label, COALESCE(description, ''), permissions, created_at, updated_at
+	`, name, strings.TrimSpace(req.Label), req.Description, req.Permissions).Scan(
+		&r.ID, &r.Name, &r.Label, &r.Description, &r.Permissions, &r.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	s.roles.clear()
+	s.audit(c, auditRoleUpdate, nil, gin.H{"role": r.Name, "permissions": r.Permissions})
+
+	c.JSON(http.StatusOK, r)
+}
+
+// DeleteRole removes a custom role nobody holds any more
+func (s *Server) DeleteRole(c *gin.Context) {
+	name := c.Param("name")
+	if rbac.IsBuiltin(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var holders int
+	if err := s.db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE $1 = ANY(roles)", name).Scan(&holders); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if holders > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Take this role away from its %d users first", holders)})
+		return
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, "DELETE FROM roles WHERE name = $1", name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	s.roles.clear()
+	s.audit(c, auditRoleDelete, nil, gin.H{"role": name})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// SetUserRole makes the role the only one the user holds
+func (s *Server) SetUserRole(c *gin.Context) {
+	var req models.SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.changeUserRoles(c, []string{req.Role})
+}
+
+// SetUserRoles replaces the roles of a user; the first becomes the primary
+// role
+func (s *Server) SetUserRoles(c *gin.Context) {
+	var req models.SetUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.changeUserRoles(c, withPrimaryRole(req.Roles[0], req.Roles[1:]))
+}
+
+// changeUserRoles gives the user of the :id parameter the roles. The caller
+// has to hold every permission of the old and the new roles, and cannot
+// change their own.
+func (s *Server) changeUserRoles(c *gin.Context, roles []string) {
+	u, ok := s.loadManagedUser(c)
+	if !ok {
+		return
+	}
+	for _, role := range roles {
+		if status, err := s.assignableRole(c, role); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.db.Pool.Exec(ctx,
+		"UPDATE users SET role = $2, roles = $3, updated_at = NOW() WHERE id = $1",
+		u.id, roles[0], roles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change roles"})
+		return
+	}
+	s.audit(c, auditUserRoles, &u.id, gin.H{"from": u.roles, "to": roles})
+	if !u.placeholder {
+		if err := s.auth.UpdateMetadata(ctx, u.id, map[string]interface{}{"role": roles[0], "roles": roles}); err != nil {
+			log.Printf("Failed to update roles with the auth provider: %v", err)
+		}
+	}
+	// Sessions pick up new roles when they refresh; end them so a demotion
+	// does not wait for that
+	if err := s.revokeUserSessions(ctx, u.id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Roles changed, but existing sessions could not be signed out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Roles changed", "role": roles[0], "roles": roles})
+}
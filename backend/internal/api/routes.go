@@ -5,6 +5,7 @@ import (
 	"rpms-backend/internal/config"
 	"rpms-backend/internal/database"
 	"rpms-backend/internal/middleware"
+	"rpms-backend/internal/rbac"
 	"rpms-backend/internal/storage"
 
 	"github.com/gin-gonic/gin"
@@ -34,27 +35,9 @@ func SetupRoutes(router *gin.Engine, db *database.Database, cfg *config.Config)
 		})
 	})
 
-	// DEBUG ENDPOINT - REMOVE IN PRODUCTION
-	router.GET("/debug/users", func(c *gin.Context) {
-		rows, err := db.Pool.Query(c.Request.Context(), "SELECT id, name, email, role FROM users")
-		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-			return
-		}
-		defer rows.Close()
-		var users []map[string]interface{}
-		for rows.Next() {
-			var id, name, email, role string
-			rows.Scan(&id, &name, &email, &role)
-			users = append(users, map[string]interface{}{
-				"id": id, "name": name, "email": email, "role": role,
-			})
-		}
-		c.JSON(200, users)
-	})
-
 	// API v1 routes
 	v1 := router.Group("/api/v1")
+	v1.Use(middleware.TenantMiddleware(server))
 	{
 		// Auth routes (no authentication required)
 		auth := v1.Group("/auth")
@@ -63,63 +46,177 @@ func SetupRoutes(router *gin.Engine, db *database.Database, cfg *config.Config)
 			auth.POST("/login", server.Login)
 			auth.POST("/verify", server.VerifyEmail)
 			auth.POST("/resend-code", server.ResendVerificationCode)
+			auth.POST("/refresh", server.RefreshSession)
+			auth.POST("/logout", server.Logout)
+			auth.POST("/password-reset", server.RequestPasswordReset)
+			auth.POST("/password-reset/confirm", server.ConfirmPasswordReset)
+			auth.POST("/mfa/verify", server.VerifyMFA)
+			auth.GET("/invitations/:token", server.GetInvitationPreview)
+			auth.POST("/invitations/accept", server.AcceptInvitation)
+			auth.GET("/sso/providers", server.GetSSOProviders)
+			auth.POST("/sso/exchange", server.ExchangeSSOCode)
+			auth.GET("/oidc/login", server.StartOIDCLogin)
+			auth.GET("/oidc/callback", server.OIDCCallback)
+			auth.POST("/ldap/login", server.LDAPLogin)
+		}
+
+		// Two-factor enrollment, reachable before the MFA policy is met
+		mfa := v1.Group("/auth/mfa")
+		mfa.Use(middleware.AuthMiddleware(jwtManager, server), middleware.RequireSession(), middleware.RequireActiveAccount(server))
+		{
+			mfa.GET("", server.GetMFAStatus)
+			mfa.POST("/totp/setup", server.SetupTOTP)
+			mfa.POST("/totp/enable", server.EnableTOTP)
+			mfa.POST("/totp/disable", server.DisableTOTP)
+			mfa.POST("/recovery-codes", server.RegenerateRecoveryCodes)
 		}
 
 		// Public routes
 		v1.GET("/events", server.GetEvents)
 		v1.GET("/news", server.GetNews)
 
+		// Public repository of published papers
+		repository := v1.Group("/repository")
+		{
+			repository.GET("/papers", server.GetRepositoryPapers)
+			repository.GET("/papers/:id", server.GetRepositoryPaper)
+			repository.GET("/papers/:id/citation", server.GetPaperCitation)
+			repository.GET("/papers/:id/retraction-notice", server.GetRetractionNotice)
+			repository.GET("/export", server.ExportRepository)
+		}
+		v1.GET("/researchers/:id/profile", server.GetResearcherProfile)
+		v1.GET("/verify/:serial", server.VerifyDocument)
+
 		// Protected routes (authentication required)
 		protected := v1.Group("/")
-		protected.Use(middleware.AuthMiddleware(jwtManager))
+		protected.Use(
+			middleware.AuthMiddleware(jwtManager, server),
+			middleware.RequireActiveAccount(server),
+			middleware.RequireMFA(cfg.GetMFARequiredRoles()),
+			middleware.LoadPermissions(server),
+		)
 		{
 			// User routes
 			protected.GET("/profile", server.GetProfile)
-			protected.PUT("/profile", server.UpdateProfile)
-			protected.PUT("/auth/password", server.ChangePassword)
-			protected.DELETE("/auth/account", server.DeleteAccount)
+			protected.PUT("/profile", middleware.RequireSession(), server.UpdateProfile)
+			protected.PUT("/auth/password", middleware.RequireSession(), server.ChangePassword)
+			protected.DELETE("/auth/account", middleware.RequireSession(), server.DeleteAccount)
 			protected.GET("/notifications", server.GetNotifications)
 			protected.PUT("/notifications/:id/read", server.MarkNotificationRead)
-			protected.POST("/notifications", server.CreateNotification)
+			protected.POST("/notifications", middleware.RequireSession(), server.CreateNotification)
 			protected.GET("/users/admin", server.GetAdminUsers)
+			protected.GET("/researchers/:id/dossier", server.GetResearcherDossier)
+
+			// Personal API tokens
+			tokens := protected.Group("/tokens")
+			tokens.Use(middleware.RequireSession())
+			{
+				tokens.GET("", server.GetAPITokens)
+				tokens.POST("", server.CreateAPIToken)
+				tokens.DELETE("/:tokenId", server.RevokeAPIToken)
+			}
 
 			papers := protected.Group("/papers")
 			{
 				papers.GET("", server.GetPapers)
-				papers.POST("", middleware.AuthorOrAdmin(), server.CreatePaper)
-				papers.PUT("/:id", middleware.AuthorOrAdmin(), server.UpdatePaper)
-				papers.DELETE("/:id", middleware.AuthorOrAdmin(), server.DeletePaper)
-				papers.POST("/:id/recommend", middleware.EditorOrAdmin(), server.RecommendPaperForPublication)
-				papers.PUT("/:id/details", middleware.EditorOrCoordinatorOrAdmin(), server.UpdatePaperDetails)
+				papers.POST("", middleware.RequirePermission(rbac.PaperSubmit), server.CreatePaper)
+				papers.PUT("/:id", middleware.RequirePermission(rbac.PaperSubmit), server.UpdatePaper)
+				papers.DELETE("/:id", middleware.RequirePermission(rbac.PaperSubmit), server.DeletePaper)
+				papers.POST("/:id/recommend", middleware.RequirePermission(rbac.PaperReview), server.RecommendPaperForPublication)
+				papers.PUT("/:id/details", middleware.RequirePermission(rbac.PaperEditDetails), server.UpdatePaperDetails)
+				papers.PUT("/:id/schedule", middleware.RequirePermission(rbac.PaperPublish), server.SchedulePaper)
+				papers.POST("/:id/withdraw", middleware.RequirePermission(rbac.PaperSubmit), server.WithdrawPaper)
+				papers.PUT("/:id/org-unit", middleware.RequirePermission(rbac.PaperEditDetails), server.SetPaperOrgUnit)
+				papers.GET("/:id/approvals", server.GetPaperApprovals)
+				papers.POST("/:id/appeals", middleware.RequirePermission(rbac.PaperSubmit), server.FileAppeal)
+				papers.GET("/:id/appeals", server.GetPaperAppeals)
+				papers.GET("/:id/coauthors", server.GetCoAuthors)
+				papers.PUT("/:id/coauthors", middleware.RequirePermission(rbac.PaperSubmit), server.SetCoAuthors)
+				papers.POST("/import/orcid", middleware.RequirePermission(rbac.PaperSubmit), server.ImportORCIDWorks)
+				papers.POST("/:id/documents", middleware.RequirePermission(rbac.DocumentGenerate), server.GenerateDocument)
+				papers.GET("/:id/documents", server.GetPaperDocuments)
+				papers.GET("/:id/documents/:docId/download", server.DownloadPaperDocument)
 			}
 
 			// Review routes
 			reviews := protected.Group("/reviews")
 			{
 				reviews.GET("", server.GetReviews)
-				reviews.POST("", middleware.EditorOrAdmin(), server.CreateReview)
+				reviews.POST("", middleware.RequirePermission(rbac.PaperReview), server.CreateReview)
+			}
+
+			// Report routes
+			reports := protected.Group("/reports")
+			{
+				reports.GET("/fiscal-years", middleware.RequirePermission(rbac.ReportView), server.GetFiscalYearReport)
+				reports.GET("/org-units", middleware.RequirePermission(rbac.ReportView), server.GetOrgUnitReport)
+			}
+
+			// Organizational hierarchy
+			orgUnits := protected.Group("/org-units")
+			{
+				orgUnits.GET("", server.GetOrgUnits)
+				orgUnits.POST("", middleware.RequirePermission(rbac.OrgUnitManage), server.CreateOrgUnit)
+				orgUnits.PUT("/:id", middleware.RequirePermission(rbac.OrgUnitManage), server.UpdateOrgUnit)
+				orgUnits.DELETE("/:id", middleware.RequirePermission(rbac.OrgUnitManage), server.DeleteOrgUnit)
+			}
+
+			// Approval chains and the approvers' queue
+			approvalChains := protected.Group("/approval-chains")
+			{
+				approvalChains.GET("", server.GetApprovalChains)
+				approvalChains.PUT("", middleware.RequirePermission(rbac.ApprovalChainManage), server.SaveApprovalChain)
+				approvalChains.DELETE("/:id", middleware.RequirePermission(rbac.ApprovalChainManage), server.DeleteApprovalChain)
+			}
+			protected.GET("/approvals/queue", server.GetApprovalQueue)
+			protected.POST("/approvals/:id/decision", middleware.RequireSession(), server.DecideApproval)
+
+			// Appeal routes
+			appeals := protected.Group("/appeals")
+			{
+				appeals.GET("", server.GetAppeals)
+				appeals.PUT("/:id/panel", middleware.RequirePermission(rbac.AppealManage), server.AssignAppealPanel)
+				appeals.POST("/:id/decision", middleware.RequireSession(), middleware.RequirePermission(rbac.AppealDecide), server.DecideAppeal)
+			}
+
+			// Journal routes
+			journals := protected.Group("/journals")
+			{
+				journals.GET("", server.GetJournals)
+				journals.GET("/:id", server.GetJournal)
+				journals.POST("", middleware.RequirePermission(rbac.JournalManage), server.CreateJournal)
+				journals.PUT("/:id", middleware.RequirePermission(rbac.JournalManage), server.UpdateJournal)
+				journals.POST("/:id/volumes", middleware.RequirePermission(rbac.JournalManage), server.CreateVolume)
+			}
+			protected.POST("/volumes/:id/issues", middleware.RequirePermission(rbac.JournalManage), server.CreateIssue)
+			issues := protected.Group("/issues")
+			{
+				issues.GET("/:id", server.GetIssue)
+				issues.PUT("/:id/papers", middleware.RequirePermission(rbac.JournalManage), server.SetIssueContents)
+				issues.POST("/:id/publish", middleware.RequirePermission(rbac.PaperPublish), server.PublishIssue)
 			}
 
 			// Event routes
 			events := protected.Group("/events")
 			{
-				events.POST("", middleware.CoordinatorOrAdmin(), server.CreateEvent)
-				events.PUT("/:id", middleware.CoordinatorOrAdmin(), server.UpdateEvent)
-				events.PUT("/:id/publish", middleware.CoordinatorOrAdmin(), server.PublishEvent)
-				events.DELETE("/:id", middleware.CoordinatorOrAdmin(), server.DeleteEvent)
+				events.POST("", middleware.RequirePermission(rbac.EventPublish), server.CreateEvent)
+				events.PUT("/:id", middleware.RequirePermission(rbac.EventPublish), server.UpdateEvent)
+				events.PUT("/:id/publish", middleware.RequirePermission(rbac.EventPublish), server.PublishEvent)
+				events.DELETE("/:id", middleware.RequirePermission(rbac.EventPublish), server.DeleteEvent)
 			}
 
 			// News routes
 			news := protected.Group("/news")
 			{
-				news.POST("", middleware.CoordinatorOrAdmin(), server.CreateNews)
-				news.PUT("/:id", middleware.CoordinatorOrAdmin(), server.UpdateNews)
-				news.PUT("/:id/publish", middleware.CoordinatorOrAdmin(), server.PublishNews)
-				news.DELETE("/:id", middleware.CoordinatorOrAdmin(), server.DeleteNews)
+				news.POST("", middleware.RequirePermission(rbac.NewsPublish), server.CreateNews)
+				news.PUT("/:id", middleware.RequirePermission(rbac.NewsPublish), server.UpdateNews)
+				news.PUT("/:id/publish", middleware.RequirePermission(rbac.NewsPublish), server.PublishNews)
+				news.DELETE("/:id", middleware.RequirePermission(rbac.NewsPublish), server.DeleteNews)
 			}
 
 			// Chat routes
 			chat := protected.Group("/chat")
+			chat.Use(middleware.RequireSession())
 			{
 				chat.POST("/upload", uploadHandler.UploadFile)
 				chat.POST("/send", chatHandler.SendMessage)
@@ -130,6 +227,7 @@ func SetupRoutes(router *gin.Engine, db *database.Database, cfg *config.Config)
 
 			// Interaction routes (likes, comments, shares)
 			interactions := protected.Group("/interactions")
+			interactions.Use(middleware.RequireSession())
 			{
 				interactions.POST("/like", server.LikePost)
 				interactions.GET("/likes/:postType/:postId", server.GetPostLikes)
@@ -139,16 +237,57 @@ func SetupRoutes(router *gin.Engine, db *database.Database, cfg *config.Config)
 				interactions.GET("/stats/:postType/:postId", server.GetEngagementStats)
 			}
 
-			// Admin only routes
+			// Administration
 			admin := protected.Group("/admin")
-			admin.Use(middleware.AdminOnly())
 			{
-				admin.GET("/stats", func(c *gin.Context) {
+				admin.GET("/stats", middleware.RequirePermission(rbac.UserManage), func(c *gin.Context) {
 					// TODO: Implement admin statistics
 					c.JSON(200, gin.H{"message": "Admin statistics endpoint"})
 				})
-				admin.POST("/users", server.AdminCreateUser)
-				admin.GET("/staff", server.GetAdminStaff)
+				admin.GET("/users", middleware.RequirePermission(rbac.UserManage), server.SearchUsers)
+				admin.GET("/users/:id", middleware.RequirePermission(rbac.UserManage), server.GetAdminUser)
+				admin.PUT("/users/:id/role", middleware.RequirePermission(rbac.UserManage), server.SetUserRole)
+				admin.PUT("/users/:id/roles", middleware.RequirePermission(rbac.UserManage), server.SetUserRoles)
+				admin.PUT("/users/:id/org-unit", middleware.RequirePermission(rbac.UserManage), server.SetUserOrgUnit)
+				admin.POST("/users/:id/unlock", middleware.RequirePermission(rbac.UserManage), server.UnlockUser)
+				admin.POST("/users/:id/deactivate", middleware.RequirePermission(rbac.UserManage), server.DeactivateUser)
+				admin.POST("/users/:id/reactivate", middleware.RequirePermission(rbac.UserManage), server.ReactivateUser)
+				admin.POST("/users/:id/reset-password", middleware.RequirePermission(rbac.UserManage), server.ForcePasswordReset)
+				admin.GET("/invitations", middleware.RequirePermission(rbac.UserManage), server.GetInvitations)
+				admin.POST("/invitations", middleware.RequirePermission(rbac.UserManage), server.CreateInvitation)
+				admin.DELETE("/invitations/:id", middleware.RequirePermission(rbac.UserManage), server.RevokeInvitation)
+				admin.POST("/invitations/:id/resend", middleware.RequirePermission(rbac.UserManage), server.ResendInvitation)
+				admin.GET("/users/:id/tokens", middleware.RequirePermission(rbac.UserManage), server.GetUserAPITokens)
+				admin.DELETE("/users/:id/tokens/:tokenId", middleware.RequirePermission(rbac.UserManage), server.RevokeUserAPIToken)
+				admin.GET("/service-accounts", middleware.RequirePermission(rbac.UserManage), server.GetServiceAccounts)
+				admin.POST("/service-accounts", middleware.RequireSession(), middleware.RequirePermission(rbac.UserManage), server.CreateServiceAccount)
+				admin.POST("/service-accounts/:id/tokens", middleware.RequireSession(), middleware.RequirePermission(rbac.UserManage), server.CreateServiceAccountToken)
+				admin.GET("/audit-log", middleware.RequirePermission(rbac.UserManage), server.GetAuditLog)
+				admin.GET("/staff", middleware.RequirePermission(rbac.UserManage), server.GetAdminStaff)
+				admin.POST("/import/papers", middleware.RequirePermission(rbac.PaperImport), server.ImportPapers)
+				admin.POST("/import/users", middleware.RequirePermission(rbac.UserManage), server.ImportUsers)
+				admin.POST("/documents/:id/revoke", middleware.RequirePermission(rbac.DocumentRevoke), server.RevokeDocument)
+				admin.POST("/papers/:id/retract", middleware.RequirePermission(rbac.PaperRetract), server.RetractPaper)
+			}
+
+			// Roles and the permissions they grant
+			protected.GET("/permissions", middleware.RequirePermission(rbac.RoleManage, rbac.UserManage), server.GetPermissions)
+			roles := protected.Group("/roles")
+			{
+				roles.GET("", middleware.RequirePermission(rbac.RoleManage, rbac.UserManage), server.GetRoles)
+				roles.POST("", middleware.RequirePermission(rbac.RoleManage), server.CreateRole)
+				roles.PUT("/:name", middleware.RequirePermission(rbac.RoleManage), server.UpdateRole)
+				roles.DELETE("/:name", middleware.RequirePermission(rbac.RoleManage), server.DeleteRole)
+			}
+
+			// Tenant management for super admins
+			super := protected.Group("/super")
+			super.Use(middleware.RequirePermission(rbac.TenantManage))
+			{
+				super.GET("/tenants", server.GetTenants)
+				super.POST("/tenants", server.CreateTenant)
+				super.PUT("/tenants/:id", server.UpdateTenant)
+				super.POST("/tenants/:id/admins", server.CreateTenantAdmin)
 			}
 		}
 	}
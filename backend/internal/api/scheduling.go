@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SchedulePaper sets when an approved paper becomes public and until when its
+// full text stays under embargo. Null values clear the schedule or embargo.
+// The scheduler publishes the paper once the time has come.
+func (s *Server) SchedulePaper(c *gin.Context) {
+	paperID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper ID"})
+		return
+	}
+
+	var req models.SchedulePaperRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var status string
+	if err := s.db.Pool.QueryRow(ctx, "SELECT status FROM papers WHERE id = $1", paperID).Scan(&status); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return
+	}
+
+	if req.PublishAt != nil {
+		if status != "approved" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only approved papers can be scheduled for publication"})
+			return
+		}
+		if !req.PublishAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+			return
+		}
+	}
+	if req.EmbargoUntil != nil && req.PublishAt != nil && !req.EmbargoUntil.After(*req.PublishAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "embargo_until must be after publish_at"})
+		return
+	}
+
+	var paper models.Paper
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE papers
+		SET scheduled_publish_at = $1, embargo_until = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING id, title, status, scheduled_publish_at, embargo_until, updated_at
+	`, req.PublishAt, req.EmbargoUntil, paperID).Scan(
+		&paper.ID, &paper.Title, &paper.Status, &paper.ScheduledPublishAt, &paper.EmbargoUntil, &paper.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule paper"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paper)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// storeRefreshToken adds a refresh token to a family and returns it
+func storeRefreshToken(ctx context.Context, db execer, userID, familyID uuid.UUID, expiresAt time.Time, mfa bool) (string, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, mfa)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, familyID, hash, expiresAt, mfa)
+	return token, err
+}
+
+func (s *Server) sessionResponse(user *models.User, refreshToken string, mfa bool) (*models.LoginResponse, error) {
+	token, err := s.jwtManager.GenerateToken(user, mfa)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResponse{
+		User:                  *user,
+		Token:                 token,
+		RefreshToken:          refreshToken,
+		ExpiresIn:             int(s.jwtManager.Expiry().Seconds()),
+		MFAEnrollmentRequired: !mfa && s.mfaRequired(user.AllRoles()),
+	}, nil
+}
+
+// issueSession signs the user in: an access token plus the first refresh
+// token of a new family, which can be refreshed until the refresh expiry.
+// mfa records whether a second factor was checked.
+func (s *Server) issueSession(ctx context.Context, user *models.User, mfa bool) (*models.LoginResponse, error) {
+	refreshToken, err := storeRefreshToken(ctx, s.db.Pool, user.ID, uuid.New(), time.Now().Add(s.jwtManager.RefreshExpiry()), mfa)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Pool.Exec(ctx, "UPDATE users SET last_login_at = NOW() WHERE id = $1", user.ID); err != nil {
+		return nil, err
+	}
+	return s.sessionResponse(user, refreshToken, mfa)
+}
+
+// revokeUserSessions revokes every refresh token of the user, signing them
+// out everywhere once their access tokens expire
+func (s *Server) revokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.Pool.Exec(ctx,
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+		userID)
+	return err
+}
+
+// RefreshSession trades a refresh token for a new access token and a new
+// refresh token. Presenting a token that was already traded means it leaked,
+// so its whole family is revoked.
+func (s *Server) RefreshSession(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var tokenID, familyID, userID uuid.UUID
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	var mfa bool
+	err = tx.QueryRow(ctx, `
+		SELECT id, family_id, user_id, expires_at, used_at, revoked_at, mfa
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, auth.HashToken(req.RefreshToken)).Scan(&tokenID, &familyID, &userID, &expiresAt, &usedAt, &revokedAt, &mfa)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	if usedAt != nil && revokedAt == nil {
+		// A rotated token came back, so it was copied: end the whole session
+		if _, err := tx.Exec(ctx,
+			"UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL",
+			familyID); err == nil {
+			tx.Commit(ctx)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; please sign in again"})
+		return
+	}
+	if revokedAt != nil || time.Now().After(expiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token is no longer valid; please sign in again"})
+		return
+	}
+
+	// Claims are rebuilt from the current account, so role changes apply
+	var user models.User
+	var deactivated bool
+	err = tx.QueryRow(ctx, `
+		SELECT id, email, name, role, roles, avatar, bio, preferences, created_at, updated_at, tenant_id,
+			deactivated_at IS NOT NULL
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Roles, &user.Avatar, &user.Bio, &user.Preferences,
+		&user.CreatedAt, &user.UpdatedAt, &user.TenantID, &deactivated,
+	)
+	if errors.Is(err, pgx.ErrNoRows) || deactivated {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token is no longer valid; please sign in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	refreshToken, err := storeRefreshToken(ctx, tx, user.ID, familyID, expiresAt, mfa)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	response, err := s.sessionResponse(&user, refreshToken, mfa)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout revokes the session the refresh token belongs to. The access token
+// stays valid until it expires, which is why it is short-lived.
+func (s *Server) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err := s.db.Pool.Exec(c.Request.Context(), `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+		  AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+	`, auth.HashToken(req.RefreshToken))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out"})
+}
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
+	"rpms-backend/internal/sso"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Cookie keeping the signed state of an OpenID Connect sign-in while the
+// browser is at the provider
+const (
+	oidcStateCookie     = "rpms_oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+// How long the code an OpenID Connect sign-in hands the frontend stays valid
+const ssoCodeTTL = time.Minute
+
+var (
+	errSSONoEmail        = errors.New("your directory account has no email address; ask the IT office to add one")
+	errSSOEmailElsewhere = errors.New("an account with this email address belongs to another institution")
+)
+
+// GetSSOProviders tells the sign-in page which single sign-on options to show
+func (s *Server) GetSSOProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SSOProviders{OIDC: s.oidc != nil, LDAP: s.ldap != nil})
+}
+
+// StartOIDCLogin sends the browser to the identity provider. The state,
+// nonce and PKCE verifier wait in a signed cookie for the callback.
+func (s *Server) StartOIDCLogin(c *gin.Context) {
+	if s.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	state := auth.OIDCState{TenantID: currentTenant(c).ID.String()}
+	for _, v := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		var err error
+		if *v, err = sso.NewVerifier(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+	}
+	cookie, err := s.jwtManager.GenerateOIDCState(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	authURL, err := s.oidc.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		log.Printf("Failed to reach the identity provider: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The identity provider is unavailable"})
+		return
+	}
+
+	// Lax, so the cookie comes back with the provider's redirect
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, int(s.jwtManager.OIDCStateExpiry().Seconds()), oidcStateCookiePath, "", s.ssoSecureCookie(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback finishes a sign-in at the identity provider. The browser is
+// sent on to the frontend with a one-time code, or an error, so no session
+// token ever appears in a URL.
+func (s *Server) OIDCCallback(c *gin.Context) {
+	if s.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", s.ssoSecureCookie(), true)
+
+	if c.Query("error") != "" {
+		s.ssoRedirect(c, "error", "Sign-in was cancelled or refused by the identity provider")
+		return
+	}
+	state, err := s.jwtManager.ValidateOIDCState(cookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(c.Query("state"))) != 1 ||
+		state.TenantID != currentTenant(c).ID.String() {
+		s.ssoRedirect(c, "error", "The sign-in expired or was started elsewhere; please try again")
+		return
+	}
+
+	ctx := c.Request.Context()
+	identity, err := s.oidc.Exchange(ctx, c.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, sso.ErrEmailNotVerified):
+			s.ssoRedirect(c, "error", "The identity provider has not verified your email address")
+		case errors.Is(err, sso.ErrInvalidCredentials):
+			s.ssoRedirect(c, "error", "The sign-in expired or was started elsewhere; please try again")
+		default:
+			log.Printf("OpenID Connect sign-in failed: %v", err)
+			s.ssoRedirect(c, "error", "The identity provider is unavailable")
+		}
+		return
+	}
+
+	userID, err := s.provisionSSOUser(c, identity)
+	if err != nil {
+		if errors.Is(err, errSSONoEmail) || errors.Is(err, errSSOEmailElsewhere) {
+			s.ssoRedirect(c, "error", err.Error())
+			return
+		}
+		log.Printf("Failed to provision single sign-on user: %v", err)
+		s.ssoRedirect(c, "error", "Failed to sign in")
+		return
+	}
+
+	code, hash, err := auth.NewToken()
+	if err == nil {
+		_, err = s.db.Pool.Exec(ctx, "DELETE FROM sso_login_codes WHERE expires_at < NOW()")
+	}
+	if err == nil {
+		_, err = s.db.Pool.Exec(ctx,
+			"INSERT INTO sso_login_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+			hash, userID, time.Now().Add(ssoCodeTTL))
+	}
+	if err != nil {
+		log.Printf("Failed to store single sign-on code: %v", err)
+		s.ssoRedirect(c, "error", "Failed to sign in")
+		return
+	}
+	s.ssoRedirect(c, "code", code)
+}
+
+// ExchangeSSOCode trades the one-time code of an OpenID Connect sign-in for
+// a session, or the second factor challenge
+func (s *Server) ExchangeSSOCode(c *gin.Context) {
+	var req models.SSOExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var userID uuid.UUID
+	err := s.db.Pool.QueryRow(ctx,
+		"DELETE FROM sso_login_codes WHERE code_hash = $1 AND expires_at > NOW() RETURNING user_id",
+		auth.HashToken(req.Code)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired sign-in code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	s.signInSSOUser(c, userID)
+}
+
+// LDAPLogin signs in with a directory account. Failures count towards the
+// same lockout as password sign-ins.
+func (s *Server) LDAPLogin(c *gin.Context) {
+	if s.ldap == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Directory sign-in is not configured"})
+		return
+	}
+	var req models.LDAPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accountKey := accountLockoutKey(currentTenant(c).ID, "ldap:"+req.Username)
+	if !s.loginAllowed(c, accountKey) {
+		return
+	}
+	identity, err := s.ldap.Authenticate(req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, sso.ErrInvalidCredentials) {
+			s.recordLoginFailure(c, accountKey, req.Username)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		log.Printf("Directory sign-in failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The directory is unavailable"})
+		return
+	}
+	if err := s.accountLockout.Reset(c.Request.Context(), accountKey); err != nil {
+		log.Printf("Failed to reset sign-in failures: %v", err)
+	}
+
+	userID, err := s.provisionSSOUser(c, identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSSONoEmail):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, errSSOEmailElsewhere):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to provision single sign-on user: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		}
+		return
+	}
+
+	s.signInSSOUser(c, userID)
+}
+
+func (s *Server) signInSSOUser(c *gin.Context, userID uuid.UUID) {
+	var user models.User
+	var totpEnabled, deactivated bool
+	err := scanSignInUser(s.db.Pool.QueryRow(c.Request.Context(), signInUserQuery+" WHERE id = $1", userID), &user, &totpEnabled, &deactivated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	s.completeSignIn(c, &user, totpEnabled, deactivated)
+}
+
+// provisionSSOUser returns the user a directory identity belongs to. An
+// identity seen before keeps its user; otherwise it is linked to the
+// tenant's account with its email, completing a placeholder, or a new
+// account is created with the roles its groups map to.
+func (s *Server) provisionSSOUser(c *gin.Context, identity *sso.Identity) (uuid.UUID, error) {
+	if identity.Email == "" {
+		return uuid.Nil, errSSONoEmail
+	}
+	ctx := c.Request.Context()
+	roles, mapped, err := s.ssoRoles(c, identity.Groups)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID uuid.UUID
+	var oldRoles []string
+	var placeholder, created bool
+	action := ""
+	err = tx.QueryRow(ctx, `
+		SELECT u.id, u.roles
+		FROM sso_identities i JOIN users u ON i.user_id = u.id
+		WHERE i.provider = $1 AND i.subject = $2
+		FOR UPDATE OF i
+	`, identity.Provider, identity.Subject).Scan(&userID, &oldRoles)
+	switch {
+	case err == nil:
+		if _, err := tx.Exec(ctx,
+			"UPDATE sso_identities SET last_login_at = NOW() WHERE provider = $1 AND subject = $2",
+			identity.Provider, identity.Subject); err != nil {
+			return uuid.Nil, err
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		err = tx.QueryRow(ctx,
+			"SELECT id, roles, COALESCE(is_placeholder, FALSE) FROM users WHERE LOWER(email) = $1 FOR UPDATE",
+			identity.Email).Scan(&userID, &oldRoles, &placeholder)
+		switch {
+		case err == nil:
+			action = auditUserSSOLink
+			if placeholder {
+				// A placeholder keeps the roles it was imported with unless
+				// the directory says otherwise
+				if !mapped {
+					roles = oldRoles
+				}
+				if _, err := tx.Exec(ctx, `
+					UPDATE users
+					SET name = COALESCE(NULLIF(name, ''), $2), role = $3, roles = $4,
+						is_verified = TRUE, is_placeholder = FALSE, updated_at = NOW()
+					WHERE id = $1
+				`, userID, ssoName(identity), roles[0], roles); err != nil {
+					return uuid.Nil, err
+				}
+			}
+		case errors.Is(err, pgx.ErrNoRows):
+			// Emails are unique across tenants
+			var taken bool
+			if err := s.db.Pool.QueryRow(database.WithoutTenant(ctx),
+				"SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = $1)", identity.Email).Scan(&taken); err != nil {
+				return uuid.Nil, err
+			}
+			if taken {
+				return uuid.Nil, errSSOEmailElsewhere
+			}
+			action = auditUserSSOProvision
+			created = true
+			err = tx.QueryRow(ctx, `
+				INSERT INTO users (email, password_hash, name, role, roles, preferences, is_verified)
+				VALUES ($1, '', $2, $3, $4, '{}', TRUE)
+				RETURNING id
+			`, identity.Email, ssoName(identity), roles[0], roles).Scan(&userID)
+			if err != nil {
+				return uuid.Nil, err
+			}
+		default:
+			return uuid.Nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO sso_identities (provider, subject, user_id) VALUES ($1, $2, $3)",
+			identity.Provider, identity.Subject, userID); err != nil {
+			return uuid.Nil, err
+		}
+	default:
+		return uuid.Nil, err
+	}
+
+	synced := false
+	if !placeholder && !created && syncSSORoles(s.config.SSO.SyncRoles, mapped, oldRoles, roles) {
+		if _, err := tx.Exec(ctx,
+			"UPDATE users SET role = $2, roles = $3, updated_at = NOW() WHERE id = $1",
+			userID, roles[0], roles); err != nil {
+			return uuid.Nil, err
+		}
+		synced = true
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
+
+	if action != "" {
+		s.audit(c, action, &userID, gin.H{"provider": identity.Provider, "subject": identity.Subject})
+	}
+	if synced {
+		s.audit(c, auditUserRoles, &userID, gin.H{"from": oldRoles, "to": roles, "provider": identity.Provider})
+	}
+	return userID, nil
+}
+
+// syncSSORoles reports whether an existing user's roles are replaced by the
+// ones their groups map to, when the directory is in charge of roles. Groups
+// that map to nothing leave the roles alone rather than handing out the
+// default role, and super admins are never touched.
+func syncSSORoles(sync, mapped bool, oldRoles, roles []string) bool {
+	return sync && mapped && !slices.Equal(oldRoles, roles) && !slices.Contains(oldRoles, rbac.RoleSuperAdmin)
+}
+
+// ssoRoles returns the roles the groups map to, skipping roles the tenant
+// does not have, or the default role when none is left. Directory groups
+// never make super admins.
+func (s *Server) ssoRoles(c *gin.Context, groups []string) (roles []string, mapped bool, err error) {
+	for _, role := range s.groupRoles.Roles(groups) {
+		if role == rbac.RoleSuperAdmin {
+			continue
+		}
+		_, ok, err := s.lookupRole(c.Request.Context(), role)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			log.Printf("SSO_GROUP_ROLES maps to unknown role %q; skipping it", role)
+			continue
+		}
+		roles = append(roles, role)
+	}
+	if len(roles) == 0 {
+		return []string{s.config.SSO.DefaultRole}, false, nil
+	}
+	return roles, true, nil
+}
+
+// ssoName is the display name of an identity, or its email's local part
+func ssoName(identity *sso.Identity) string {
+	if identity.Name != "" {
+		return identity.Name
+	}
+	name, _, _ := strings.Cut(identity.Email, "@")
+	return name
+}
+
+// ssoRedirect sends the browser to the frontend's single sign-on page with
+// a code or an error
+func (s *Server) ssoRedirect(c *gin.Context, key, value string) {
+	c.Redirect(http.StatusFound, s.config.SSO.FrontendCallbackURL+"?"+url.Values{key: {value}}.Encode())
+}
+
+// ssoSecureCookie reports whether the callback is served over HTTPS, where
+// the state cookie must not travel in clear text
+func (s *Server) ssoSecureCookie() bool {
+	return strings.HasPrefix(s.config.SSO.OIDCRedirectURL, "https://")
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+
+	"rpms-backend/internal/rbac"
+)
+
+func TestSyncSSORoles(t *testing.T) {
+	admin := []string{rbac.RoleAdmin}
+	editor := []string{rbac.RoleEditor}
+	author := []string{rbac.RoleAuthor}
+	cases := []struct {
+		name     string
+		sync     bool
+		mapped   bool
+		oldRoles []string
+		roles    []string
+		want     bool
+	}{
+		{"unmapped returning admin", true, false, admin, author, false},
+		{"unmapped returning editor", true, false, editor, author, false},
+		{"groups changed", true, true, admin, editor, true},
+		{"groups unchanged", true, true, editor, editor, false},
+		{"sync off", false, true, admin, editor, false},
+		{"super admin", true, true, []string{rbac.RoleSuperAdmin}, author, false},
+	}
+	for _, c := range cases {
+		if got := syncSSORoles(c.sync, c.mapped, c.oldRoles, c.roles); got != c.want {
+			t.Errorf("%s: synced %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"rpms-backend/internal/auth"
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/email"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const tenantQuery = `
+	SELECT id, slug, name, COALESCE(domain, ''), publication_id_prefix,
+		COALESCE(university_name, ''), COALESCE(university_name_amharic, ''),
+		COALESCE(office_name, ''), COALESCE(office_name_amharic, ''),
+		COALESCE(address, ''), COALESCE(logo_path, ''),
+		COALESCE(email_sender_name, ''), COALESCE(email_sender_address, ''),
+		active, created_at, updated_at
+	FROM tenants
+`
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Resolved tenants are kept for a minute so every request does not hit the
+// tenants table; changes made through the API clear the cache at once
+const tenantCacheTTL = time.Minute
+
+type tenantCache struct {
+	mu      sync.Mutex
+	entries map[string]tenantCacheEntry
+}
+
+type tenantCacheEntry struct {
+	tenant  *models.Tenant
+	expires time.Time
+}
+
+func (tc *tenantCache) get(key string) (*models.Tenant, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	e, ok := tc.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.tenant, true
+}
+
+func (tc *tenantCache) put(key string, t *models.Tenant) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.entries == nil {
+		tc.entries = map[string]tenantCacheEntry{}
+	}
+	tc.entries[key] = tenantCacheEntry{tenant: t, expires: time.Now().Add(tenantCacheTTL)}
+}
+
+func (tc *tenantCache) clear() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.entries = nil
+}
+
+func scanTenant(row pgx.Row, t *models.Tenant) error {
+	return row.Scan(
+		&t.ID, &t.Slug, &t.Name, &t.Domain, &t.PublicationIDPrefix,
+		&t.UniversityName, &t.UniversityNameAmharic,
+		&t.OfficeName, &t.OfficeNameAmharic,
+		&t.Address, &t.LogoPath,
+		&t.EmailSenderName, &t.EmailSenderAddress,
+		&t.Active, &t.CreatedAt, &t.UpdatedAt,
+	)
+}
+
+// ResolveTenant finds the tenant by slug when one is given, otherwise by the
+// host's domain, falling back to the default tenant
+func (s *Server) ResolveTenant(ctx context.Context, slug, host string) (*models.Tenant, error) {
+	key := slug + "|" + host
+	if t, ok := s.tenants.get(key); ok {
+		return t, nil
+	}
+
+	var t models.Tenant
+	var err error
+	if slug != "" {
+		err = scanTenant(s.db.Pool.QueryRow(ctx, tenantQuery+" WHERE slug = $1", slug), &t)
+	} else {
+		err = scanTenant(s.db.Pool.QueryRow(ctx, tenantQuery+`
+			WHERE domain = $1 OR slug = 'default'
+			ORDER BY slug = 'default'
+			LIMIT 1
+		`, host), &t)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.tenants.put(key, &t)
+	return &t, nil
+}
+
+// currentTenant returns the tenant the request is addressed to
+func currentTenant(c *gin.Context) *models.Tenant {
+	if t, ok := c.Get("tenant"); ok {
+		if tenant, ok := t.(*models.Tenant); ok {
+			return tenant
+		}
+	}
+	return &models.Tenant{ID: uuid.MustParse(database.DefaultTenantID), PublicationIDPrefix: "SMU_P", Active: true}
+}
+
+// documentBranding is the deployment's document settings with the tenant's
+// branding laid over them
+func (s *Server) documentBranding(c *gin.Context) config.DocumentsConfig {
+	b := s.config.Documents
+	t := currentTenant(c)
+	override := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	override(&b.UniversityName, t.UniversityName)
+	override(&b.UniversityNameAmharic, t.UniversityNameAmharic)
+	override(&b.OfficeName, t.OfficeName)
+	override(&b.OfficeNameAmharic, t.OfficeNameAmharic)
+	override(&b.Address, t.Address)
+	override(&b.LogoPath, t.LogoPath)
+	return b
+}
+
+// mailContext returns the request context, asking mail sent on its behalf
+// to be signed as the tenant
+func mailContext(c *gin.Context) context.Context {
+	t := currentTenant(c)
+	return email.WithSender(c.Request.Context(), email.Sender{Name: t.EmailSenderName, Address: t.EmailSenderAddress})
+}
+
+// GetTenants lists every tenant
+func (s *Server) GetTenants(c *gin.Context) {
+	rows, err := s.db.Pool.Query(c.Request.Context(), tenantQuery+" ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenants"})
+		return
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var t models.Tenant
+		if err := scanTenant(rows, &t); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read tenants"})
+			return
+		}
+		tenants = append(tenants, t)
+	}
+	c.JSON(http.StatusOK, tenants)
+}
+
+func normalizeTenantRequest(req *models.TenantRequest) error {
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Domain = strings.ToLower(strings.TrimSpace(req.Domain))
+	if !tenantSlugPattern.MatchString(req.Slug) {
+		return errors.New("slug may only contain lowercase letters, digits and single hyphens")
+	}
+	return nil
+}
+
+// CreateTenant adds a tenant
+func (s *Server) CreateTenant(c *gin.Context) {
+	var req models.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeTenantRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	active := req.Active == nil || *req.Active
+
+	var id uuid.UUID
+	err := s.db.Pool.QueryRow(c.Request.Context(), `
+		INSERT INTO tenants (slug, name, domain, publication_id_prefix, university_name, university_name_amharic,
+			office_name, office_name_amharic, address, logo_path, email_sender_name, email_sender_address, active)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
+			NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''), $13)
+		RETURNING id
+	`, req.Slug, req.Name, req.Domain, req.PublicationIDPrefix, req.UniversityName, req.UniversityNameAmharic,
+		req.OfficeName, req.OfficeNameAmharic, req.Address, req.LogoPath, req.EmailSenderName, req.EmailSenderAddress, active,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "A tenant with this slug or domain already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tenant"})
+		return
+	}
+	s.tenants.clear()
+
+	var t models.Tenant
+	if err := scanTenant(s.db.Pool.QueryRow(c.Request.Context(), tenantQuery+" WHERE id = $1", id), &t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenant"})
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// UpdateTenant changes a tenant's settings. Deactivating a tenant locks its
+// users out without deleting anything.
+func (s *Server) UpdateTenant(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+	var req models.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeTenantRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if id.String() == database.DefaultTenantID && (req.Slug != "default" || (req.Active != nil && !*req.Active)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The default tenant cannot be renamed or deactivated"})
+		return
+	}
+
+	tag, err := s.db.Pool.Exec(c.Request.Context(), `
+		UPDATE tenants
+		SET slug = $2, name = $3, domain = NULLIF($4, ''), publication_id_prefix = $5,
+			university_name = NULLIF($6, ''), university_name_amharic = NULLIF($7, ''),
+			office_name = NULLIF($8, ''), office_name_amharic = NULLIF($9, ''),
+			address = NULLIF($10, ''), logo_path = NULLIF($11, ''),
+			email_sender_name = NULLIF($12, ''), email_sender_address = NULLIF($13, ''),
+			active = COALESCE($14, active), updated_at = NOW()
+		WHERE id = $1
+	`, id, req.Slug, req.Name, req.Domain, req.PublicationIDPrefix, req.UniversityName, req.UniversityNameAmharic,
+		req.OfficeName, req.OfficeNameAmharic, req.Address, req.LogoPath, req.EmailSenderName, req.EmailSenderAddress, req.Active)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "A tenant with this slug or domain already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tenant"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+	s.tenants.clear()
+
+	var t models.Tenant
+	if err := scanTenant(s.db.Pool.QueryRow(c.Request.Context(), tenantQuery+" WHERE id = $1", id), &t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenant"})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// CreateTenantAdmin creates a confirmed admin account in a tenant, typically
+// the first one of a new tenant
+func (s *Server) CreateTenantAdmin(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+	var req models.CreateTenantAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := s.db.Pool.QueryRow(c.Request.Context(), "SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)", tenantID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenant"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	identity, err := s.auth.CreateUser(c.Request.Context(), req.Email, req.Password, map[string]interface{}{
+		"name": req.Name,
+		"role": "admin",
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create user: %v", err)})
+		return
+	}
+
+	// Write as the target tenant so the row lands there
+	ctx := database.WithTenant(c.Request.Context(), tenantID)
+	user := models.User{
+		ID:          identity.ID,
+		Email:       identity.Email,
+		Name:        req.Name,
+		Role:        "admin",
+		IsVerified:  true,
+		Preferences: map[string]interface{}{},
+	}
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO users (id, email, password_hash, name, role, is_verified, preferences)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at, tenant_id
+	`, user.ID, user.Email, identity.PasswordHash, user.Name, user.Role, user.IsVerified, user.Preferences,
+	).Scan(&user.CreatedAt, &user.UpdatedAt, &user.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create user in local DB: %v", err)})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
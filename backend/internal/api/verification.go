@@ -0,0 +1,129 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/documents"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var verificationPage = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Document verification - {{.SerialNumber}}</title>
+<style>
+body { font-family: "Segoe UI", Arial, sans-serif; max-width: 560px; margin: 3rem auto; padding: 0 1rem; color: #222; }
+.status { padding: 1rem; border-radius: 8px; font-size: 1.2rem; font-weight: bold; }
+.authentic { background: #dcfce7; color: #166534; }
+.revoked { background: #fef3c7; color: #92400e; }
+.invalid { background: #fee2e2; color: #991b1b; }
+dt { color: #666; margin-top: .75rem; }
+dd { margin: 0; }
+</style>
+</head>
+<body>
+<div class="status {{.Status}}">{{.Message}}</div>
+<dl>
+<dt>Serial number</dt><dd>{{.SerialNumber}}</dd>
+{{if .RecipientName}}<dt>Issued to</dt><dd>{{.RecipientName}}</dd>{{end}}
+{{if .PaperTitle}}<dt>Paper</dt><dd>{{.PaperTitle}}</dd>{{end}}
+{{if .IssuedAt}}<dt>Issued on</dt><dd>{{.IssuedAt.Format "2 January 2006"}}</dd>{{end}}
+{{if .RevokedAt}}<dt>Revoked on</dt><dd>{{.RevokedAt.Format "2 January 2006"}}</dd>
+<dt>Reason</dt><dd>{{.RevokedReason}}</dd>{{end}}
+</dl>
+</body>
+</html>
+`))
+
+// VerifyDocument is the public endpoint behind the QR code on issued documents.
+// It recomputes the signature over the stored key fields and reports whether
+// the document is authentic, revoked or not genuine. Browsers get an HTML page.
+func (s *Server) VerifyDocument(c *gin.Context) {
+	serial := c.Param("serial")
+	result := models.DocumentVerification{SerialNumber: serial}
+
+	var kind, recipient, title, signature, reason string
+	var issuedAt time.Time
+	var revokedAt *time.Time
+	// Serial numbers are unique across tenants, and a document's QR code
+	// may be checked through any tenant's address
+	err := s.db.Pool.QueryRow(database.WithoutTenant(c.Request.Context()), `
+		SELECT kind, recipient_name, paper_title, issued_at, COALESCE(signature, ''), revoked_at, COALESCE(revocation_reason, '')
+		FROM generated_documents
+		WHERE serial_number = $1
+	`, serial).Scan(&kind, &recipient, &title, &issuedAt, &signature, &revokedAt, &reason)
+
+	key := []byte(s.config.Documents.SigningKey)
+	sig := c.Query("sig")
+	switch {
+	case err != nil:
+		result.Status = documents.StatusInvalid
+		result.Message = "No document with this serial number was issued"
+	case !documents.CheckSignature(key, signature, kind, serial, recipient, title, issuedAt),
+		sig != "" && !documents.CheckSignature(key, sig, kind, serial, recipient, title, issuedAt):
+		// Either the stored record was altered or the QR code does not belong to it
+		result.Status = documents.StatusInvalid
+		result.Message = "This document could not be verified"
+	default:
+		result.Kind = kind
+		result.RecipientName = recipient
+		result.PaperTitle = title
+		result.IssuedAt = &issuedAt
+		if revokedAt != nil {
+			result.Status = documents.StatusRevoked
+			result.Message = "This document was issued but has been revoked"
+			result.RevokedAt = revokedAt
+			result.RevokedReason = reason
+		} else {
+			result.Status = documents.StatusAuthentic
+			result.Message = "This is an authentic document"
+		}
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		verificationPage.Execute(c.Writer, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeDocument marks an issued document as revoked. Verification keeps
+// reporting it, with the reason, so a revoked copy cannot pass as valid.
+func (s *Server) RevokeDocument(c *gin.Context) {
+	docID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req models.RevokeDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var doc models.GeneratedDocument
+	err = scanGeneratedDocument(s.db.Pool.QueryRow(c.Request.Context(), `
+		UPDATE generated_documents
+		SET revoked_at = NOW(), revoked_by = $2, revocation_reason = $3
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+generatedDocumentColumns,
+		docID, c.GetString("user_id"), req.Reason,
+	), &doc)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found or already revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
@@ -32,6 +32,8 @@ func setupTestServer(t *testing.T) (*gin.Engine, *database.Database) {
 	cfg := config.New()
 	// Force test DB if possible, or use the dev DB but be careful
 	// For now we use the configured DB.
+	// Keep accounts local so no identity service is needed.
+	cfg.AuthProvider = "local"
 
 	db, err := database.NewConnection(cfg)
 	if err != nil {
@@ -84,6 +86,7 @@ func TestPublicationWorkflow(t *testing.T) {
 	if w.Code != http.StatusCreated {
 		t.Fatalf("Failed to register author: %d - %s", w.Code, w.Body.String())
 	}
+	verifyTestUser(t, router, db, authorEmail)
 
 	// 2. Login Author
 	loginReq := map[string]string{
@@ -241,8 +244,8 @@ func TestPublicationWorkflow(t *testing.T) {
 	}
 
 	// Cleanup
-	db.Pool.Exec(context.Background(), "DELETE FROM users WHERE email IN ($1, $2, $3)", authorEmail, editorEmail, coordEmail)
-	db.Pool.Exec(context.Background(), "DELETE FROM papers WHERE id = $1", paperID)
+	db.Pool.Exec(database.WithoutTenant(context.Background()), "DELETE FROM users WHERE email IN ($1, $2, $3)", authorEmail, editorEmail, coordEmail)
+	db.Pool.Exec(database.WithoutTenant(context.Background()), "DELETE FROM papers WHERE id = $1", paperID)
 }
 
 func insertTestUser(t *testing.T, router *gin.Engine, db *database.Database, email, password, role string) string {
@@ -270,17 +273,18 @@ func insertTestUser(t *testing.T, router *gin.Engine, db *database.Database, ema
 	if w.Code != http.StatusCreated {
 		t.Fatalf("Failed to register user %s: %d - %s", email, w.Code, w.Body.String())
 	}
+	verifyTestUser(t, router, db, email)
 
 	// 2. Get User ID from DB
 	var userID string
-	err := db.Pool.QueryRow(context.Background(), "SELECT id FROM users WHERE email = $1", email).Scan(&userID)
+	err := db.Pool.QueryRow(database.WithoutTenant(context.Background()), "SELECT id FROM users WHERE email = $1", email).Scan(&userID)
 	if err != nil {
 		t.Fatalf("Failed to find user %s in DB: %v", email, err)
 	}
 
 	// 3. Update Role if needed
 	if role != "author" {
-		_, err = db.Pool.Exec(context.Background(), "UPDATE users SET role = $1 WHERE id = $2", role, userID)
+		_, err = db.Pool.Exec(database.WithoutTenant(context.Background()), "UPDATE users SET role = $1 WHERE id = $2", role, userID)
 		if err != nil {
 			t.Fatalf("Failed to update role for user %s: %v", email, err)
 		}
@@ -288,3 +292,23 @@ func insertTestUser(t *testing.T, router *gin.Engine, db *database.Database, ema
 
 	return userID
 }
+
+// verifyTestUser completes a registration with the code the local provider
+// would have emailed
+func verifyTestUser(t *testing.T, router *gin.Engine, db *database.Database, email string) {
+	var code string
+	err := db.Pool.QueryRow(database.WithoutTenant(context.Background()), "SELECT code FROM pending_signups WHERE email = LOWER($1)", email).Scan(&code)
+	if err != nil {
+		t.Fatalf("Failed to find verification code for %s: %v", email, err)
+	}
+
+	body, _ := json.Marshal(models.VerifyEmailRequest{Email: email, Code: code})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to verify user %s: %d - %s", email, w.Code, w.Body.String())
+	}
+}
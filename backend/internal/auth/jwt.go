@@ -14,31 +14,87 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// Every role of the user, Role included; missing from older tokens
+	Roles []string `json:"roles,omitempty"`
+	// Tenant the user belongs to
+	TenantID string `json:"tenant_id,omitempty"`
+	// Whether the user completed a second factor when signing in
+	MFA bool `json:"mfa"`
+	jwt.RegisteredClaims
+}
+
+// Audience of the token that carries a password-verified login to the
+// second factor step; it is not an access token
+const mfaChallengeAudience = "mfa-challenge"
+
+// How long the second factor step may take
+const mfaChallengeExpiry = 5 * time.Minute
+
+// Audience of the token in a staff invitation email
+const invitationAudience = "invitation"
+
+// Audience of the cookie that carries an OpenID Connect sign-in through the
+// provider, and how long the sign-in may take
+const (
+	oidcStateAudience = "oidc-state"
+	oidcStateExpiry   = 10 * time.Minute
+)
+
+// OIDCState is what a single sign-on started with: the values the provider
+// must send back or be shown, and the tenant it started in
+type OIDCState struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	TenantID string `json:"tenant_id"`
+}
+
+type oidcStateClaims struct {
+	OIDCState
 	jwt.RegisteredClaims
 }
 
 type JWTManager struct {
-	secretKey string
-	expiry    time.Duration
+	secretKey     string
+	expiry        time.Duration
+	refreshExpiry time.Duration
 }
 
 func NewJWTManager(cfg *config.Config) *JWTManager {
 	expiry, err := time.ParseDuration(cfg.JWT.Expiry)
 	if err != nil {
-		expiry = 24 * time.Hour // Default to 24 hours
+		expiry = 15 * time.Minute // Default to 15 minutes
+	}
+	refreshExpiry, err := time.ParseDuration(cfg.JWT.RefreshExpiry)
+	if err != nil {
+		refreshExpiry = 30 * 24 * time.Hour
 	}
 
 	return &JWTManager{
-		secretKey: cfg.JWT.Secret,
-		expiry:    expiry,
+		secretKey:     cfg.JWT.Secret,
+		expiry:        expiry,
+		refreshExpiry: refreshExpiry,
 	}
 }
 
-func (j *JWTManager) GenerateToken(user *models.User) (string, error) {
+// Expiry is the lifetime of access tokens
+func (j *JWTManager) Expiry() time.Duration {
+	return j.expiry
+}
+
+// RefreshExpiry is how long a session can be refreshed after sign-in
+func (j *JWTManager) RefreshExpiry() time.Duration {
+	return j.refreshExpiry
+}
+
+func (j *JWTManager) GenerateToken(user *models.User, mfa bool) (string, error) {
 	claims := &Claims{
-		UserID: user.ID.String(),
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:   user.ID.String(),
+		Email:    user.Email,
+		Role:     user.Role,
+		Roles:    user.AllRoles(),
+		TenantID: user.TenantID.String(),
+		MFA:      mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -62,31 +118,103 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Challenge tokens have an audience and no user; neither grants access
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != "" && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
-func (j *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ValidateToken(tokenString)
-	if err != nil {
-		return "", err
+// GenerateMFAChallenge returns a short-lived token proving the user passed
+// the password step, to be traded for a session with a second factor
+func (j *JWTManager) GenerateMFAChallenge(userID string) (string, error) {
+	claims := &jwt.RegisteredClaims{
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaChallengeExpiry)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(j.secretKey))
+}
+
+// MFAChallengeExpiry is the lifetime of challenge tokens
+func (j *JWTManager) MFAChallengeExpiry() time.Duration {
+	return mfaChallengeExpiry
+}
+
+// ValidateMFAChallenge returns the user a challenge token was issued to
+func (j *JWTManager) ValidateMFAChallenge(tokenString string) (string, error) {
+	return j.validateSubject(tokenString, mfaChallengeAudience)
+}
+
+// GenerateInvitation returns the token a staff invitation email carries. It
+// only proves which invitation it belongs to; whether that invitation is
+// still open is up to the caller.
+func (j *JWTManager) GenerateInvitation(invitationID string, expiresAt time.Time) (string, error) {
+	claims := &jwt.RegisteredClaims{
+		Subject:   invitationID,
+		Audience:  jwt.ClaimStrings{invitationAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(j.secretKey))
+}
 
-	// Create new claims with updated expiration
-	newClaims := &Claims{
-		UserID: claims.UserID,
-		Email:  claims.Email,
-		Role:   claims.Role,
+// ValidateInvitation returns the invitation an invitation token was issued for
+func (j *JWTManager) ValidateInvitation(tokenString string) (string, error) {
+	return j.validateSubject(tokenString, invitationAudience)
+}
+
+// GenerateOIDCState signs the state of a single sign-on for the browser to
+// keep until the provider sends it back
+func (j *JWTManager) GenerateOIDCState(state OIDCState) (string, error) {
+	claims := &oidcStateClaims{
+		OIDCState: state,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiry)),
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(j.secretKey))
 }
+
+// OIDCStateExpiry is how long a single sign-on may take
+func (j *JWTManager) OIDCStateExpiry() time.Duration {
+	return oidcStateExpiry
+}
+
+// ValidateOIDCState returns the state GenerateOIDCState signed
+func (j *JWTManager) ValidateOIDCState(tokenString string) (*OIDCState, error) {
+	claims := &oidcStateClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(j.secretKey), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(oidcStateAudience))
+	if err != nil {
+		return nil, err
+	}
+	if claims.State == "" || claims.Verifier == "" || claims.Nonce == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return &claims.OIDCState, nil
+}
+
+// validateSubject checks a token issued for the audience and returns its
+// subject
+func (j *JWTManager) validateSubject(tokenString, audience string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(j.secretKey), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(audience))
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("invalid token")
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestMFAChallengeIsNotAnAccessToken(t *testing.T) {
+	j := NewJWTManager(&config.Config{JWT: config.JWTConfig{Secret: "test", Expiry: "15m"}})
+	user := &models.User{ID: uuid.New(), Email: "a@b.edu", Role: "admin"}
+
+	challenge, err := j.GenerateMFAChallenge(user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.ValidateToken(challenge); err == nil {
+		t.Error("challenge token accepted as an access token")
+	}
+	if id, err := j.ValidateMFAChallenge(challenge); err != nil || id != user.ID.String() {
+		t.Errorf("challenge rejected: %v", err)
+	}
+
+	access, err := j.GenerateToken(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.ValidateMFAChallenge(access); err == nil {
+		t.Error("access token accepted as a challenge")
+	}
+	claims, err := j.ValidateToken(access)
+	if err != nil || !claims.MFA {
+		t.Errorf("access token lost its mfa claim: %v", err)
+	}
+}
+
+func TestTokenCarriesEveryRole(t *testing.T) {
+	j := NewJWTManager(&config.Config{JWT: config.JWTConfig{Secret: "test", Expiry: "15m"}})
+
+	user := &models.User{ID: uuid.New(), Role: "author", Roles: []string{"author", "editor"}}
+	token, err := j.GenerateToken(user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := j.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != "author" || len(claims.Roles) != 2 || claims.Roles[1] != "editor" {
+		t.Errorf("got role %q and roles %v", claims.Role, claims.Roles)
+	}
+
+	// Users loaded without their roles still get their primary role
+	user.Roles = nil
+	token, _ = j.GenerateToken(user, false)
+	if claims, _ := j.ValidateToken(token); len(claims.Roles) != 1 || claims.Roles[0] != "author" {
+		t.Errorf("got roles %v", claims.Roles)
+	}
+}
+
+func TestInvitationTokens(t *testing.T) {
+	j := NewJWTManager(&config.Config{JWT: config.JWTConfig{Secret: "test", Expiry: "15m"}})
+	id := uuid.NewString()
+
+	token, err := j.GenerateInvitation(id, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := j.ValidateInvitation(token); err != nil || got != id {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := j.ValidateToken(token); err == nil {
+		t.Error("invitation accepted as an access token")
+	}
+	if _, err := j.ValidateMFAChallenge(token); err == nil {
+		t.Error("invitation accepted as a challenge")
+	}
+
+	expired, _ := j.GenerateInvitation(id, time.Now().Add(-time.Minute))
+	if _, err := j.ValidateInvitation(expired); err == nil {
+		t.Error("expired invitation accepted")
+	}
+}
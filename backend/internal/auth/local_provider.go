@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/email"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// How long an emailed verification code stays valid
+	verificationCodeTTL = time.Hour
+	// Minimum time between two codes sent to the same address
+	resendInterval = 30 * time.Second
+	// Wrong codes allowed before the registration has to start over
+	maxCodeAttempts = 5
+)
+
+// LocalProvider keeps bcrypt password hashes in the users table and emails
+// its own verification codes. Registrations wait in pending_signups until
+// they are verified, so unverified addresses never reach users.
+type LocalProvider struct {
+	db   *database.Database
+	mail *email.EmailSender
+}
+
+func NewLocalProvider(db *database.Database, mail *email.EmailSender) *LocalProvider {
+	return &LocalProvider{db: db, mail: mail}
+}
+
+func (p *LocalProvider) SignUp(ctx context.Context, address, password string, metadata map[string]interface{}) error {
+	address = strings.ToLower(address)
+	if taken, err := p.emailTaken(ctx, address); err != nil {
+		return err
+	} else if taken {
+		return ErrEmailTaken
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	code, err := verificationCode()
+	if err != nil {
+		return err
+	}
+
+	tag, err := p.db.Pool.Exec(ctx, `
+		INSERT INTO pending_signups (email, password_hash, metadata, code, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, email) DO UPDATE
+		SET password_hash = EXCLUDED.password_hash, metadata = EXCLUDED.metadata, code = EXCLUDED.code,
+			expires_at = EXCLUDED.expires_at, attempts = 0, sent_at = NOW()
+		WHERE pending_signups.sent_at < NOW() - $6::interval
+	`, address, hash, metadata, code, time.Now().Add(verificationCodeTTL), resendInterval.String())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTooSoon
+	}
+
+	return p.mail.ForContext(ctx).SendVerificationEmail(address, code)
+}
+
+func (p *LocalProvider) Verify(ctx context.Context, address, code string) (*Identity, error) {
+	address = strings.ToLower(address)
+
+	var identity Identity
+	var storedCode string
+	var expiresAt time.Time
+	var attempts int
+	err := p.db.Pool.QueryRow(ctx, `
+		SELECT email, password_hash, metadata, code, expires_at, attempts
+		FROM pending_signups
+		WHERE email = $1
+	`, address).Scan(&identity.Email, &identity.PasswordHash, &identity.Metadata, &storedCode, &expiresAt, &attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(expiresAt) || attempts >= maxCodeAttempts {
+		p.db.Pool.Exec(ctx, "DELETE FROM pending_signups WHERE email = $1", address)
+		return nil, ErrInvalidCode
+	}
+	if subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) != 1 {
+		p.db.Pool.Exec(ctx, "UPDATE pending_signups SET attempts = attempts + 1 WHERE email = $1", address)
+		return nil, ErrInvalidCode
+	}
+
+	if _, err := p.db.Pool.Exec(ctx, "DELETE FROM pending_signups WHERE email = $1", address); err != nil {
+		return nil, err
+	}
+	identity.ID = uuid.New()
+	return &identity, nil
+}
+
+func (p *LocalProvider) ResendCode(ctx context.Context, address string) error {
+	address = strings.ToLower(address)
+	code, err := verificationCode()
+	if err != nil {
+		return err
+	}
+
+	tag, err := p.db.Pool.Exec(ctx, `
+		UPDATE pending_signups
+		SET code = $2, expires_at = $3, attempts = 0, sent_at = NOW()
+		WHERE email = $1 AND sent_at < NOW() - $4::interval
+	`, address, code, time.Now().Add(verificationCodeTTL), resendInterval.String())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var pending bool
+		p.db.Pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pending_signups WHERE email = $1)", address).Scan(&pending)
+		if pending {
+			return ErrTooSoon
+		}
+		// Say nothing about addresses that have no registration under way
+		return nil
+	}
+
+	return p.mail.ForContext(ctx).SendVerificationEmail(address, code)
+}
+
+func (p *LocalProvider) SignIn(ctx context.Context, address, password string) (*Identity, error) {
+	var identity Identity
+	err := p.db.Pool.QueryRow(ctx,
+		"SELECT id, email, password_hash FROM users WHERE LOWER(email) = LOWER($1)",
+		address).Scan(&identity.ID, &identity.Email, &identity.PasswordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Accounts created by Supabase or imported as placeholders have no hash
+	if identity.PasswordHash == "" || !CheckPassword(password, identity.PasswordHash) {
+		return nil, ErrInvalidCredentials
+	}
+	return &identity, nil
+}
+
+func (p *LocalProvider) CreateUser(ctx context.Context, address, password string, metadata map[string]interface{}) (*Identity, error) {
+	address = strings.ToLower(address)
+	if taken, err := p.emailTaken(ctx, address); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{ID: uuid.New(), Email: address, Metadata: metadata, PasswordHash: hash}, nil
+}
+
+func (p *LocalProvider) ChangePassword(ctx context.Context, userID uuid.UUID, address, oldPassword, newPassword string) error {
+	var currentHash string
+	if err := p.db.Pool.QueryRow(ctx, "SELECT password_hash FROM users WHERE id = $1", userID).Scan(&currentHash); err != nil {
+		return err
+	}
+	if currentHash == "" || !CheckPassword(oldPassword, currentHash) {
+		return ErrInvalidCredentials
+	}
+	return p.SetPassword(ctx, userID, newPassword)
+}
+
+func (p *LocalProvider) SetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	newHash, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.Pool.Exec(ctx, "UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", newHash, userID)
+	return err
+}
+
+// SetDisabled has nothing to do: the account lives in the users table, whose
+// deactivated_at the API checks at sign-in
+func (p *LocalProvider) SetDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error {
+	return nil
+}
+
+// UpdateMetadata has nothing to do: profile fields live in the users table
+func (p *LocalProvider) UpdateMetadata(ctx context.Context, userID uuid.UUID, metadata map[string]interface{}) error {
+	return nil
+}
+
+// emailTaken reports whether a real (not placeholder) account in any tenant
+// uses the address. Placeholders are completed by registering.
+func (p *LocalProvider) emailTaken(ctx context.Context, address string) (bool, error) {
+	var taken bool
+	err := p.db.Pool.QueryRow(database.WithoutTenant(ctx), `
+		SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = $1 AND NOT COALESCE(is_placeholder, FALSE))
+	`, address).Scan(&taken)
+	return taken, err
+}
+
+func verificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
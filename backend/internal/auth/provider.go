@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/email"
+
+	"github.com/google/uuid"
+)
+
+// Identity is an account as the authentication provider knows it
+type Identity struct {
+	ID    uuid.UUID
+	Email string
+	// Registration form fields, kept by the provider until verification
+	Metadata map[string]interface{}
+	// Set by providers that keep passwords in the users table
+	PasswordHash string
+}
+
+// AuthProvider checks passwords and verifies email addresses. Profiles,
+// roles and tenants stay in the local users table either way; new accounts
+// are inserted there once the provider returns their identity.
+type AuthProvider interface {
+	// SignUp starts a registration and sends a verification code
+	SignUp(ctx context.Context, email, password string, metadata map[string]interface{}) error
+	// Verify completes a registration with the emailed code
+	Verify(ctx context.Context, email, code string) (*Identity, error)
+	ResendCode(ctx context.Context, email string) error
+	SignIn(ctx context.Context, email, password string) (*Identity, error)
+	// CreateUser creates an account that needs no verification
+	CreateUser(ctx context.Context, email, password string, metadata map[string]interface{}) (*Identity, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, email, oldPassword, newPassword string) error
+	// SetPassword replaces a password without the old one, after a reset
+	SetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error
+	// SetDisabled stops or allows sign-ins of a deactivated account
+	SetDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error
+	// UpdateMetadata keeps the provider's copy of profile fields such as
+	// name and role in step with the users table
+	UpdateMetadata(ctx context.Context, userID uuid.UUID, metadata map[string]interface{}) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidCode        = errors.New("invalid or expired verification code")
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrTooSoon            = errors.New("please wait a few seconds before requesting another code")
+	// ErrUnavailable wraps failures to reach the provider, which say nothing
+	// about the credentials
+	ErrUnavailable = errors.New("authentication provider unavailable")
+)
+
+// NewProvider returns the provider named by AUTH_PROVIDER
+func NewProvider(cfg *config.Config, db *database.Database, mail *email.EmailSender) (AuthProvider, error) {
+	switch cfg.AuthProvider {
+	case "", "supabase":
+		return NewSupabaseProvider(cfg), nil
+	case "local":
+		return NewLocalProvider(db, mail), nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_PROVIDER %q (use supabase or local)", cfg.AuthProvider)
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/supabase"
+
+	"github.com/google/uuid"
+)
+
+// SupabaseProvider keeps accounts in Supabase Auth, which also sends the
+// verification emails
+type SupabaseProvider struct {
+	client *supabase.Client
+}
+
+func NewSupabaseProvider(cfg *config.Config) *SupabaseProvider {
+	return &SupabaseProvider{client: supabase.NewClient(cfg)}
+}
+
+func (p *SupabaseProvider) SignUp(ctx context.Context, email, password string, metadata map[string]interface{}) error {
+	_, err := p.client.SignUp(email, password, metadata)
+	return err
+}
+
+func (p *SupabaseProvider) Verify(ctx context.Context, email, code string) (*Identity, error) {
+	user, err := p.client.Verify(email, code)
+	if err != nil {
+		return nil, err
+	}
+	return supabaseIdentity(user)
+}
+
+// ResendCode returns a *supabase.SupabaseError carrying Supabase's status
+// code when the request is refused
+func (p *SupabaseProvider) ResendCode(ctx context.Context, email string) error {
+	return p.client.Resend(email)
+}
+
+func (p *SupabaseProvider) SignIn(ctx context.Context, email, password string) (*Identity, error) {
+	resp, err := p.client.SignIn(email, password)
+	if err != nil {
+		return nil, signInError(err)
+	}
+	return supabaseIdentity(&resp.User)
+}
+
+// signInError tells refused credentials, which Supabase answers with 400
+// (invalid_grant), from failures to reach it, such as timeouts and 5xx
+// responses
+func signInError(err error) error {
+	var se *supabase.SupabaseError
+	if errors.As(err, &se) && se.StatusCode == http.StatusBadRequest {
+		return ErrInvalidCredentials
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+func (p *SupabaseProvider) CreateUser(ctx context.Context, email, password string, metadata map[string]interface{}) (*Identity, error) {
+	user, err := p.client.AdminCreateUser(email, password, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return supabaseIdentity(user)
+}
+
+func (p *SupabaseProvider) ChangePassword(ctx context.Context, userID uuid.UUID, email, oldPassword, newPassword string) error {
+	if _, err := p.client.SignIn(email, oldPassword); err != nil {
+		return signInError(err)
+	}
+	return p.client.AdminUpdatePassword(userID.String(), newPassword)
+}
+
+func (p *SupabaseProvider) SetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return p.client.AdminUpdatePassword(userID.String(), newPassword)
+}
+
+func (p *SupabaseProvider) SetDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error {
+	return p.client.AdminSetBanned(userID.String(), disabled)
+}
+
+func (p *SupabaseProvider) UpdateMetadata(ctx context.Context, userID uuid.UUID, metadata map[string]interface{}) error {
+	return p.client.AdminUpdateUser(userID.String(), supabase.AdminUpdateUserRequest{UserMetadata: metadata})
+}
+
+func supabaseIdentity(user *supabase.User) (*Identity, error) {
+	id, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{ID: id, Email: user.Email, Metadata: user.UserMetadata}, nil
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"rpms-backend/internal/supabase"
+)
+
+func TestSignInError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"refused", &supabase.SupabaseError{StatusCode: 400, Message: "Invalid login credentials"}, ErrInvalidCredentials},
+		{"server error", &supabase.SupabaseError{StatusCode: 503, Message: "upstream"}, ErrUnavailable},
+		{"rate limited", &supabase.SupabaseError{StatusCode: 429, Message: "slow down"}, ErrUnavailable},
+		{"network", &url.Error{Op: "Post", URL: "https://example.supabase.co", Err: errors.New("timeout")}, ErrUnavailable},
+	}
+	for _, c := range cases {
+		if err := signInError(c.err); !errors.Is(err, c.want) {
+			t.Errorf("%s: %v, want %v", c.name, err, c.want)
+		}
+	}
+}
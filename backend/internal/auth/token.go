@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewToken returns an opaque random token for the client, such as a refresh
+// or password reset token, and the hash stored in its place
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex SHA-256 of an opaque token. The tokens are random
+// enough that a fast hash is safe to store.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APITokenPrefix starts every API token, telling them apart from JWTs
+const APITokenPrefix = "rpms_"
+
+// NewAPIToken returns a new API token and the hash stored in its place
+func NewAPIToken() (token, hash string, err error) {
+	random, _, err := NewToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + random
+	return token, HashToken(token), nil
+}
+
+// IsAPIToken reports whether a bearer token is an API token rather than a JWT
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
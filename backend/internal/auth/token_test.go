@@ -0,0 +1,38 @@
+package auth
+
+import "testing"
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 43 {
+		t.Errorf("token length = %d, want 43", len(token))
+	}
+	if hash != HashToken(token) {
+		t.Error("hash does not match the token")
+	}
+	if hash == token {
+		t.Error("hash is the token itself")
+	}
+
+	other, _, _ := NewToken()
+	if other == token {
+		t.Error("two tokens are equal")
+	}
+}
+
+func TestNewAPIToken(t *testing.T) {
+	token, hash, err := NewAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIToken(token) || hash != HashToken(token) {
+		t.Errorf("token %q, hash %q", token, hash)
+	}
+	// JWTs start with the base64 of their header
+	if IsAPIToken("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Error("a JWT passes for an API token")
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret "12345678901234567890" from the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 SHA1 vectors, truncated to six digits
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("code at %d = %s, want %s", c.unix, got, c.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step, ok := ValidateTOTP(rfcSecret, "081804", now)
+	if !ok || step != TOTPStep(now) {
+		t.Fatalf("current code rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "081804", now.Add(30*time.Second)); !ok {
+		t.Error("code from the previous step rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "081804", now.Add(90*time.Second)); ok {
+		t.Error("stale code accepted")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "000000", now); ok {
+		t.Error("wrong code accepted")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("RPMS", "a@b.edu", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/RPMS:a@b.edu?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("unexpected URI %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("malformed code %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) != 10 {
+		t.Error("duplicate recovery codes")
+	}
+	if NormalizeRecoveryCode(" K7QM2-X9DPA ") != "k7qm2x9dpa" {
+		t.Error("normalization failed")
+	}
+}
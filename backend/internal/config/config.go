@@ -1,16 +1,40 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"strings"
 )
 
+// devDocSigningKey signs documents in development when DOC_SIGNING_KEY is
+// not set. Documents signed with it prove nothing.
+const devDocSigningKey = "rpms-development-document-signing-key"
+
 type Config struct {
-	Database DatabaseConfig
-	Supabase SupabaseConfig
-	JWT      JWTConfig
-	SMTP     SMTPConfig
-	GinMode  string
+	Database  DatabaseConfig
+	Supabase  SupabaseConfig
+	JWT       JWTConfig
+	SMTP      SMTPConfig
+	Documents DocumentsConfig
+	SSO       SSOConfig
+	GinMode   string
+	// How often background jobs such as scheduled publication run
+	SchedulerInterval string
+	// Days after a rejection during which the author can appeal
+	AppealWindowDays string
+	// Where accounts and passwords live: "supabase" or "local"
+	AuthProvider string
+	// Frontend page that completes a password reset; the token is appended
+	// as ?token=
+	PasswordResetURL string
+	// Frontend page where staff accept an invitation; the token is appended
+	// as ?token=
+	InvitationURL string
+	// Comma separated roles that must sign in with a second factor
+	MFARequiredRoles string
+	// Where failed sign-ins and second factor codes are counted: "memory" for
+	// a single instance, "postgres" when several instances share the load
+	LoginTracker string
 }
 
 type DatabaseConfig struct {
@@ -31,7 +55,11 @@ type SupabaseConfig struct {
 
 type JWTConfig struct {
 	Secret string
+	// Lifetime of access tokens; keep it short, sessions live on through
+	// refresh tokens
 	Expiry string
+	// Lifetime of a refresh token family since sign-in
+	RefreshExpiry string
 }
 
 type SMTPConfig struct {
@@ -41,6 +69,63 @@ type SMTPConfig struct {
 	Password string
 }
 
+// SSOConfig holds sign-in through the university directory. OpenID Connect
+// is on when an issuer is set, LDAP when a server URL is.
+type SSOConfig struct {
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	// This API's /api/v1/auth/oidc/callback URL, as registered with the provider
+	OIDCRedirectURL string
+	// Space separated scopes to request
+	OIDCScopes string
+	// Claim listing the user's groups
+	OIDCGroupsClaim string
+	// Accept emails the provider does not mark as verified
+	OIDCTrustUnverifiedEmail bool
+
+	LDAPURL          string
+	LDAPStartTLS     bool
+	LDAPBindDN       string
+	LDAPBindPassword string
+	LDAPBaseDN       string
+	// Filter finding a user by the name they sign in with; %s is replaced by it
+	LDAPUserFilter     string
+	LDAPEmailAttribute string
+	LDAPNameAttribute  string
+	LDAPGroupAttribute string
+
+	// group:role pairs separated by semicolons
+	GroupRoles string
+	// Role of new users in no mapped group
+	DefaultRole string
+	// Replace the roles of returning users with those of their groups
+	SyncRoles bool
+	// Frontend page the OIDC callback sends the browser to with a one-time
+	// ?code= to trade for a session
+	FrontendCallbackURL string
+}
+
+// DocumentsConfig holds the branding printed on generated letters and certificates
+type DocumentsConfig struct {
+	UniversityName        string
+	UniversityNameAmharic string
+	OfficeName            string
+	OfficeNameAmharic     string
+	Address               string
+	LogoPath              string
+	// TTF font with Ethiopic glyphs, needed to print the Amharic text
+	AmharicFontPath       string
+	SignatoryName         string
+	SignatoryTitle        string
+	SignatoryTitleAmharic string
+	SerialPrefix          string
+	// Base URL of the public verification endpoint printed in the QR code
+	VerifyURL string
+	// HMAC key documents are signed with
+	SigningKey string
+}
+
 func New() *Config {
 	return &Config{
 		Database: DatabaseConfig{
@@ -58,8 +143,9 @@ func New() *Config {
 			Bucket:         getEnv("SUPABASE_BUCKET", "chat-attachments"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
-			Expiry: getEnv("JWT_EXPIRY", "24h"),
+			Secret:        getEnv("JWT_SECRET", "your-secret-key"),
+			Expiry:        getEnv("JWT_EXPIRY", "15m"),
+			RefreshExpiry: getEnv("JWT_REFRESH_EXPIRY", "720h"),
 		},
 		SMTP: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", "smtp.gmail.com"),
@@ -67,8 +153,68 @@ func New() *Config {
 			Email:    getEnv("SMTP_EMAIL", ""),
 			Password: getEnv("SMTP_PASSWORD", ""),
 		},
-		GinMode: getEnv("GIN_MODE", "debug"),
+		Documents: DocumentsConfig{
+			UniversityName:        getEnv("DOC_UNIVERSITY_NAME", "St. Mary's University"),
+			UniversityNameAmharic: getEnv("DOC_UNIVERSITY_NAME_AM", "ቅድስት ማርያም ዩኒቨርሲቲ"),
+			OfficeName:            getEnv("DOC_OFFICE_NAME", "Office of Research and Publication"),
+			OfficeNameAmharic:     getEnv("DOC_OFFICE_NAME_AM", "የምርምርና ህትመት ጽ/ቤት"),
+			Address:               getEnv("DOC_ADDRESS", "Addis Ababa, Ethiopia"),
+			LogoPath:              getEnv("DOC_LOGO_PATH", ""),
+			AmharicFontPath:       getEnv("DOC_AMHARIC_FONT_PATH", ""),
+			SignatoryName:         getEnv("DOC_SIGNATORY_NAME", ""),
+			SignatoryTitle:        getEnv("DOC_SIGNATORY_TITLE", "Director, Research and Publication"),
+			SignatoryTitleAmharic: getEnv("DOC_SIGNATORY_TITLE_AM", "የምርምርና ህትመት ዳይሬክተር"),
+			SerialPrefix:          getEnv("DOC_SERIAL_PREFIX", "RPMS"),
+			VerifyURL:             getEnv("DOC_VERIFY_URL", "http://localhost:8080/api/v1/verify"),
+			SigningKey:            getEnv("DOC_SIGNING_KEY", ""),
+		},
+		SSO: SSOConfig{
+			OIDCIssuer:               getEnv("OIDC_ISSUER", ""),
+			OIDCClientID:             getEnv("OIDC_CLIENT_ID", ""),
+			OIDCClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+			OIDCRedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oidc/callback"),
+			OIDCScopes:               getEnv("OIDC_SCOPES", "openid email profile"),
+			OIDCGroupsClaim:          getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			OIDCTrustUnverifiedEmail: getEnv("OIDC_TRUST_UNVERIFIED_EMAIL", "false") == "true",
+			LDAPURL:                  getEnv("LDAP_URL", ""),
+			LDAPStartTLS:             getEnv("LDAP_STARTTLS", "false") == "true",
+			LDAPBindDN:               getEnv("LDAP_BIND_DN", ""),
+			LDAPBindPassword:         getEnv("LDAP_BIND_PASSWORD", ""),
+			LDAPBaseDN:               getEnv("LDAP_BASE_DN", ""),
+			LDAPUserFilter:           getEnv("LDAP_USER_FILTER", "(|(uid=%s)(mail=%s))"),
+			LDAPEmailAttribute:       getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			LDAPNameAttribute:        getEnv("LDAP_NAME_ATTRIBUTE", "cn"),
+			LDAPGroupAttribute:       getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			GroupRoles:               getEnv("SSO_GROUP_ROLES", ""),
+			DefaultRole:              getEnv("SSO_DEFAULT_ROLE", "author"),
+			SyncRoles:                getEnv("SSO_SYNC_ROLES", "false") == "true",
+			FrontendCallbackURL:      getEnv("SSO_CALLBACK_URL", "http://localhost:3000/sso/callback"),
+		},
+		GinMode:           getEnv("GIN_MODE", "debug"),
+		SchedulerInterval: getEnv("SCHEDULER_INTERVAL", "1m"),
+		AppealWindowDays:  getEnv("APPEAL_WINDOW_DAYS", "30"),
+		AuthProvider:      getEnv("AUTH_PROVIDER", "supabase"),
+		PasswordResetURL:  getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		InvitationURL:     getEnv("INVITATION_URL", "http://localhost:3000/accept-invitation"),
+		MFARequiredRoles:  getEnv("MFA_REQUIRED_ROLES", ""),
+		LoginTracker:      getEnv("LOGIN_TRACKER", "memory"),
+	}
+}
+
+// RequireDocSigningKey checks that documents are signed with a key of their
+// own. Only development (GIN_MODE=debug) falls back to a fixed key.
+func (c *Config) RequireDocSigningKey() error {
+	key := c.Documents.SigningKey
+	switch {
+	case key == "" && c.GinMode == "debug":
+		c.Documents.SigningKey = devDocSigningKey
+		return nil
+	case key == "":
+		return errors.New("DOC_SIGNING_KEY must be set outside development")
+	case key == c.JWT.Secret:
+		return errors.New("DOC_SIGNING_KEY must differ from JWT_SECRET")
 	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -106,6 +252,18 @@ func (c *Config) buildDatabaseURL() string {
 	return sb.String()
 }
 
+// GetMFARequiredRoles returns the roles that cannot use the API without a
+// second factor
+func (c *Config) GetMFARequiredRoles() []string {
+	var roles []string
+	for _, role := range strings.Split(c.MFARequiredRoles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
 func (c *Config) GetCORSOrigins() []string {
 	origins := getEnv("CORS_ORIGINS", "http://localhost:3000")
 	return strings.Split(origins, ",")
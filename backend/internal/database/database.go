@@ -19,7 +19,13 @@ type Database struct {
 func NewConnection(cfg *config.Config) (*Database, error) {
 	ctx := context.Background()
 
-	pool, err := pgxpool.New(ctx, cfg.GetDatabaseURL())
+	poolConfig, err := pgxpool.ParseConfig(cfg.GetDatabaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database URL: %w", err)
+	}
+	poolConfig.BeforeAcquire = setTenant
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
@@ -44,7 +50,8 @@ func (db *Database) GetDB() *pgxpool.Pool {
 }
 
 func RunMigrations(db *Database) error {
-	ctx := context.Background()
+	// Migrations work on every tenant's rows
+	ctx := WithoutTenant(context.Background())
 
 	// Create users table
 	createUsersTable := `
@@ -246,14 +253,16 @@ func RunMigrations(db *Database) error {
 		ALTER TABLE papers ADD COLUMN IF NOT EXISTS submitted_to_incubator VARCHAR(50);
 	`
 
-	// Update paper status check constraint
+	// Update paper status check constraint. Migrations run on every start,
+	// so this is the only place the list of statuses may live: any later
+	// copy would be undone here and fail on rows in the newer statuses.
 	updatePaperStatusConstraint := `
 		DO $$
 		BEGIN
 			IF EXISTS (SELECT 1 FROM information_schema.constraint_column_usage WHERE table_name = 'papers' AND constraint_name = 'papers_status_check') THEN
 				ALTER TABLE papers DROP CONSTRAINT papers_status_check;
 			END IF;
-			ALTER TABLE papers ADD CONSTRAINT papers_status_check CHECK (status IN ('draft', 'submitted', 'under_review', 'approved', 'rejected', 'published', 'recommended_for_publication'));
+			ALTER TABLE papers ADD CONSTRAINT papers_status_check CHECK (status IN ('draft', 'submitted', 'under_review', 'approved', 'rejected', 'published', 'recommended_for_publication', 'withdrawn', 'retracted'));
 		END $$;
 	`
 
@@ -343,6 +352,477 @@ func RunMigrations(db *Database) error {
 		ALTER TABLE events ADD COLUMN IF NOT EXISTS video_url TEXT;
 	`
 
+	// Mark authors created by the legacy publication import until they sign up
+	addPlaceholderToUsers := `ALTER TABLE users ADD COLUMN IF NOT EXISTS is_placeholder BOOLEAN DEFAULT FALSE;`
+
+	// ORCID iDs for researchers, DOIs for citations and external co-authors
+	addORCIDColumns := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS orcid VARCHAR(19);
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS doi VARCHAR(255);
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS orcid_put_code BIGINT;
+	`
+
+	createPaperCoauthorsTable := `
+	CREATE TABLE IF NOT EXISTS paper_coauthors (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+		user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+		name VARCHAR(255) NOT NULL,
+		email VARCHAR(255),
+		affiliation VARCHAR(255),
+		orcid VARCHAR(19),
+		position INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_paper_coauthors_paper_id ON paper_coauthors(paper_id);`
+
+	// Generated letters and certificates, stored as rendered PDFs
+	createGeneratedDocumentsTable := `
+	CREATE SEQUENCE IF NOT EXISTS document_serial_seq;
+	CREATE TABLE IF NOT EXISTS generated_documents (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		serial_number VARCHAR(64) NOT NULL UNIQUE,
+		kind VARCHAR(50) NOT NULL,
+		paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+		recipient_id UUID REFERENCES users(id) ON DELETE SET NULL,
+		recipient_name VARCHAR(255) NOT NULL,
+		paper_title VARCHAR(500) NOT NULL,
+		issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		content BYTEA NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_generated_documents_paper_id ON generated_documents(paper_id);`
+
+	// Signatures and revocation for public verification of issued documents
+	addDocumentVerification := `
+		ALTER TABLE generated_documents ADD COLUMN IF NOT EXISTS signature VARCHAR(64);
+		ALTER TABLE generated_documents ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE generated_documents ADD COLUMN IF NOT EXISTS revoked_by UUID REFERENCES users(id) ON DELETE SET NULL;
+		ALTER TABLE generated_documents ADD COLUMN IF NOT EXISTS revocation_reason TEXT;
+	`
+
+	// The university's own journals, their volumes and issues
+	createJournalTables := `
+	CREATE TABLE IF NOT EXISTS journals (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		title VARCHAR(500) NOT NULL,
+		title_amharic VARCHAR(500),
+		issn VARCHAR(9),
+		e_issn VARCHAR(9),
+		journal_type VARCHAR(50) DEFAULT 'Local',
+		publisher VARCHAR(255),
+		description TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS journal_volumes (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		journal_id UUID NOT NULL REFERENCES journals(id) ON DELETE CASCADE,
+		volume_number INTEGER NOT NULL,
+		year INTEGER NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		UNIQUE(journal_id, volume_number)
+	);
+	CREATE TABLE IF NOT EXISTS journal_issues (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		volume_id UUID NOT NULL REFERENCES journal_volumes(id) ON DELETE CASCADE,
+		issue_number INTEGER NOT NULL,
+		title VARCHAR(500),
+		status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published')),
+		publication_date DATE,
+		published_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		UNIQUE(volume_id, issue_number)
+	);
+	ALTER TABLE papers ADD COLUMN IF NOT EXISTS issue_id UUID REFERENCES journal_issues(id) ON DELETE SET NULL;
+	ALTER TABLE papers ADD COLUMN IF NOT EXISTS toc_position INTEGER;
+	ALTER TABLE papers ADD COLUMN IF NOT EXISTS page_start INTEGER;
+	ALTER TABLE papers ADD COLUMN IF NOT EXISTS page_end INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_papers_issue_id ON papers(issue_id);`
+
+	// Scheduled publication and full-text embargoes
+	addPaperScheduling := `
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS scheduled_publish_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS embargo_until TIMESTAMP WITH TIME ZONE;
+		CREATE INDEX IF NOT EXISTS idx_papers_scheduled_publish_at ON papers(scheduled_publish_at) WHERE scheduled_publish_at IS NOT NULL;
+	`
+
+	// Withdrawn submissions and retracted publications keep their row, so the
+	// record and its reviews survive. Their statuses are allowed by
+	// updatePaperStatusConstraint.
+	addPaperWithdrawalAndRetraction := `
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS withdrawn_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS withdrawal_reason TEXT;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS retracted_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS retracted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS retraction_notice TEXT;
+	`
+
+	// Appeals against rejections. rejected_at starts the appeal window; papers
+	// rejected before it existed fall back to their last update.
+	createAppealTables := `
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS rejected_at TIMESTAMP WITH TIME ZONE;
+		UPDATE papers SET rejected_at = updated_at WHERE status = 'rejected' AND rejected_at IS NULL;
+
+		CREATE TABLE IF NOT EXISTS paper_appeals (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+			appellant_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			reason TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'panel_assigned', 'upheld', 'reopened')),
+			rejected_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			panel_assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			panel_assigned_at TIMESTAMP WITH TIME ZONE,
+			decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			decision_note TEXT,
+			decided_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_appeals_paper_id ON paper_appeals(paper_id);
+		-- One appeal per rejection
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_appeals_rejection ON paper_appeals(paper_id, rejected_at);
+
+		CREATE TABLE IF NOT EXISTS appeal_panel_members (
+			appeal_id UUID NOT NULL REFERENCES paper_appeals(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			PRIMARY KEY (appeal_id, user_id)
+		);
+	`
+
+	// Organizational hierarchy. Papers without a unit of their own belong to
+	// their author's unit; scoped editors and coordinators only see their subtree.
+	createOrgUnits := `
+		CREATE TABLE IF NOT EXISTS org_units (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			parent_id UUID REFERENCES org_units(id) ON DELETE RESTRICT,
+			kind VARCHAR(30) NOT NULL CHECK (kind IN ('university', 'college', 'department', 'research_center')),
+			name VARCHAR(255) NOT NULL,
+			name_amharic VARCHAR(255),
+			code VARCHAR(50) UNIQUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_org_units_parent_id ON org_units(parent_id);
+
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS scope_org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL;
+		ALTER TABLE papers ADD COLUMN IF NOT EXISTS org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS idx_users_org_unit_id ON users(org_unit_id);
+		CREATE INDEX IF NOT EXISTS idx_papers_org_unit_id ON papers(org_unit_id);
+
+		-- Link papers whose free-text institution code matches a unit code
+		UPDATE papers p SET org_unit_id = o.id
+		FROM org_units o
+		WHERE p.org_unit_id IS NULL AND p.institution_code IS NOT NULL AND p.institution_code = o.code;
+	`
+
+	// Approval chains per paper type, and each paper's approval rounds
+	createApprovalChains := `
+		CREATE TABLE IF NOT EXISTS approval_chains (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			paper_type VARCHAR(100) NOT NULL UNIQUE,
+			name VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS approval_chain_steps (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			chain_id UUID NOT NULL REFERENCES approval_chains(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			approver_role VARCHAR(50) CHECK (approver_role IN ('editor', 'coordinator', 'admin')),
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE RESTRICT,
+			unit_kind VARCHAR(30),
+			UNIQUE (chain_id, position)
+		);
+		CREATE TABLE IF NOT EXISTS paper_approvals (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+			round INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			step_name VARCHAR(255) NOT NULL,
+			approver_role VARCHAR(50),
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'pending', 'approved', 'rejected', 'returned', 'cancelled')),
+			decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			comment TEXT,
+			decided_at TIMESTAMP WITH TIME ZONE,
+			activated_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			UNIQUE (paper_id, round, position)
+		);
+		CREATE INDEX IF NOT EXISTS idx_paper_approvals_pending ON paper_approvals(paper_id) WHERE status IN ('waiting', 'pending');
+	`
+
+	// Registrations waiting for their emailed code, used by the local
+	// authentication provider
+	createPendingSignups := `
+		CREATE TABLE IF NOT EXISTS pending_signups (
+			email VARCHAR(255) NOT NULL,
+			password_hash VARCHAR(255) NOT NULL,
+			metadata JSONB NOT NULL DEFAULT '{}',
+			code VARCHAR(6) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+	`
+
+	// Refresh tokens, stored hashed. Each sign-in starts a family; using a
+	// token rotates it, and reusing a rotated one revokes the family.
+	// Deleting a user deletes their tokens.
+	createRefreshTokens := `
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id UUID NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE,
+			revoked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+	`
+
+	// Single-use password reset tokens, stored hashed
+	createPasswordResets := `
+		CREATE TABLE IF NOT EXISTS password_resets (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+	`
+
+	// TOTP second factor. The last accepted time step keeps a code from being
+	// used twice; recovery codes are stored hashed. Refresh token families
+	// remember whether the sign-in used a second factor.
+	addTwoFactor := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+		CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			used_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+		ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE;
+	`
+
+	// Failed sign-ins per account and per client address, shared by all
+	// instances when LOGIN_TRACKER is postgres
+	createLoginAttempts := `
+		CREATE TABLE IF NOT EXISTS login_attempts (
+			key VARCHAR(512) NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			locked_until TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+	`
+
+	// Tenants. Every tenant-owned table gets a tenant_id filled from the
+	// connection's tenant, and a row level security policy that hides other
+	// tenants' rows. Connections without a tenant see no rows; those marked
+	// as working across tenants see every row and write to the default
+	// tenant, which the single-university data is moved into.
+	addTenants := `
+		CREATE TABLE IF NOT EXISTS tenants (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			slug VARCHAR(50) NOT NULL UNIQUE,
+			name VARCHAR(255) NOT NULL,
+			domain VARCHAR(255) UNIQUE,
+			publication_id_prefix VARCHAR(20) NOT NULL DEFAULT 'SMU_P',
+			university_name VARCHAR(255),
+			university_name_amharic VARCHAR(255),
+			office_name VARCHAR(255),
+			office_name_amharic VARCHAR(255),
+			address TEXT,
+			logo_path TEXT,
+			email_sender_name VARCHAR(255),
+			email_sender_address VARCHAR(255),
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		INSERT INTO tenants (id, slug, name) VALUES ('` + DefaultTenantID + `', 'default', 'St. Mary''s University')
+		ON CONFLICT (id) DO NOTHING;
+
+		CREATE OR REPLACE FUNCTION current_tenant_id() RETURNS UUID
+		LANGUAGE sql STABLE AS $$ SELECT NULLIF(current_setting('app.tenant_id', true), '')::uuid $$;
+		CREATE OR REPLACE FUNCTION all_tenants() RETURNS BOOLEAN
+		LANGUAGE sql STABLE AS $$ SELECT COALESCE(current_setting('app.all_tenants', true), '') = 'on' $$;
+
+		-- Roles are data (see the roles table), checked by the application
+		ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+
+		-- Unit codes and approval chains are unique within a tenant
+		ALTER TABLE org_units DROP CONSTRAINT IF EXISTS org_units_code_key;
+		ALTER TABLE approval_chains DROP CONSTRAINT IF EXISTS approval_chains_paper_type_key;
+	`
+	for _, t := range tenantTables {
+		if t.owner != "" {
+			addTenants += inheritTenant(t.name, t.ownerColumn, t.owner)
+		}
+		addTenants += isolateTenant(t.name)
+	}
+	addTenants += `
+		-- Sign-ups and failure counts are kept per tenant. Older databases
+		-- keyed them by email and key alone.
+		ALTER TABLE pending_signups DROP CONSTRAINT IF EXISTS pending_signups_pkey;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_signups_tenant_email ON pending_signups(tenant_id, email);
+		ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_pkey;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_tenant_key ON login_attempts(tenant_id, key);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_org_units_tenant_code ON org_units(tenant_id, code);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_approval_chains_tenant_paper_type ON approval_chains(tenant_id, paper_type);
+	`
+
+	// Custom roles of a tenant. Built-in roles live in code (package rbac).
+	createRoles := `
+		CREATE TABLE IF NOT EXISTS roles (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(50) NOT NULL,
+			label VARCHAR(255) NOT NULL,
+			description TEXT,
+			permissions TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		ALTER TABLE approval_chain_steps DROP CONSTRAINT IF EXISTS approval_chain_steps_approver_role_check;
+	` + isolateTenant("roles") + `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_tenant_name ON roles(tenant_id, name);
+	`
+
+	// A user can hold several roles. role stays the primary one, shown in
+	// lists and kept for older clients; roles holds all of them. The trigger
+	// keeps role inside roles, and a statement that only changes role swaps
+	// it in place of the old one, as before.
+	addUserRoles := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{}';
+		UPDATE users SET roles = ARRAY[role] WHERE NOT (role = ANY(roles));
+		CREATE INDEX IF NOT EXISTS idx_users_roles ON users USING GIN (roles);
+
+		CREATE OR REPLACE FUNCTION users_sync_roles() RETURNS TRIGGER AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND NEW.role IS DISTINCT FROM OLD.role AND NEW.roles = OLD.roles THEN
+				NEW.roles := array_remove(NEW.roles, OLD.role);
+			END IF;
+			IF NOT (NEW.role = ANY(NEW.roles)) THEN
+				NEW.roles := array_prepend(NEW.role, NEW.roles);
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS users_sync_roles ON users;
+		CREATE TRIGGER users_sync_roles BEFORE INSERT OR UPDATE OF role, roles ON users
+			FOR EACH ROW EXECUTE FUNCTION users_sync_roles();
+	`
+
+	// Deactivated accounts cannot sign in or use their tokens. The audit log
+	// records what admins did to accounts and roles.
+	addUserAdministration := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE;
+
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			action VARCHAR(100) NOT NULL,
+			target_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			details JSONB NOT NULL DEFAULT '{}',
+			ip_address VARCHAR(64),
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_target_user_id ON audit_log(target_user_id);
+	` + isolateTenant("audit_log")
+
+	// Staff invitations. The emailed token is signed and names the
+	// invitation; the row says whether it is still open and what the
+	// invitee gets.
+	createInvitations := `
+		CREATE TABLE IF NOT EXISTS invitations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			email VARCHAR(255) NOT NULL,
+			name VARCHAR(255),
+			role VARCHAR(50) NOT NULL,
+			roles TEXT[] NOT NULL DEFAULT '{}',
+			org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL,
+			scope_org_unit_id UUID REFERENCES org_units(id) ON DELETE SET NULL,
+			invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			accepted_at TIMESTAMP WITH TIME ZONE,
+			accepted_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			revoked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations(LOWER(email));
+	` + isolateTenant("invitations")
+
+	// Accounts of the university directory linked to users, and the
+	// one-time codes an OpenID Connect sign-in hands the browser to trade
+	// for a session
+	createSSOIdentities := `
+		CREATE TABLE IF NOT EXISTS sso_identities (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			provider VARCHAR(20) NOT NULL,
+			subject VARCHAR(500) NOT NULL,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			last_login_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_sso_identities_user_id ON sso_identities(user_id);
+
+		CREATE TABLE IF NOT EXISTS sso_login_codes (
+			code_hash VARCHAR(64) PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+	` + isolateTenant("sso_identities") + isolateTenant("sso_login_codes") + `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_sso_identities_subject ON sso_identities(tenant_id, provider, subject);
+	`
+
+	// API tokens of users and service accounts, stored hashed. Service
+	// accounts are users without a password.
+	createAPITokens := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS is_service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			prefix VARCHAR(20) NOT NULL,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			last_used_ip VARCHAR(64),
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			revoked_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+
+		-- Whether the session creating the token had completed a second factor
+		ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE;
+	` + isolateTenant("api_tokens")
+
 	migrations := []string{
 		createUsersTable,
 		createPapersTable,
@@ -376,6 +856,29 @@ func RunMigrations(db *Database) error {
 		addReviewRatingColumns,
 		addMediaToNews,
 		addMediaToEvents,
+		addPlaceholderToUsers,
+		addORCIDColumns,
+		createPaperCoauthorsTable,
+		createGeneratedDocumentsTable,
+		addDocumentVerification,
+		createJournalTables,
+		addPaperScheduling,
+		addPaperWithdrawalAndRetraction,
+		createAppealTables,
+		createOrgUnits,
+		createApprovalChains,
+		createPendingSignups,
+		createRefreshTokens,
+		createPasswordResets,
+		addTwoFactor,
+		createLoginAttempts,
+		addTenants,
+		createRoles,
+		addUserRoles,
+		addUserAdministration,
+		createInvitations,
+		createSSOIdentities,
+		createAPITokens,
 	}
 
 	for _, migration := range migrations {
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabase connects to the scratch database in TEST_DATABASE_URL. The
+// tests write to it, so never point it at real data.
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.BeforeAcquire = setTenant
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return &Database{Pool: pool}
+}
+
+func TestMigrationsRerunWithNewerPaperStatuses(t *testing.T) {
+	db := testDatabase(t)
+	ctx := WithoutTenant(context.Background())
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	userID := uuid.New()
+	if _, err := db.Pool.Exec(ctx,
+		"INSERT INTO users (id, email, password_hash, name, role) VALUES ($1, $2, '', 'Migration Test', 'author')",
+		userID, userID.String()+"@example.invalid"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM users WHERE id = $1", userID) })
+	for _, status := range []string{"withdrawn", "retracted"} {
+		if _, err := db.Pool.Exec(ctx,
+			"INSERT INTO papers (title, author_id, status) VALUES ($1, $2, $3)",
+			"Migration test "+status, userID, status); err != nil {
+			t.Fatalf("insert %s paper: %v", status, err)
+		}
+	}
+
+	// Every start runs the migrations again
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("second run with withdrawn and retracted papers: %v", err)
+	}
+}
+
+func TestContextWithoutTenantSeesNothing(t *testing.T) {
+	db := testDatabase(t)
+	all := WithoutTenant(context.Background())
+	if err := RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.New()
+	if _, err := db.Pool.Exec(all,
+		"INSERT INTO users (id, email, password_hash, name, role) VALUES ($1, $2, '', 'Tenant Test', 'author')",
+		userID, userID.String()+"@example.invalid"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Pool.Exec(all, "DELETE FROM users WHERE id = $1", userID) })
+
+	var n int
+	if err := db.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM users WHERE id = $1", userID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("a context without a tenant sees users")
+	}
+	if err := db.Pool.QueryRow(WithTenant(context.Background(), uuid.MustParse(DefaultTenantID)),
+		"SELECT COUNT(*) FROM users WHERE id = $1", userID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Error("the user's tenant does not see it")
+	}
+
+	if _, err := db.Pool.Exec(context.Background(),
+		"INSERT INTO notifications (user_id, message) VALUES ($1, 'unscoped')", userID); err == nil {
+		t.Error("a context without a tenant wrote a notification")
+	}
+}
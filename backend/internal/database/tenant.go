@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTenantID is the tenant that data from before multi-tenancy, and rows
+// written without a tenant, belong to
+const DefaultTenantID = "00000000-0000-0000-0000-000000000001"
+
+// tenantTables carry a tenant_id column guarded by row level security. Rows
+// written before a table was isolated take the tenant of the row they belong
+// to, through ownerColumn referencing owner, or else the default tenant.
+// Owners come before the tables that belong to them.
+var tenantTables = []struct{ name, ownerColumn, owner string }{
+	{"users", "", ""},
+	{"papers", "", ""},
+	{"events", "", ""},
+	{"news", "", ""},
+	{"messages", "", ""},
+	{"reviews", "", ""},
+	{"journals", "", ""},
+	{"org_units", "", ""},
+	{"approval_chains", "", ""},
+	{"paper_appeals", "", ""},
+	{"notifications", "user_id", "users"},
+	{"generated_documents", "paper_id", "papers"},
+	{"paper_coauthors", "paper_id", "papers"},
+	{"journal_volumes", "journal_id", "journals"},
+	{"journal_issues", "volume_id", "journal_volumes"},
+	{"refresh_tokens", "user_id", "users"},
+	{"password_resets", "user_id", "users"},
+	{"mfa_recovery_codes", "user_id", "users"},
+	{"pending_signups", "", ""},
+	{"login_attempts", "", ""},
+}
+
+type tenantKey struct{}
+
+// allTenants marks a context that deliberately works across tenants
+type allTenants struct{}
+
+// WithTenant returns a context whose queries only see rows of the tenant
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant set on the context, if any
+func TenantFrom(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// setTenant runs before a pooled connection is handed out and points the
+// row level security policies at the tenant of the caller's context.
+// Contexts without a tenant see no rows and cannot write any; migrations, the
+// scheduler and CLIs ask for every tenant with WithoutTenant.
+func setTenant(ctx context.Context, conn *pgx.Conn) bool {
+	tenant, all := "", "off"
+	if id, ok := TenantFrom(ctx); ok {
+		tenant = id.String()
+	} else if _, ok := ctx.Value(tenantKey{}).(allTenants); ok {
+		all = "on"
+	}
+	if _, err := conn.Exec(ctx,
+		"SELECT set_config('app.tenant_id', $1, false), set_config('app.all_tenants', $2, false)",
+		tenant, all); err != nil {
+		log.Printf("Failed to set tenant on connection: %v", err)
+		return false
+	}
+	return true
+}
+
+// RequireRLS returns an error when the database role ignores row level
+// security, in which case every tenant would see every other tenant's rows.
+// The API server refuses to start then; tools that work across tenants on
+// purpose connect without this check.
+func (db *Database) RequireRLS(ctx context.Context) error {
+	var bypass bool
+	err := db.Pool.QueryRow(ctx,
+		"SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypass)
+	if err != nil {
+		return fmt.Errorf("failed to check the database role: %w", err)
+	}
+	if bypass {
+		return fmt.Errorf("the database role bypasses row level security, so tenants would not be isolated; connect as a role without SUPERUSER or BYPASSRLS")
+	}
+	return nil
+}
+
+// WithoutTenant returns a context whose queries see every tenant's rows, for
+// the few lookups that span tenants such as email uniqueness and for jobs
+// that work across tenants. Rows it inserts without a tenant_id land in the
+// default tenant. It only takes effect when a connection is acquired, not
+// inside a transaction begun with another context.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, allTenants{})
+}
+
+// inheritTenant returns the SQL that gives a table's rows the tenant of the
+// owner row they reference, ahead of isolateTenant
+func inheritTenant(table, ownerColumn, owner string) string {
+	return fmt.Sprintf(`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE RESTRICT;
+		UPDATE %[1]s t SET tenant_id = o.tenant_id FROM %[3]s o WHERE t.%[2]s = o.id AND t.tenant_id IS NULL;
+	`, table, ownerColumn, owner)
+}
+
+// isolateTenant returns the SQL that gives a table a tenant_id filled from the
+// connection's tenant and the row level security policy hiding other
+// tenants' rows
+func isolateTenant(table string) string {
+	return fmt.Sprintf(`
+		ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE RESTRICT;
+		UPDATE %[1]s SET tenant_id = '%[2]s' WHERE tenant_id IS NULL;
+		ALTER TABLE %[1]s ALTER COLUMN tenant_id SET DEFAULT COALESCE(current_tenant_id(), '%[2]s');
+		ALTER TABLE %[1]s ALTER COLUMN tenant_id SET NOT NULL;
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_tenant_id ON %[1]s(tenant_id);
+		ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY;
+		ALTER TABLE %[1]s FORCE ROW LEVEL SECURITY;
+		DROP POLICY IF EXISTS tenant_isolation ON %[1]s;
+		CREATE POLICY tenant_isolation ON %[1]s
+			USING (all_tenants() OR tenant_id = current_tenant_id())
+			WITH CHECK (all_tenants() OR tenant_id = current_tenant_id());
+	`, table, DefaultTenantID)
+}
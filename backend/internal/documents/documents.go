@@ -0,0 +1,137 @@
+// Package documents renders the official letters and certificates the
+// research office issues for papers.
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/ethcal"
+)
+
+// Document kinds
+const (
+	KindAcceptanceLetter       = "acceptance_letter"
+	KindPublicationCertificate = "publication_certificate"
+)
+
+// Template describes one kind of document. The body templates are executed
+// with a view built from Data and the branding.
+type Template struct {
+	Kind         string
+	Code         string
+	Title        string
+	TitleAmharic string
+	Certificate  bool
+	// Paper statuses the document can be issued for
+	Statuses []string
+
+	english *template.Template
+	amharic *template.Template
+}
+
+// Templates holds the document kinds RPMS can issue, by kind
+var Templates = map[string]*Template{
+	KindAcceptanceLetter: {
+		Kind:         KindAcceptanceLetter,
+		Code:         "AL",
+		Title:        "Letter of Acceptance",
+		TitleAmharic: "የተቀባይነት ደብዳቤ",
+		Statuses:     []string{"approved", "recommended_for_publication", "published"},
+		english: template.Must(template.New("al-en").Parse(
+			`Dear {{.RecipientName}},
+
+We are pleased to inform you that your paper entitled "{{.PaperTitle}}" has been reviewed and accepted for publication{{if .JournalName}} in {{.JournalName}}{{end}}.{{if .PublicationID}} The paper is registered under publication number {{.PublicationID}}.{{end}}
+
+We congratulate you on this achievement and thank you for your contribution to research at {{.UniversityName}}.`)),
+		amharic: template.Must(template.New("al-am").Parse(
+			`ለ{{.RecipientName}}
+
+"{{.PaperTitleAmharic}}" በሚል ርዕስ ያቀረቡት የጥናት ጽሑፍ ተገምግሞ ለህትመት ተቀባይነት ማግኘቱን ስናሳውቅዎ በደስታ ነው።{{if .PublicationID}} ጽሑፉ በህትመት ቁጥር {{.PublicationID}} ተመዝግቧል።{{end}}
+
+ለ{{.UniversityNameAmharic}} የምርምር ሥራ ላደረጉት አስተዋጽኦ እናመሰግናለን።`)),
+	},
+	KindPublicationCertificate: {
+		Kind:         KindPublicationCertificate,
+		Code:         "PC",
+		Title:        "Certificate of Publication",
+		TitleAmharic: "የህትመት ምስክር ወረቀት",
+		Certificate:  true,
+		Statuses:     []string{"published"},
+		english: template.Must(template.New("pc-en").Parse(
+			`This is to certify that {{.RecipientName}} is the author of the paper "{{.PaperTitle}}", published{{if .JournalName}} in {{.JournalName}}{{end}}{{if .PublicationDate}} on {{.PublicationDate}}{{end}}{{if .PublicationID}} under publication number {{.PublicationID}}{{end}}.`)),
+		amharic: template.Must(template.New("pc-am").Parse(
+			`{{.RecipientName}} "{{.PaperTitleAmharic}}" የሚል ርዕስ ያለው የጥናት ጽሑፍ ደራሲ መሆናቸውንና ጽሑፉ{{if .PublicationDateAmharic}} {{.PublicationDateAmharic}}{{end}} መታተሙን እናረጋግጣለን።`)),
+	},
+}
+
+// AllowedFor reports whether the document can be issued for a paper in the given status
+func (t *Template) AllowedFor(status string) bool {
+	for _, s := range t.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Data is the paper and author data a document is generated from
+type Data struct {
+	Kind              string
+	Serial            string
+	RecipientName     string
+	PaperTitle        string
+	PaperTitleAmharic string
+	PublicationID     string
+	JournalName       string
+	PublicationDate   *time.Time
+	IssuedAt          time.Time
+	// Printed as a QR code so the document can be checked online
+	VerifyURL string
+}
+
+// view is what the body templates see
+type view struct {
+	Data
+	UniversityName         string
+	UniversityNameAmharic  string
+	PublicationDate        string
+	PublicationDateAmharic string
+}
+
+func (t *Template) bodies(d Data, b config.DocumentsConfig) (string, string, error) {
+	v := view{
+		Data:                  d,
+		UniversityName:        b.UniversityName,
+		UniversityNameAmharic: b.UniversityNameAmharic,
+	}
+	if v.PaperTitleAmharic == "" {
+		v.PaperTitleAmharic = d.PaperTitle
+	}
+	if d.PublicationDate != nil {
+		v.PublicationDate = d.PublicationDate.Format("2 January 2006")
+		v.PublicationDateAmharic = ethcal.FromGregorian(*d.PublicationDate).AmharicString()
+	}
+
+	var en, am bytes.Buffer
+	if err := t.english.Execute(&en, v); err != nil {
+		return "", "", err
+	}
+	if err := t.amharic.Execute(&am, v); err != nil {
+		return "", "", err
+	}
+	return en.String(), am.String(), nil
+}
+
+// Serial builds a document serial number such as "RPMS-AL-2017-000042" from
+// the prefix, the document kind, the Ethiopian fiscal year and a sequence number.
+func Serial(prefix, kind string, issuedAt time.Time, seq int64) (string, error) {
+	t, ok := Templates[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown document kind %q", kind)
+	}
+	return fmt.Sprintf("%s-%s-%d-%06d", prefix, t.Code, ethcal.FiscalYear(issuedAt), seq), nil
+}
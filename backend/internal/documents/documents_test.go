@@ -0,0 +1,150 @@
+package documents
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"rpms-backend/internal/config"
+)
+
+func sampleData(kind string) Data {
+	published := time.Date(2024, 9, 11, 0, 0, 0, 0, time.UTC)
+	return Data{
+		Kind:            kind,
+		Serial:          "RPMS-PC-2017-000001",
+		RecipientName:   "Almaz Tesfaye",
+		PaperTitle:      "Coffee leaf rust in Sidama",
+		PublicationID:   "SMU-2024-0007",
+		JournalName:     "Ethiopian Journal of Science",
+		PublicationDate: &published,
+		IssuedAt:        published,
+	}
+}
+
+func TestSerial(t *testing.T) {
+	// 11 September 2024 is Meskerem 1, 2017, in the fiscal year that ends in 2017
+	got, err := Serial("RPMS", KindPublicationCertificate, time.Date(2024, 9, 11, 0, 0, 0, 0, time.UTC), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "RPMS-PC-2017-000042" {
+		t.Errorf("Serial = %q", got)
+	}
+	if _, err := Serial("RPMS", "memo", time.Now(), 1); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestBodies(t *testing.T) {
+	en, am, err := Templates[KindPublicationCertificate].bodies(sampleData(KindPublicationCertificate), config.DocumentsConfig{UniversityName: "St. Mary's University"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(en, "Almaz Tesfaye") || !strings.Contains(en, "11 September 2024") {
+		t.Errorf("unexpected English body: %s", en)
+	}
+	// Without an Amharic title the English one is used, and dates are Ethiopian
+	if !strings.Contains(am, "Coffee leaf rust in Sidama") || !strings.Contains(am, "መስከረም 1 ቀን 2017 ዓ.ም") {
+		t.Errorf("unexpected Amharic body: %s", am)
+	}
+}
+
+func TestAllowedFor(t *testing.T) {
+	if Templates[KindPublicationCertificate].AllowedFor("approved") {
+		t.Error("certificates are only for published papers")
+	}
+	if !Templates[KindAcceptanceLetter].AllowedFor("approved") {
+		t.Error("acceptance letters can be issued for approved papers")
+	}
+}
+
+func TestRender(t *testing.T) {
+	branding := config.DocumentsConfig{
+		UniversityName:        "St. Mary's University",
+		UniversityNameAmharic: "ቅድስት ማርያም ዩኒቨርሲቲ",
+		OfficeName:            "Office of Research and Publication",
+		SignatoryName:         "Dr. Example",
+		SignatoryTitle:        "Director",
+		SerialPrefix:          "RPMS",
+		LogoPath:              "does-not-exist.png",
+	}
+
+	for kind := range Templates {
+		var buf bytes.Buffer
+		if err := Render(&buf, sampleData(kind), branding); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+			t.Errorf("%s: output is not a PDF", kind)
+		}
+	}
+
+	// Any UTF-8 TrueType font exercises the bilingual path
+	const font = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	if _, err := os.Stat(font); err != nil {
+		t.Skip("no TrueType font available")
+	}
+	branding.AmharicFontPath = font
+	var buf bytes.Buffer
+	if err := Render(&buf, sampleData(KindAcceptanceLetter), branding); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	key := []byte("secret")
+	issued := time.Date(2024, 9, 11, 15, 4, 5, 0, time.FixedZone("EAT", 3*3600))
+	sig := Sign(key, KindPublicationCertificate, "RPMS-PC-2017-000001", "Almaz Tesfaye", "Coffee leaf rust", issued)
+
+	if !CheckSignature(key, sig, KindPublicationCertificate, "RPMS-PC-2017-000001", "Almaz Tesfaye", "Coffee leaf rust", issued.UTC()) {
+		t.Error("signature should verify regardless of time zone")
+	}
+	if CheckSignature(key, sig, KindPublicationCertificate, "RPMS-PC-2017-000001", "Almaz Tesfay", "Coffee leaf rust", issued) {
+		t.Error("a changed recipient must not verify")
+	}
+	if CheckSignature(key, sig, KindAcceptanceLetter, "RPMS-PC-2017-000001", "Almaz Tesfaye", "Coffee leaf rust", issued) {
+		t.Error("a changed kind must not verify")
+	}
+	if CheckSignature([]byte("other"), sig, KindPublicationCertificate, "RPMS-PC-2017-000001", "Almaz Tesfaye", "Coffee leaf rust", issued) {
+		t.Error("a different key must not verify")
+	}
+	if CheckSignature(key, "", KindPublicationCertificate, "RPMS-PC-2017-000001", "Almaz Tesfaye", "Coffee leaf rust", issued) {
+		t.Error("an empty signature must not verify")
+	}
+
+	want := "https://rpms.example/api/v1/verify/RPMS-PC-2017-000001?sig=" + sig
+	if got := VerifyURL("https://rpms.example/api/v1/verify/", "RPMS-PC-2017-000001", sig); got != want {
+		t.Errorf("VerifyURL = %q", got)
+	}
+}
+
+func TestRenderWithQRCode(t *testing.T) {
+	d := sampleData(KindPublicationCertificate)
+	d.VerifyURL = "https://rpms.example/api/v1/verify/" + d.Serial + "?sig=abc"
+	var buf bytes.Buffer
+	if err := Render(&buf, d, config.DocumentsConfig{UniversityName: "St. Mary's University"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderRetractionNotice(t *testing.T) {
+	published := time.Date(2024, 9, 11, 0, 0, 0, 0, time.UTC)
+	n := RetractionNotice{
+		PaperTitle:      "Coffee leaf rust in Sidama",
+		Authors:         []string{"Almaz Tesfaye", "Kebede Alemu"},
+		PublicationID:   "SMU-2024-0007",
+		PublicationDate: &published,
+		RetractedAt:     published.AddDate(1, 0, 0),
+		Notice:          "The field data in tables 2 and 3 could not be reproduced.",
+	}
+	var buf bytes.Buffer
+	if err := RenderRetractionNotice(&buf, n, config.DocumentsConfig{UniversityName: "St. Mary's University"}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("output is not a PDF")
+	}
+}
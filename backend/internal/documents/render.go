@@ -0,0 +1,257 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"rpms-backend/internal/config"
+	"rpms-backend/internal/ethcal"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+const amharicFont = "ethiopic"
+
+// Render writes the document as a PDF. The Amharic text needs a font with
+// Ethiopic glyphs (DOC_AMHARIC_FONT_PATH); without one the document is
+// rendered in English only.
+func Render(w io.Writer, d Data, b config.DocumentsConfig) error {
+	t, ok := Templates[d.Kind]
+	if !ok {
+		return fmt.Errorf("unknown document kind %q", d.Kind)
+	}
+	english, amharic, err := t.bodies(d, b)
+	if err != nil {
+		return fmt.Errorf("failed to fill %s template: %w", d.Kind, err)
+	}
+
+	orientation := "P"
+	if t.Certificate {
+		orientation = "L"
+	}
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	pdf.SetTitle(t.Title+" "+d.Serial, true)
+	pdf.SetCreator(b.OfficeName, true)
+
+	bilingual := false
+	if b.AmharicFontPath != "" {
+		font, err := os.ReadFile(b.AmharicFontPath)
+		if err != nil {
+			return fmt.Errorf("failed to read Amharic font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes(amharicFont, "", font)
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("failed to load Amharic font: %w", err)
+		}
+		bilingual = true
+	}
+
+	r := &renderer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor(""), bilingual: bilingual}
+	if d.VerifyURL != "" {
+		png, err := qrcode.Encode(d.VerifyURL, qrcode.Medium, 256)
+		if err != nil {
+			return fmt.Errorf("failed to encode verification QR code: %w", err)
+		}
+		pdf.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		r.hasQR = true
+	}
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	if t.Certificate {
+		r.certificate(t, d, b, english, amharic)
+	} else {
+		r.letter(t, d, b, english, amharic)
+	}
+	return pdf.Output(w)
+}
+
+type renderer struct {
+	pdf       *gofpdf.Fpdf
+	tr        func(string) string
+	bilingual bool
+	hasQR     bool
+}
+
+// qr places the verification QR code with a caption underneath
+func (r *renderer) qr(x, y, size float64) {
+	if !r.hasQR {
+		return
+	}
+	r.pdf.ImageOptions("qr", x, y, size, size, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	r.pdf.SetXY(x-10, y+size)
+	r.english("", 7)
+	r.pdf.SetTextColor(102, 102, 102)
+	r.pdf.CellFormat(size+20, 4, "Scan to verify this document", "", 0, "C", false, 0, "")
+	r.pdf.SetTextColor(34, 34, 34)
+}
+
+func (r *renderer) english(style string, size float64) {
+	r.pdf.SetFont("Helvetica", style, size)
+}
+
+// amharicText writes a centred or left aligned block of Amharic text, if the font is available
+func (r *renderer) amharicText(text string, size, lineHeight float64, align string) {
+	if !r.bilingual || text == "" {
+		return
+	}
+	r.pdf.SetFont(amharicFont, "", size)
+	r.pdf.MultiCell(0, lineHeight, text, "", align, false)
+}
+
+func (r *renderer) logo(b config.DocumentsConfig, x, y, size float64) bool {
+	if b.LogoPath == "" {
+		return false
+	}
+	r.pdf.ImageOptions(b.LogoPath, x, y, size, 0, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	if r.pdf.Err() {
+		// A missing or unreadable logo should not block issuing the document
+		r.pdf.ClearError()
+		return false
+	}
+	return true
+}
+
+func (r *renderer) letterhead(b config.DocumentsConfig) {
+	pdf := r.pdf
+	left, top, _, _ := pdf.GetMargins()
+	hasLogo := r.logo(b, left, top, 22)
+
+	pdf.SetY(top)
+	pdf.SetTextColor(30, 58, 138)
+	r.english("B", 16)
+	pdf.CellFormat(0, 8, r.tr(b.UniversityName), "", 1, "C", false, 0, "")
+	r.amharicText(b.UniversityNameAmharic, 13, 7, "C")
+	pdf.SetTextColor(60, 60, 60)
+	r.english("", 11)
+	pdf.CellFormat(0, 6, r.tr(b.OfficeName), "", 1, "C", false, 0, "")
+	r.amharicText(b.OfficeNameAmharic, 10, 6, "C")
+	if b.Address != "" {
+		r.english("", 9)
+		pdf.CellFormat(0, 5, r.tr(b.Address), "", 1, "C", false, 0, "")
+	}
+	if hasLogo && pdf.GetY() < top+24 {
+		pdf.SetY(top + 24)
+	}
+	pdf.Ln(2)
+	pageWidth, _ := pdf.GetPageSize()
+	pdf.SetDrawColor(30, 58, 138)
+	pdf.SetLineWidth(0.6)
+	pdf.Line(left, pdf.GetY(), pageWidth-left, pdf.GetY())
+	pdf.SetLineWidth(0.2)
+	pdf.SetTextColor(34, 34, 34)
+}
+
+func (r *renderer) signature(b config.DocumentsConfig, x, width float64) {
+	pdf := r.pdf
+	pdf.SetX(x)
+	pdf.SetDrawColor(34, 34, 34)
+	pdf.Line(x, pdf.GetY(), x+width, pdf.GetY())
+	pdf.Ln(1)
+	if b.SignatoryName != "" {
+		pdf.SetX(x)
+		r.english("B", 11)
+		pdf.CellFormat(width, 6, r.tr(b.SignatoryName), "", 1, "L", false, 0, "")
+	}
+	pdf.SetX(x)
+	r.english("", 10)
+	pdf.CellFormat(width, 5, r.tr(b.SignatoryTitle), "", 1, "L", false, 0, "")
+	if r.bilingual && b.SignatoryTitleAmharic != "" {
+		pdf.SetX(x)
+		pdf.SetFont(amharicFont, "", 9)
+		pdf.CellFormat(width, 5, b.SignatoryTitleAmharic, "", 1, "L", false, 0, "")
+	}
+}
+
+func (r *renderer) letter(t *Template, d Data, b config.DocumentsConfig, english, amharic string) {
+	pdf := r.pdf
+	r.letterhead(b)
+	pdf.Ln(6)
+
+	r.english("", 10)
+	pdf.CellFormat(95, 5, "Ref: "+d.Serial, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 5, "Date: "+d.IssuedAt.Format("2 January 2006"), "", 1, "R", false, 0, "")
+	if r.bilingual {
+		pdf.SetFont(amharicFont, "", 9)
+		pdf.CellFormat(0, 5, "ቀን: "+ethcal.FromGregorian(d.IssuedAt).AmharicString(), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(8)
+
+	r.english("B", 14)
+	pdf.CellFormat(0, 8, r.tr(t.Title), "", 1, "C", false, 0, "")
+	r.amharicText(t.TitleAmharic, 12, 7, "C")
+	pdf.Ln(6)
+
+	r.english("", 11)
+	pdf.MultiCell(0, 6, r.tr(english), "", "L", false)
+	if r.bilingual {
+		pdf.Ln(6)
+		r.amharicText(amharic, 10, 6, "L")
+	}
+
+	pdf.Ln(20)
+	left, _, right, _ := pdf.GetMargins()
+	signatureY := pdf.GetY()
+	r.signature(b, left, 80)
+	pageWidth, _ := pdf.GetPageSize()
+	r.qr(pageWidth-right-30, signatureY-12, 30)
+}
+
+func (r *renderer) certificate(t *Template, d Data, b config.DocumentsConfig, english, amharic string) {
+	pdf := r.pdf
+	pageWidth, pageHeight := pdf.GetPageSize()
+
+	// Double border
+	pdf.SetDrawColor(30, 58, 138)
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(8, 8, pageWidth-16, pageHeight-16, "D")
+	pdf.SetLineWidth(0.4)
+	pdf.Rect(12, 12, pageWidth-24, pageHeight-24, "D")
+	pdf.SetLineWidth(0.2)
+
+	pdf.SetMargins(25, 18, 25)
+	pdf.SetAutoPageBreak(false, 0)
+	if r.logo(b, pageWidth/2-11, 18, 22) {
+		pdf.SetY(42)
+	} else {
+		pdf.SetY(22)
+	}
+
+	pdf.SetTextColor(30, 58, 138)
+	r.english("B", 18)
+	pdf.CellFormat(0, 9, r.tr(b.UniversityName), "", 1, "C", false, 0, "")
+	r.amharicText(b.UniversityNameAmharic, 14, 7, "C")
+	r.english("", 11)
+	pdf.SetTextColor(60, 60, 60)
+	pdf.CellFormat(0, 6, r.tr(b.OfficeName), "", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	pdf.SetTextColor(30, 58, 138)
+	r.english("B", 26)
+	pdf.CellFormat(0, 12, r.tr(strings.ToUpper(t.Title)), "", 1, "C", false, 0, "")
+	r.amharicText(t.TitleAmharic, 16, 9, "C")
+	pdf.Ln(6)
+
+	pdf.SetTextColor(34, 34, 34)
+	r.english("", 13)
+	pdf.MultiCell(0, 7, r.tr(english), "", "C", false)
+	if r.bilingual {
+		pdf.Ln(3)
+		r.amharicText(amharic, 11, 6, "C")
+	}
+
+	pdf.SetY(pageHeight - 50)
+	r.signature(b, 30, 80)
+
+	pdf.SetXY(pageWidth-110, pageHeight-49)
+	r.english("", 10)
+	pdf.CellFormat(80, 5, "Issued: "+d.IssuedAt.Format("2 January 2006"), "", 2, "R", false, 0, "")
+	pdf.SetX(pageWidth - 110)
+	pdf.CellFormat(80, 5, "Serial No: "+d.Serial, "", 2, "R", false, 0, "")
+
+	r.qr(pageWidth/2-14, pageHeight-54, 28)
+}
@@ -0,0 +1,102 @@
+package documents
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/config"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// RetractionNotice is the public notice published for a retracted paper
+type RetractionNotice struct {
+	PaperTitle      string
+	Authors         []string
+	PublicationID   string
+	JournalName     string
+	DOI             string
+	PublicationDate *time.Time
+	RetractedAt     time.Time
+	Notice          string
+}
+
+// RenderRetractionNotice writes the notice as a PDF with a RETRACTED
+// watermark across every page, for display in place of the paper's usual
+// landing page.
+func RenderRetractionNotice(w io.Writer, n RetractionNotice, b config.DocumentsConfig) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Retraction notice: "+n.PaperTitle, true)
+	pdf.SetCreator(b.OfficeName, true)
+	pdf.SetHeaderFunc(func() { watermark(pdf, "RETRACTED") })
+	pdf.SetAutoPageBreak(true, 20)
+
+	r := &renderer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.AddPage()
+	r.letterhead(b)
+	pdf.Ln(10)
+
+	pdf.SetTextColor(185, 28, 28)
+	r.english("B", 18)
+	pdf.CellFormat(0, 10, "Retraction Notice", "", 1, "C", false, 0, "")
+	pdf.SetTextColor(34, 34, 34)
+	pdf.Ln(4)
+
+	r.english("B", 12)
+	pdf.MultiCell(0, 6, r.tr(n.PaperTitle), "", "L", false)
+	r.english("", 10)
+	if len(n.Authors) > 0 {
+		pdf.MultiCell(0, 5, r.tr(strings.Join(n.Authors, ", ")), "", "L", false)
+	}
+	var details []string
+	if n.JournalName != "" {
+		details = append(details, n.JournalName)
+	}
+	if n.PublicationDate != nil {
+		details = append(details, "published "+n.PublicationDate.Format("2 January 2006"))
+	}
+	if n.PublicationID != "" {
+		details = append(details, n.PublicationID)
+	}
+	if n.DOI != "" {
+		details = append(details, "doi:"+n.DOI)
+	}
+	if len(details) > 0 {
+		pdf.SetTextColor(102, 102, 102)
+		pdf.MultiCell(0, 5, r.tr(strings.Join(details, ", ")), "", "L", false)
+		pdf.SetTextColor(34, 34, 34)
+	}
+	pdf.Ln(6)
+
+	r.english("", 11)
+	pdf.MultiCell(0, 6, r.tr(fmt.Sprintf("This paper was retracted on %s.", n.RetractedAt.Format("2 January 2006"))), "", "L", false)
+	pdf.Ln(3)
+	pdf.MultiCell(0, 6, r.tr(n.Notice), "", "L", false)
+	pdf.Ln(8)
+
+	r.english("I", 9)
+	pdf.SetTextColor(102, 102, 102)
+	pdf.MultiCell(0, 5, "The paper remains available for the scholarly record but should not be cited as valid work.", "", "L", false)
+	return pdf.Output(w)
+}
+
+// watermark draws text diagonally across the middle of the current page
+func watermark(pdf *gofpdf.Fpdf, text string) {
+	pageWidth, pageHeight := pdf.GetPageSize()
+	x, y := pdf.GetXY()
+
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, pageWidth/2, pageHeight/2)
+	pdf.SetAlpha(0.12, "Normal")
+	pdf.SetFont("Helvetica", "B", 90)
+	pdf.SetTextColor(185, 28, 28)
+	width := pdf.GetStringWidth(text)
+	pdf.Text(pageWidth/2-width/2, pageHeight/2+10, text)
+	pdf.SetAlpha(1, "Normal")
+	pdf.TransformEnd()
+
+	pdf.SetTextColor(34, 34, 34)
+	pdf.SetXY(x, y)
+}
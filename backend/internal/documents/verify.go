@@ -0,0 +1,42 @@
+package documents
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Verification outcomes reported by the public verification endpoint
+const (
+	StatusAuthentic = "authentic"
+	StatusRevoked   = "revoked"
+	StatusInvalid   = "invalid"
+)
+
+// Sign computes the HMAC-SHA256 over a document's key fields. Any change to
+// the kind, serial, recipient, paper title or issue date changes the signature.
+func Sign(key []byte, kind, serial, recipient, paperTitle string, issuedAt time.Time) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{
+		kind,
+		serial,
+		recipient,
+		paperTitle,
+		issuedAt.UTC().Format("2006-01-02"),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckSignature reports whether signature matches the document's key fields
+func CheckSignature(key []byte, signature, kind, serial, recipient, paperTitle string, issuedAt time.Time) bool {
+	expected := Sign(key, kind, serial, recipient, paperTitle, issuedAt)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// VerifyURL is the address printed in a document's QR code
+func VerifyURL(base, serial, signature string) string {
+	return strings.TrimRight(base, "/") + "/" + url.PathEscape(serial) + "?sig=" + signature
+}
@@ -1,34 +1,72 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	"html"
+	"net/mail"
 	"net/smtp"
 	"rpms-backend/internal/config"
+	"time"
 )
 
 type EmailSender struct {
 	config *config.Config
+	sender Sender
+}
+
+// Sender is the name and address mail appears to come from. Mail is still
+// delivered through the configured SMTP account.
+type Sender struct {
+	Name    string
+	Address string
 }
 
 func NewEmailSender(cfg *config.Config) *EmailSender {
 	return &EmailSender{config: cfg}
 }
 
+type senderKey struct{}
+
+// WithSender returns a context asking mail sent on its behalf to be signed
+// as the sender
+func WithSender(ctx context.Context, sender Sender) context.Context {
+	return context.WithValue(ctx, senderKey{}, sender)
+}
+
+// ForContext returns the sender signing as the context's sender, if it has one
+func (s *EmailSender) ForContext(ctx context.Context) *EmailSender {
+	if sender, ok := ctx.Value(senderKey{}).(Sender); ok {
+		return s.From(sender)
+	}
+	return s
+}
+
+// From returns a copy of the sender that signs mail as the given sender.
+// Empty fields keep the SMTP account's address and the RPMS name.
+func (s *EmailSender) From(sender Sender) *EmailSender {
+	return &EmailSender{config: s.config, sender: sender}
+}
+
+func (s *EmailSender) fromHeader() string {
+	name := s.sender.Name
+	if name == "" {
+		name = "RPMS"
+	}
+	address := s.sender.Address
+	if address == "" {
+		address = s.config.SMTP.Email
+	}
+	return (&mail.Address{Name: name, Address: address}).String()
+}
+
 func (s *EmailSender) SendVerificationEmail(toEmail, code string) error {
 	// If SMTP credentials are not set, fallback to logging (or return error)
-	if s.config.SMTP.Email == "" || s.config.SMTP.Password == "" {
+	if !s.configured() {
 		fmt.Printf("SMTP credentials not set. Mocking email to %s with code %s\n", toEmail, code)
 		return nil
 	}
 
-	from := s.config.SMTP.Email
-	password := s.config.SMTP.Password
-	host := s.config.SMTP.Host
-	port := s.config.SMTP.Port
-	address := host + ":" + port
-
-	subject := "Subject: Verify your RPMS Account\n"
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
 	body := fmt.Sprintf(`
 		<html>
 			<body>
@@ -40,7 +78,117 @@ func (s *EmailSender) SendVerificationEmail(toEmail, code string) error {
 		</html>
 	`, code)
 
-	message := []byte(subject + mime + body)
+	return s.send(toEmail, "Verify your RPMS Account", body)
+}
+
+// SendPasswordResetEmail sends the link that lets the recipient choose a new
+// password
+func (s *EmailSender) SendPasswordResetEmail(toEmail, resetURL string, validFor time.Duration) error {
+	if !s.configured() {
+		fmt.Printf("SMTP credentials not set. Mocking password reset email to %s with link %s\n", toEmail, resetURL)
+		return nil
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>Reset your RPMS password</h2>
+				<p>Someone asked to reset the password of this account. Follow the link below to choose a new one:</p>
+				<p><a href="%s">Reset password</a></p>
+				<p>The link can be used once and expires in %s.</p>
+				<p>If you did not request this, please ignore this email; your password stays the same.</p>
+			</body>
+		</html>
+	`, html.EscapeString(resetURL), validFor)
+
+	return s.send(toEmail, "Reset your RPMS password", body)
+}
+
+// SendInvitationEmail invites a new staff member to choose a password and
+// complete their profile
+func (s *EmailSender) SendInvitationEmail(toEmail, name, setPasswordURL string, validFor time.Duration) error {
+	if !s.configured() {
+		fmt.Printf("SMTP credentials not set. Mocking invitation email to %s with link %s\n", toEmail, setPasswordURL)
+		return nil
+	}
+
+	greeting := "Welcome to RPMS"
+	if name != "" {
+		greeting += ", " + html.EscapeString(name)
+	}
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>%s</h2>
+				<p>You have been invited to RPMS. Follow the link below to choose your password and sign in:</p>
+				<p><a href="%s">Accept invitation</a></p>
+				<p>The link can be used once and expires in %s.</p>
+			</body>
+		</html>
+	`, greeting, html.EscapeString(setPasswordURL), validFor)
+
+	return s.send(toEmail, "You are invited to RPMS", body)
+}
+
+// SendForcedPasswordResetEmail tells the owner of an account that an admin
+// reset its password, with the link to choose a new one
+func (s *EmailSender) SendForcedPasswordResetEmail(toEmail, resetURL string, validFor time.Duration) error {
+	if !s.configured() {
+		fmt.Printf("SMTP credentials not set. Mocking forced password reset email to %s with link %s\n", toEmail, resetURL)
+		return nil
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>Your RPMS password was reset</h2>
+				<p>An administrator reset the password of this account and signed it out everywhere. Follow the link below to choose a new password:</p>
+				<p><a href="%s">Choose a new password</a></p>
+				<p>The link can be used once and expires in %s.</p>
+			</body>
+		</html>
+	`, html.EscapeString(resetURL), validFor)
+
+	return s.send(toEmail, "Your RPMS password was reset", body)
+}
+
+// SendAccountLockedEmail tells the owner of an account that it was locked
+// after repeated failed sign-ins
+func (s *EmailSender) SendAccountLockedEmail(toEmail string, until time.Time) error {
+	if !s.configured() {
+		fmt.Printf("SMTP credentials not set. Mocking account locked email to %s\n", toEmail)
+		return nil
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+			<body>
+				<h2>Your RPMS account was locked</h2>
+				<p>There were too many failed attempts to sign in to this account, so sign-in is blocked until %s.</p>
+				<p>If this was not you, someone may be guessing your password. Consider resetting it once the lock ends.</p>
+			</body>
+		</html>
+	`, until.UTC().Format("2 Jan 2006 15:04 MST"))
+
+	return s.send(toEmail, "Your RPMS account was locked", body)
+}
+
+func (s *EmailSender) configured() bool {
+	return s.config.SMTP.Email != "" && s.config.SMTP.Password != ""
+}
+
+func (s *EmailSender) send(toEmail, subject, body string) error {
+	from := s.config.SMTP.Email
+	password := s.config.SMTP.Password
+	host := s.config.SMTP.Host
+	port := s.config.SMTP.Port
+	address := host + ":" + port
+
+	header := "From: " + s.fromHeader() + "\n"
+	header += "Subject: " + subject + "\n"
+	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+
+	message := []byte(header + mime + body)
 
 	auth := smtp.PlainAuth("", from, password, host)
 
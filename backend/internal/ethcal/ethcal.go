@@ -0,0 +1,162 @@
+package ethcal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ethiopian calendar (Amete Mihret era) conversion.
+// Conversions go through the Julian Day Number so they work for any date.
+
+const (
+	// jdnEpochOffset is the JDN of the day before Meskerem 1, year 1.
+	jdnEpochOffset = 1724220
+	// jdnUnixEpoch is the JDN of 1970-01-01.
+	jdnUnixEpoch = 2440588
+
+	// FiscalYearStartMonth is Hamle, the month the Ethiopian fiscal year starts on.
+	FiscalYearStartMonth = 11
+
+	// CalendarGregorian and CalendarEthiopian are the accepted values of the
+	// "calendar" API parameter.
+	CalendarGregorian = "gregorian"
+	CalendarEthiopian = "ethiopian"
+)
+
+var monthNames = []string{
+	"Meskerem", "Tikimt", "Hidar", "Tahsas", "Tir", "Yekatit",
+	"Megabit", "Miazia", "Ginbot", "Sene", "Hamle", "Nehase", "Pagume",
+}
+
+var amharicMonthNames = []string{
+	"መስከረም", "ጥቅምት", "ኅዳር", "ታኅሣሥ", "ጥር", "የካቲት",
+	"መጋቢት", "ሚያዝያ", "ግንቦት", "ሰኔ", "ሐምሌ", "ነሐሴ", "ጳጉሜ",
+}
+
+// Date is a day in the Ethiopian calendar. Month runs 1-13, where 13 is Pagume.
+type Date struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+// IsLeapYear reports whether the Ethiopian year has a 6-day Pagume.
+func IsLeapYear(year int) bool {
+	return year%4 == 3
+}
+
+// DaysInMonth returns the number of days in the given Ethiopian month.
+func DaysInMonth(year, month int) int {
+	if month == 13 {
+		if IsLeapYear(year) {
+			return 6
+		}
+		return 5
+	}
+	return 30
+}
+
+// Valid reports whether d is a real Ethiopian calendar day.
+func (d Date) Valid() bool {
+	if d.Year < 1 || d.Month < 1 || d.Month > 13 || d.Day < 1 {
+		return false
+	}
+	return d.Day <= DaysInMonth(d.Year, d.Month)
+}
+
+// MonthName returns the English transliteration of the month name.
+func (d Date) MonthName() string {
+	if d.Month < 1 || d.Month > 13 {
+		return ""
+	}
+	return monthNames[d.Month-1]
+}
+
+// AmharicMonthName returns the month name in Ge'ez script.
+func (d Date) AmharicMonthName() string {
+	if d.Month < 1 || d.Month > 13 {
+		return ""
+	}
+	return amharicMonthNames[d.Month-1]
+}
+
+// AmharicString formats the date the way it is written in Amharic letters,
+// e.g. "መስከረም 12 ቀን 2017 ዓ.ም".
+func (d Date) AmharicString() string {
+	return fmt.Sprintf("%s %d ቀን %d ዓ.ም", d.AmharicMonthName(), d.Day, d.Year)
+}
+
+// String formats the date as YYYY-MM-DD.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Parse reads an Ethiopian date in YYYY-MM-DD form.
+func Parse(s string) (Date, error) {
+	var d Date
+	if _, err := fmt.Sscanf(s, "%d-%d-%d", &d.Year, &d.Month, &d.Day); err != nil {
+		return Date{}, fmt.Errorf("invalid ethiopian date %q: expected YYYY-MM-DD", s)
+	}
+	if !d.Valid() {
+		return Date{}, fmt.Errorf("invalid ethiopian date %q", s)
+	}
+	return d, nil
+}
+
+func (d Date) jdn() int {
+	return jdnEpochOffset + 365*(d.Year-1) + d.Year/4 + 30*(d.Month-1) + d.Day
+}
+
+func fromJDN(jdn int) Date {
+	n := jdn - jdnEpochOffset - 1
+	// Each 4-year cycle is 365+365+366+365 days; the third year has Pagume 6.
+	cycle, r := n/1461, n%1461
+	var yearInCycle, dayOfYear int
+	switch {
+	case r < 730:
+		yearInCycle, dayOfYear = r/365, r%365
+	case r < 1096:
+		yearInCycle, dayOfYear = 2, r-730
+	default:
+		yearInCycle, dayOfYear = 3, r-1096
+	}
+	return Date{
+		Year:  4*cycle + yearInCycle + 1,
+		Month: dayOfYear/30 + 1,
+		Day:   dayOfYear%30 + 1,
+	}
+}
+
+// FromGregorian converts the calendar day of t (in t's location) to an Ethiopian date.
+func FromGregorian(t time.Time) Date {
+	utc := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return fromJDN(int(utc.Unix()/86400) + jdnUnixEpoch)
+}
+
+// ToGregorian returns midnight UTC of the Gregorian day matching d.
+func (d Date) ToGregorian() time.Time {
+	return time.Unix(int64(d.jdn()-jdnUnixEpoch)*86400, 0).UTC()
+}
+
+// FiscalYear returns the Ethiopian fiscal year (E.F.Y.) that t falls in.
+// A fiscal year starts on Hamle 1 and is named after the year it ends in.
+func FiscalYear(t time.Time) int {
+	d := FromGregorian(t)
+	if d.Month >= FiscalYearStartMonth {
+		return d.Year + 1
+	}
+	return d.Year
+}
+
+// FiscalYearLabel formats a fiscal year the way it is stored on papers.
+func FiscalYearLabel(year int) string {
+	return fmt.Sprintf("%d EFY", year)
+}
+
+// FiscalYearBounds returns the first day of the fiscal year and the first day
+// of the following one, both as Gregorian midnights in UTC.
+func FiscalYearBounds(year int) (time.Time, time.Time) {
+	start := Date{Year: year - 1, Month: FiscalYearStartMonth, Day: 1}
+	end := Date{Year: year, Month: FiscalYearStartMonth, Day: 1}
+	return start.ToGregorian(), end.ToGregorian()
+}
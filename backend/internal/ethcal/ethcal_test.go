@@ -0,0 +1,80 @@
+package ethcal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConversionKnownDates(t *testing.T) {
+	cases := []struct {
+		gregorian string
+		ethiopian Date
+	}{
+		{"2024-09-11", Date{2017, 1, 1}},  // Enkutatash 2017
+		{"2023-09-12", Date{2016, 1, 1}},  // Enkutatash after Pagume 6
+		{"2023-09-11", Date{2015, 13, 6}}, // Pagume 6, leap year
+		{"2024-07-08", Date{2016, 11, 1}}, // Hamle 1, start of 2017 EFY
+		{"2025-01-07", Date{2017, 4, 29}}, // Genna
+		{"2000-01-01", Date{1992, 4, 22}},
+	}
+
+	for _, tc := range cases {
+		g, _ := time.Parse("2006-01-02", tc.gregorian)
+		if got := FromGregorian(g); got != tc.ethiopian {
+			t.Errorf("FromGregorian(%s) = %v, want %v", tc.gregorian, got, tc.ethiopian)
+		}
+		if got := tc.ethiopian.ToGregorian(); !got.Equal(g) {
+			t.Errorf("ToGregorian(%v) = %s, want %s", tc.ethiopian, got.Format("2006-01-02"), tc.gregorian)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	start := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 365*40; i++ {
+		g := start.AddDate(0, 0, i)
+		d := FromGregorian(g)
+		if !d.Valid() {
+			t.Fatalf("FromGregorian(%s) produced invalid date %v", g.Format("2006-01-02"), d)
+		}
+		if back := d.ToGregorian(); !back.Equal(g) {
+			t.Fatalf("round trip %s -> %v -> %s", g.Format("2006-01-02"), d, back.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestFiscalYear(t *testing.T) {
+	cases := []struct {
+		date string
+		want int
+	}{
+		{"2024-07-07", 2016}, // Sene 30, 2016
+		{"2024-07-08", 2017}, // Hamle 1, 2016
+		{"2024-09-11", 2017},
+		{"2025-07-07", 2017},
+		{"2025-07-08", 2018},
+	}
+	for _, tc := range cases {
+		d, _ := time.Parse("2006-01-02", tc.date)
+		if got := FiscalYear(d); got != tc.want {
+			t.Errorf("FiscalYear(%s) = %d, want %d", tc.date, got, tc.want)
+		}
+	}
+
+	start, end := FiscalYearBounds(2017)
+	if start.Format("2006-01-02") != "2024-07-08" || end.Format("2006-01-02") != "2025-07-08" {
+		t.Errorf("FiscalYearBounds(2017) = %s, %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("2015-13-06"); err != nil {
+		t.Errorf("Parse leap Pagume 6: %v", err)
+	}
+	if _, err := Parse("2017-13-06"); err == nil {
+		t.Error("Parse accepted Pagume 6 in a non-leap year")
+	}
+	if _, err := Parse("2017-02-31"); err == nil {
+		t.Error("Parse accepted day 31")
+	}
+}
@@ -0,0 +1,298 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rpms-backend/internal/models"
+)
+
+// Citation formats understood by Citations
+const (
+	FormatBibTeX  = "bibtex"
+	FormatRIS     = "ris"
+	FormatCSLJSON = "csl-json"
+)
+
+// ContentType returns the MIME type to serve a citation format with
+func ContentType(format string) string {
+	switch format {
+	case FormatBibTeX:
+		return "application/x-bibtex; charset=utf-8"
+	case FormatRIS:
+		return "application/x-research-info-systems; charset=utf-8"
+	case FormatCSLJSON:
+		return "application/vnd.citationstyles.csl+json; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// FileExtension returns the usual extension for a citation format
+func FileExtension(format string) string {
+	switch format {
+	case FormatBibTeX:
+		return ".bib"
+	case FormatRIS:
+		return ".ris"
+	}
+	return ".json"
+}
+
+// Citations renders records in one of the supported formats
+func Citations(format string, records []models.RepositoryRecord) ([]byte, error) {
+	switch format {
+	case FormatBibTeX:
+		var sb strings.Builder
+		for i, rec := range records {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(BibTeX(rec))
+		}
+		return []byte(sb.String()), nil
+	case FormatRIS:
+		var sb strings.Builder
+		for _, rec := range records {
+			sb.WriteString(RIS(rec))
+		}
+		return []byte(sb.String()), nil
+	case FormatCSLJSON:
+		items := make([]map[string]interface{}, 0, len(records))
+		for _, rec := range records {
+			items = append(items, CSL(rec))
+		}
+		return json.MarshalIndent(items, "", "  ")
+	}
+	return nil, fmt.Errorf("unsupported citation format %q: use bibtex, ris or csl-json", format)
+}
+
+var nonKeyChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func citationKey(rec models.RepositoryRecord) string {
+	key := rec.PublicationID
+	if key == "" {
+		key = rec.ID.String()[:8]
+	}
+	if len(rec.Authors) > 0 {
+		parts := strings.Fields(rec.Authors[0].Name)
+		if len(parts) > 0 {
+			key = parts[len(parts)-1] + "_" + key
+		}
+	}
+	return nonKeyChars.ReplaceAllString(key, "_")
+}
+
+// citationTitle flags retracted papers in the title, as indexers do, so the
+// retraction is not lost when the citation is copied
+func citationTitle(rec models.RepositoryRecord) string {
+	if rec.Retracted {
+		return "RETRACTED: " + rec.Title
+	}
+	return rec.Title
+}
+
+// retractionNote describes the retraction for the format's note field
+func retractionNote(rec models.RepositoryRecord) string {
+	if !rec.Retracted {
+		return ""
+	}
+	note := "Retracted"
+	if rec.RetractedAt != nil {
+		note += " on " + rec.RetractedAt.Format("2006-01-02")
+	}
+	if rec.RetractionNotice != "" {
+		note += ": " + rec.RetractionNotice
+	}
+	return note
+}
+
+func bibtexEscape(s string) string {
+	return strings.NewReplacer("{", "\\{", "}", "\\}", "&", "\\&", "%", "\\%", "$", "\\$", "#", "\\#", "_", "\\_").Replace(s)
+}
+
+// BibTeX renders a record as a BibTeX entry. ORCID iDs go in the orcid field
+// as "Name: iD" pairs, following the biblatex convention.
+func BibTeX(rec models.RepositoryRecord) string {
+	entryType := "misc"
+	switch rec.PublicationType {
+	case "Journal Article":
+		entryType = "article"
+	case "Conference Paper":
+		entryType = "inproceedings"
+	case "Book":
+		entryType = "book"
+	case "Book Chapter":
+		entryType = "incollection"
+	case "Thesis":
+		entryType = "phdthesis"
+	}
+
+	var names, orcids []string
+	for _, a := range rec.Authors {
+		names = append(names, bibtexEscape(a.Name))
+		if a.ORCID != "" {
+			orcids = append(orcids, bibtexEscape(a.Name)+": "+a.ORCID)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@%s{%s,\n", entryType, citationKey(rec))
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "  %s = {%s},\n", name, value)
+		}
+	}
+	field("title", bibtexEscape(citationTitle(rec)))
+	field("author", strings.Join(names, " and "))
+	field("orcid", strings.Join(orcids, ", "))
+	switch entryType {
+	case "article":
+		field("journal", bibtexEscape(rec.JournalName))
+	case "inproceedings", "incollection":
+		field("booktitle", bibtexEscape(rec.JournalName))
+	default:
+		field("howpublished", bibtexEscape(rec.JournalName))
+	}
+	field("volume", rec.Volume)
+	field("number", rec.Issue)
+	field("pages", strings.Replace(rec.Pages, "-", "--", 1))
+	if rec.PublicationDate != nil {
+		field("year", fmt.Sprintf("%d", rec.PublicationDate.Year()))
+		field("month", strings.ToLower(rec.PublicationDate.Month().String()[:3]))
+	}
+	field("doi", rec.DOI)
+	field("abstract", bibtexEscape(rec.Abstract))
+	note := rec.PublicationID
+	if rec.Retracted {
+		note = strings.TrimPrefix(note+". "+bibtexEscape(retractionNote(rec)), ". ")
+	}
+	field("note", note)
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// RIS renders a record in the RIS tagged format. RIS has no ORCID tag, so
+// each author's iD is written as a C1 (author note) line.
+func RIS(rec models.RepositoryRecord) string {
+	risType := "GEN"
+	switch rec.PublicationType {
+	case "Journal Article":
+		risType = "JOUR"
+	case "Conference Paper":
+		risType = "CPAPER"
+	case "Book":
+		risType = "BOOK"
+	case "Book Chapter":
+		risType = "CHAP"
+	case "Thesis":
+		risType = "THES"
+	}
+
+	var sb strings.Builder
+	line := func(tag, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "%s  - %s\n", tag, strings.ReplaceAll(value, "\n", " "))
+		}
+	}
+	line("TY", risType)
+	line("TI", citationTitle(rec))
+	for _, a := range rec.Authors {
+		line("AU", a.Name)
+	}
+	for _, a := range rec.Authors {
+		if a.ORCID != "" {
+			line("C1", a.Name+" ORCID: "+a.ORCIDURI)
+		}
+	}
+	line("T2", rec.JournalName)
+	line("VL", rec.Volume)
+	line("IS", rec.Issue)
+	if start, end, ok := strings.Cut(rec.Pages, "-"); ok {
+		line("SP", start)
+		line("EP", end)
+	}
+	if rec.PublicationDate != nil {
+		line("PY", fmt.Sprintf("%d", rec.PublicationDate.Year()))
+		line("DA", rec.PublicationDate.Format("2006/01/02"))
+	}
+	line("DO", rec.DOI)
+	line("AB", rec.Abstract)
+	line("ID", rec.PublicationID)
+	line("UR", rec.FileUrl)
+	line("N1", retractionNote(rec))
+	sb.WriteString("ER  - \n")
+	return sb.String()
+}
+
+// CSL renders a record as a CSL-JSON item. Author ORCID iDs use the
+// ORCID field supported by citeproc processors.
+func CSL(rec models.RepositoryRecord) map[string]interface{} {
+	cslType := "document"
+	switch rec.PublicationType {
+	case "Journal Article":
+		cslType = "article-journal"
+	case "Conference Paper":
+		cslType = "paper-conference"
+	case "Book":
+		cslType = "book"
+	case "Book Chapter":
+		cslType = "chapter"
+	case "Thesis":
+		cslType = "thesis"
+	}
+
+	authors := make([]map[string]string, 0, len(rec.Authors))
+	for _, a := range rec.Authors {
+		author := map[string]string{"literal": a.Name}
+		if parts := strings.Fields(a.Name); len(parts) > 1 {
+			author = map[string]string{
+				"given":  strings.Join(parts[:len(parts)-1], " "),
+				"family": parts[len(parts)-1],
+			}
+		}
+		if a.ORCID != "" {
+			author["ORCID"] = a.ORCIDURI
+		}
+		authors = append(authors, author)
+	}
+
+	item := map[string]interface{}{
+		"id":     citationKey(rec),
+		"type":   cslType,
+		"title":  citationTitle(rec),
+		"author": authors,
+	}
+	if rec.JournalName != "" {
+		item["container-title"] = rec.JournalName
+	}
+	if rec.PublicationDate != nil {
+		d := rec.PublicationDate
+		item["issued"] = map[string]interface{}{
+			"date-parts": [][]int{{d.Year(), int(d.Month()), d.Day()}},
+		}
+	}
+	if rec.Volume != "" {
+		item["volume"] = rec.Volume
+	}
+	if rec.Issue != "" {
+		item["issue"] = rec.Issue
+	}
+	if rec.Pages != "" {
+		item["page"] = rec.Pages
+	}
+	if rec.DOI != "" {
+		item["DOI"] = rec.DOI
+	}
+	if rec.Abstract != "" {
+		item["abstract"] = rec.Abstract
+	}
+	if rec.PublicationID != "" {
+		item["number"] = rec.PublicationID
+	}
+	if rec.Retracted {
+		item["note"] = retractionNote(rec)
+	}
+	return item
+}
@@ -0,0 +1,531 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/ethcal"
+	"rpms-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PaperFields lists the target fields a column can be mapped to.
+// They match the JSON names of the paper model, plus the author columns.
+var PaperFields = []string{
+	"title", "abstract", "type", "status", "submission_date",
+	"author_email", "author_name",
+	"institution_code", "publication_id", "publication_isced_band", "publication_title_amharic",
+	"publication_date", "publication_type", "journal_type", "journal_name", "indigenous_knowledge",
+	"fiscal_year", "allocated_budget", "external_budget", "nrf_fund", "research_type",
+	"completion_status", "female_researchers", "male_researchers", "outside_female_researchers",
+	"outside_male_researchers", "benefited_industry", "ethical_clearance", "pi_name", "pi_gender",
+	"co_investigators", "produced_prototype", "hetril_collaboration", "submitted_to_incubator",
+}
+
+// ErrInvalidOptions is returned when the options or column mapping do not fit the file
+var ErrInvalidOptions = errors.New("invalid import options")
+
+// Options controls how a table is turned into papers
+type Options struct {
+	// Mapping maps a target field to the spreadsheet column holding it.
+	// Fields without an entry are read from a column with the same name.
+	Mapping map[string]string `json:"mapping"`
+	// Calendar is the calendar dates in the file are written in
+	Calendar string `json:"calendar"`
+	// DefaultStatus is used for rows without a status column
+	DefaultStatus string `json:"default_status"`
+}
+
+// RowResult is the outcome for one spreadsheet row
+type RowResult struct {
+	Row           int      `json:"row"`
+	Title         string   `json:"title"`
+	AuthorEmail   string   `json:"author_email"`
+	AuthorExists  bool     `json:"author_exists"`
+	PublicationID string   `json:"publication_id,omitempty"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Report summarises a dry run or an import
+type Report struct {
+	DryRun             bool        `json:"dry_run"`
+	TotalRows          int         `json:"total_rows"`
+	ValidRows          int         `json:"valid_rows"`
+	InvalidRows        int         `json:"invalid_rows"`
+	Imported           int         `json:"imported"`
+	PlaceholderAuthors int         `json:"placeholder_authors"`
+	Rows               []RowResult `json:"rows"`
+}
+
+type paperRow struct {
+	paper       models.Paper
+	authorEmail string
+	authorName  string
+	submittedAt *time.Time
+}
+
+// ImportPapers validates every row of the table and, unless dryRun is set,
+// inserts the papers in a single transaction. Nothing is written when any row is invalid.
+func ImportPapers(ctx context.Context, db *database.Database, table *Table, opts Options, dryRun bool) (*Report, error) {
+	if opts.DefaultStatus == "" {
+		opts.DefaultStatus = "published"
+	}
+	if opts.Calendar == "" {
+		opts.Calendar = ethcal.CalendarGregorian
+	}
+	if opts.Calendar != ethcal.CalendarGregorian && opts.Calendar != ethcal.CalendarEthiopian {
+		return nil, fmt.Errorf("%w: calendar must be gregorian or ethiopian", ErrInvalidOptions)
+	}
+	if err := checkStatus(opts.DefaultStatus); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+
+	columns, err := resolveColumns(table.Header, opts.Mapping, PaperFields, "title", "author_email")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: dryRun, TotalRows: len(table.Rows)}
+	parsed := make([]paperRow, len(table.Rows))
+	seenIDs := map[string]int{}
+	for i, record := range table.Rows {
+		result := RowResult{Row: i + 2}
+		row, errs := parseRow(record, columns, opts)
+		if row.paper.PublicationID != "" {
+			if first, ok := seenIDs[row.paper.PublicationID]; ok {
+				errs = append(errs, fmt.Sprintf("publication_id duplicates row %d", first))
+			} else {
+				seenIDs[row.paper.PublicationID] = result.Row
+			}
+		}
+		result.Title = row.paper.Title
+		result.AuthorEmail = row.authorEmail
+		result.PublicationID = row.paper.PublicationID
+		result.Errors = errs
+		parsed[i] = row
+		report.Rows = append(report.Rows, result)
+	}
+
+	if err := checkExisting(ctx, db, report, parsed); err != nil {
+		return nil, err
+	}
+
+	for _, result := range report.Rows {
+		if len(result.Errors) > 0 {
+			report.InvalidRows++
+		} else {
+			report.ValidRows++
+		}
+	}
+
+	if dryRun || report.InvalidRows > 0 {
+		return report, nil
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	authors := map[string]uuid.UUID{}
+	for i, row := range parsed {
+		authorID, ok := authors[row.authorEmail]
+		if !ok {
+			var created bool
+			authorID, created, err = findOrCreateAuthor(ctx, tx, row.authorEmail, row.authorName)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", report.Rows[i].Row, err)
+			}
+			if created {
+				report.PlaceholderAuthors++
+			}
+			authors[row.authorEmail] = authorID
+		}
+
+		row.paper.AuthorID = authorID
+		if err := insertPaper(ctx, tx, &row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", report.Rows[i].Row, err)
+		}
+		report.Imported++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return report, nil
+}
+
+// resolveColumns maps each target field to its column index in the header
+func resolveColumns(header []string, mapping map[string]string, fields []string, required ...string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		index[normalizeHeader(h)] = i
+	}
+
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidOptions, field)
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range fields {
+		source, mapped := mapping[field]
+		if !mapped {
+			source = field
+		}
+		if i, ok := index[normalizeHeader(source)]; ok {
+			columns[field] = i
+		} else if mapped {
+			return nil, fmt.Errorf("%w: column %q mapped to %s is not in the file", ErrInvalidOptions, source, field)
+		}
+	}
+
+	for _, field := range required {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: no column for required field %s", ErrInvalidOptions, field)
+		}
+	}
+	return columns, nil
+}
+
+func normalizeHeader(h string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), " ", "_")
+}
+
+func parseRow(record []string, columns map[string]int, opts Options) (paperRow, []string) {
+	var errs []string
+	get := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(field string) float64 {
+		v, err := parseNumber(get(field))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+		}
+		return v
+	}
+	count := func(field string) int {
+		v := number(field)
+		if v != math.Trunc(v) {
+			errs = append(errs, fmt.Sprintf("%s: must be a whole number", field))
+		}
+		return int(v)
+	}
+	date := func(field string) *time.Time {
+		raw := get(field)
+		if raw == "" {
+			return nil
+		}
+		t, err := parseDate(raw, opts.Calendar)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			return nil
+		}
+		return &t
+	}
+
+	row := paperRow{
+		authorEmail: strings.ToLower(get("author_email")),
+		authorName:  get("author_name"),
+		submittedAt: date("submission_date"),
+	}
+	p := &row.paper
+	p.Title = get("title")
+	p.Abstract = get("abstract")
+	p.Type = get("type")
+	p.Status = get("status")
+	p.InstitutionCode = get("institution_code")
+	p.PublicationID = get("publication_id")
+	p.PublicationTitleAmharic = get("publication_title_amharic")
+	p.PublicationDate = date("publication_date")
+	p.PublicationType = get("publication_type")
+	p.JournalType = get("journal_type")
+	p.JournalName = get("journal_name")
+	p.FiscalYear = get("fiscal_year")
+	p.AllocatedBudget = number("allocated_budget")
+	p.ExternalBudget = number("external_budget")
+	p.NRFFund = number("nrf_fund")
+	p.ResearchType = get("research_type")
+	p.CompletionStatus = get("completion_status")
+	p.FemaleResearchers = count("female_researchers")
+	p.MaleResearchers = count("male_researchers")
+	p.OutsideFemaleResearchers = count("outside_female_researchers")
+	p.OutsideMaleResearchers = count("outside_male_researchers")
+	p.BenefitedIndustry = get("benefited_industry")
+	p.EthicalClearance = get("ethical_clearance")
+	p.PIName = get("pi_name")
+	p.PIGender = get("pi_gender")
+	p.CoInvestigators = get("co_investigators")
+	p.ProducedPrototype = get("produced_prototype")
+	p.HetrilCollaboration = get("hetril_collaboration")
+	p.SubmittedToIncubator = get("submitted_to_incubator")
+
+	if p.Title == "" {
+		errs = append(errs, "title: required")
+	} else if len(p.Title) > 500 {
+		errs = append(errs, "title: longer than 500 characters")
+	}
+
+	if row.authorEmail == "" {
+		errs = append(errs, "author_email: required")
+	} else if _, err := mail.ParseAddress(row.authorEmail); err != nil {
+		errs = append(errs, "author_email: not a valid email address")
+	}
+
+	if p.Type == "" {
+		p.Type = "Research Paper"
+	}
+	if p.Status == "" {
+		p.Status = opts.DefaultStatus
+	}
+	if err := checkStatus(p.Status); err != nil {
+		errs = append(errs, fmt.Sprintf("status: %v", err))
+	}
+
+	if band := get("publication_isced_band"); band != "" {
+		code, err := NormalizeISCEDBand(band)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("publication_isced_band: %v", err))
+		}
+		p.PublicationISCEDBand = code
+	}
+
+	if raw := get("indigenous_knowledge"); raw != "" {
+		v, err := parseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("indigenous_knowledge: %v", err))
+		}
+		p.IndigenousKnowledge = v
+	}
+
+	if p.FiscalYear == "" {
+		switch {
+		case p.PublicationDate != nil:
+			p.FiscalYear = ethcal.FiscalYearLabel(ethcal.FiscalYear(*p.PublicationDate))
+		case row.submittedAt != nil:
+			p.FiscalYear = ethcal.FiscalYearLabel(ethcal.FiscalYear(*row.submittedAt))
+		}
+	}
+
+	return row, errs
+}
+
+// NormalizeISCEDBand accepts "7", "07" or "07 - Engineering, ..." and returns the two digit code
+func NormalizeISCEDBand(raw string) (string, error) {
+	code := strings.TrimSpace(raw)
+	if i := strings.IndexAny(code, " -"); i > 0 {
+		code = code[:i]
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return "", fmt.Errorf("%q is not an ISCED-F broad field code", raw)
+	}
+	code = fmt.Sprintf("%02d", n)
+	if _, ok := models.ISCEDBands[code]; !ok {
+		return "", fmt.Errorf("%q is not an ISCED-F broad field code", raw)
+	}
+	return code, nil
+}
+
+// checkStatus accepts the statuses a paper can be imported in. Withdrawn and
+// retracted papers need a notice, a date and who did it, which a spreadsheet
+// does not carry; they are imported and then withdrawn or retracted in RPMS.
+func checkStatus(status string) error {
+	switch status {
+	case "withdrawn":
+		return errors.New("withdrawn papers cannot be imported; import the paper and withdraw it in RPMS")
+	case "retracted":
+		return errors.New("retracted papers cannot be imported; import the paper and retract it in RPMS")
+	}
+	for _, s := range models.PaperStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a paper status", status)
+}
+
+func parseNumber(raw string) (float64, error) {
+	cleaned := strings.TrimSpace(raw)
+	if strings.HasPrefix(strings.ToUpper(cleaned), "ETB") {
+		cleaned = cleaned[3:]
+	}
+	cleaned = strings.NewReplacer(",", "", " ", "").Replace(cleaned)
+	if cleaned == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", raw)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return v, nil
+}
+
+func parseBool(raw string) (bool, error) {
+	switch strings.ToLower(raw) {
+	case "yes", "y", "true", "1":
+		return true, nil
+	case "no", "n", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not yes or no", raw)
+}
+
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "02/01/2006", "2/1/2006", "2006/01/02"}
+
+// excelEpoch is day zero of spreadsheet serial dates
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func parseDate(raw, calendar string) (time.Time, error) {
+	if calendar == ethcal.CalendarEthiopian {
+		d, err := ethcal.Parse(strings.ReplaceAll(raw, "/", "-"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return d.ToGregorian(), nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil && serial > 0 && serial < 100000 {
+		return excelEpoch.AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (expected YYYY-MM-DD)", raw)
+}
+
+// checkExisting marks rows whose author already has an account and flags
+// publication IDs that are already taken
+func checkExisting(ctx context.Context, db *database.Database, report *Report, rows []paperRow) error {
+	var emails, pubIDs []string
+	for _, row := range rows {
+		if row.authorEmail != "" {
+			emails = append(emails, row.authorEmail)
+		}
+		if row.paper.PublicationID != "" {
+			pubIDs = append(pubIDs, row.paper.PublicationID)
+		}
+	}
+
+	existing := map[string]bool{}
+	dbRows, err := db.Query(ctx, "SELECT LOWER(email) FROM users WHERE LOWER(email) = ANY($1)", emails)
+	if err != nil {
+		return fmt.Errorf("failed to look up authors: %w", err)
+	}
+	for dbRows.Next() {
+		var email string
+		if err := dbRows.Scan(&email); err == nil {
+			existing[email] = true
+		}
+	}
+	dbRows.Close()
+
+	taken := map[string]bool{}
+	dbRows, err = db.Query(ctx, "SELECT publication_id FROM papers WHERE publication_id = ANY($1)", pubIDs)
+	if err != nil {
+		return fmt.Errorf("failed to look up publication IDs: %w", err)
+	}
+	for dbRows.Next() {
+		var id string
+		if err := dbRows.Scan(&id); err == nil {
+			taken[id] = true
+		}
+	}
+	dbRows.Close()
+
+	for i := range report.Rows {
+		report.Rows[i].AuthorExists = existing[rows[i].authorEmail]
+		if taken[rows[i].paper.PublicationID] {
+			report.Rows[i].Errors = append(report.Rows[i].Errors, "publication_id already exists")
+		}
+	}
+	return nil
+}
+
+// findOrCreateAuthor returns the user with the given email, creating an
+// unverified placeholder author when there is none
+func findOrCreateAuthor(ctx context.Context, tx pgx.Tx, email, name string) (uuid.UUID, bool, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, "SELECT id FROM users WHERE LOWER(email) = $1", email).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if err != pgx.ErrNoRows {
+		return uuid.Nil, false, fmt.Errorf("failed to look up author %s: %w", email, err)
+	}
+
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, name, role, is_verified, is_placeholder, preferences)
+		VALUES ($1, '', $2, 'author', FALSE, TRUE, '{}')
+		RETURNING id
+	`, email, name).Scan(&id)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to create placeholder author %s: %w", email, err)
+	}
+	return id, true, nil
+}
+
+func insertPaper(ctx context.Context, tx pgx.Tx, row *paperRow) error {
+	p := &row.paper
+	submittedAt := time.Now()
+	if row.submittedAt != nil {
+		submittedAt = *row.submittedAt
+	}
+
+	var publicationID *string
+	if p.PublicationID != "" {
+		publicationID = &p.PublicationID
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO papers (
+			title, abstract, author_id, status, type, created_at, updated_at,
+			institution_code, publication_id, publication_isced_band, publication_title_amharic,
+			publication_date, publication_type, journal_type, journal_name, indigenous_knowledge,
+			fiscal_year, allocated_budget, external_budget, nrf_fund, research_type, completion_status,
+			female_researchers, male_researchers, outside_female_researchers, outside_male_researchers,
+			benefited_industry, ethical_clearance, pi_name, pi_gender, co_investigators,
+			produced_prototype, hetril_collaboration, submitted_to_incubator
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+				$20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33)
+	`,
+		p.Title, p.Abstract, p.AuthorID, p.Status, p.Type, submittedAt,
+		p.InstitutionCode, publicationID, p.PublicationISCEDBand, p.PublicationTitleAmharic,
+		p.PublicationDate, p.PublicationType, p.JournalType, p.JournalName, p.IndigenousKnowledge,
+		p.FiscalYear, p.AllocatedBudget, p.ExternalBudget, p.NRFFund, p.ResearchType, p.CompletionStatus,
+		p.FemaleResearchers, p.MaleResearchers, p.OutsideFemaleResearchers, p.OutsideMaleResearchers,
+		p.BenefitedIndustry, p.EthicalClearance, p.PIName, p.PIGender, p.CoInvestigators,
+		p.ProducedPrototype, p.HetrilCollaboration, p.SubmittedToIncubator,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert paper %q: %w", p.Title, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"rpms-backend/internal/ethcal"
+)
+
+func TestParseRow(t *testing.T) {
+	header := []string{"Title", "Author Email", "Status", "Publication Date", "Publication ISCED Band", "Allocated Budget", "Female Researchers", "Indigenous Knowledge"}
+	columns, err := resolveColumns(header, nil, PaperFields, "title", "author_email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{DefaultStatus: "published"}
+
+	cases := []struct {
+		name   string
+		record []string
+		errs   []string
+	}{
+		{"valid", []string{"Soil health", "Author@Example.edu", "", "2024-07-08", "07 - Engineering", "ETB 1,500", "2", "yes"}, nil},
+		{"short row", []string{"Soil health", "author@example.edu"}, nil},
+		{"missing title", []string{"", "author@example.edu"}, []string{"title"}},
+		{"long title", []string{strings.Repeat("x", 501), "author@example.edu"}, []string{"title"}},
+		{"missing email", []string{"Soil health", ""}, []string{"author_email"}},
+		{"bad email", []string{"Soil health", "not an email"}, []string{"author_email"}},
+		{"bad status", []string{"Soil health", "author@example.edu", "accepted"}, []string{"status"}},
+		{"withdrawn", []string{"Soil health", "author@example.edu", "withdrawn"}, []string{"status"}},
+		{"retracted", []string{"Soil health", "author@example.edu", "retracted"}, []string{"status"}},
+		{"bad date", []string{"Soil health", "author@example.edu", "", "next week"}, []string{"publication_date"}},
+		{"bad band", []string{"Soil health", "author@example.edu", "", "", "11"}, []string{"publication_isced_band"}},
+		{"negative budget", []string{"Soil health", "author@example.edu", "", "", "", "-5"}, []string{"allocated_budget"}},
+		{"fractional count", []string{"Soil health", "author@example.edu", "", "", "", "", "1.5"}, []string{"female_researchers"}},
+		{"bad flag", []string{"Soil health", "author@example.edu", "", "", "", "", "", "maybe"}, []string{"indigenous_knowledge"}},
+		{"several errors", []string{"", "", "accepted"}, []string{"title", "author_email", "status"}},
+	}
+	for _, c := range cases {
+		_, errs := parseRow(c.record, columns, opts)
+		if len(errs) != len(c.errs) {
+			t.Errorf("%s: errors %v, want fields %v", c.name, errs, c.errs)
+			continue
+		}
+		for i, field := range c.errs {
+			if !strings.HasPrefix(errs[i], field+":") {
+				t.Errorf("%s: error %q, want one for %s", c.name, errs[i], field)
+			}
+		}
+	}
+}
+
+func TestParseRowFillsDefaults(t *testing.T) {
+	header := []string{"title", "author_email", "Status", "Publication Date", "Publication ISCED Band", "Allocated Budget", "Female Researchers", "Indigenous Knowledge"}
+	columns, err := resolveColumns(header, nil, PaperFields, "title", "author_email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, errs := parseRow(
+		[]string{" Soil health ", "Author@Example.edu", "", "2024-07-08", "7", "ETB 1,500.50", "2", "Yes"},
+		columns, Options{DefaultStatus: "published"})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	p := row.paper
+	if p.Title != "Soil health" || row.authorEmail != "author@example.edu" {
+		t.Errorf("title %q, email %q", p.Title, row.authorEmail)
+	}
+	if p.Type != "Research Paper" || p.Status != "published" {
+		t.Errorf("type %q, status %q", p.Type, p.Status)
+	}
+	if p.PublicationISCEDBand != "07" || p.AllocatedBudget != 1500.5 || p.FemaleResearchers != 2 || !p.IndigenousKnowledge {
+		t.Errorf("band %q, budget %v, female researchers %d, indigenous knowledge %v",
+			p.PublicationISCEDBand, p.AllocatedBudget, p.FemaleResearchers, p.IndigenousKnowledge)
+	}
+	if want := ethcal.FiscalYearLabel(2017); p.FiscalYear != want {
+		t.Errorf("fiscal year %q, want %q", p.FiscalYear, want)
+	}
+}
+
+func TestNormalizeISCEDBand(t *testing.T) {
+	cases := []struct {
+		raw  string
+		code string
+		ok   bool
+	}{
+		{"7", "07", true},
+		{"07", "07", true},
+		{" 10 ", "10", true},
+		{"00", "00", true},
+		{"06 - Information and Communication Technologies", "06", true},
+		{"09-Health and welfare", "09", true},
+		{"11", "", false},
+		{"-1", "", false},
+		{"Engineering", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		code, err := NormalizeISCEDBand(c.raw)
+		if (err == nil) != c.ok || code != c.code {
+			t.Errorf("NormalizeISCEDBand(%q) = %q, %v", c.raw, code, err)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		raw string
+		v   float64
+		ok  bool
+	}{
+		{"", 0, true},
+		{"42", 42, true},
+		{"1,250,000", 1250000, true},
+		{"1 250.75", 1250.75, true},
+		{"ETB 5,000", 5000, true},
+		{"etb300", 300, true},
+		{"-1", 0, false},
+		{"five", 0, false},
+		{"12 birr", 0, false},
+	}
+	for _, c := range cases {
+		v, err := parseNumber(c.raw)
+		if (err == nil) != c.ok || v != c.v {
+			t.Errorf("parseNumber(%q) = %v, %v", c.raw, v, err)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	cases := []struct {
+		raw      string
+		calendar string
+		want     time.Time
+		ok       bool
+	}{
+		{"2024-03-05", "", date(2024, 3, 5), true},
+		{"2024-03-05T10:30:00Z", "", date(2024, 3, 5).Add(10*time.Hour + 30*time.Minute), true},
+		{"05/03/2024", "", date(2024, 3, 5), true},
+		{"5/3/2024", "", date(2024, 3, 5), true},
+		{"2024/03/05", "", date(2024, 3, 5), true},
+		{"45356", "", date(2024, 3, 5), true},
+		{"2024-03-05", ethcal.CalendarGregorian, date(2024, 3, 5), true},
+		{"2016-01-01", ethcal.CalendarEthiopian, date(2023, 9, 12), true},
+		{"2016/11/01", ethcal.CalendarEthiopian, date(2024, 7, 8), true},
+		{"2016-13-07", ethcal.CalendarEthiopian, time.Time{}, false},
+		{"03-05-2024", "", time.Time{}, false},
+		{"2024-02-30", "", time.Time{}, false},
+		{"0", "", time.Time{}, false},
+		{"soon", "", time.Time{}, false},
+	}
+	for _, c := range cases {
+		got, err := parseDate(c.raw, c.calendar)
+		if (err == nil) != c.ok || !got.Equal(c.want) {
+			t.Errorf("parseDate(%q, %q) = %v, %v", c.raw, c.calendar, got, err)
+		}
+	}
+}
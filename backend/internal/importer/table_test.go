@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xlsxFixture builds a workbook from the given parts, keyed by file name
+func xlsxFixture(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const fixtureSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="4" uniqueCount="4">
+	<si><t>title</t></si>
+	<si><t>author_email</t></si>
+	<si><t>allocated_budget</t></si>
+	<si><r><t>Soil </t></r><r><t>health</t></r></si>
+</sst>`
+
+// The second row leaves B2 out and the blank third row is skipped
+const fixtureSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+	<sheetData>
+		<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>
+		<row r="2"><c r="A2" t="s"><v>3</v></c><c r="C2"><v>1500</v></c></row>
+		<row r="3"><c r="A3" t="inlineStr"><is><t></t></is></c></row>
+		<row r="4"><c r="A4" t="inlineStr"><is><t>Maize yields</t></is></c><c r="B4" t="inlineStr"><is><t>author@example.edu</t></is></c></row>
+	</sheetData>
+</worksheet>`
+
+func TestReadXLSX(t *testing.T) {
+	data := xlsxFixture(t, map[string]string{
+		"xl/sharedStrings.xml":     fixtureSharedStrings,
+		"xl/worksheets/sheet1.xml": fixtureSheet,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row><c t="inlineStr"><is><t>ignored</t></is></c></row></sheetData></worksheet>`,
+	})
+	table, err := ReadXLSX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"title", "author_email", "allocated_budget"}; !reflect.DeepEqual(table.Header, want) {
+		t.Errorf("header %q, want %q", table.Header, want)
+	}
+	want := [][]string{
+		{"Soil health", "", "1500"},
+		{"Maize yields", "author@example.edu", ""},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("rows %q, want %q", table.Rows, want)
+	}
+}
+
+func TestReadXLSXErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"not a zip", []byte("title,author_email\n"), "failed to open xlsx"},
+		{"no worksheet", xlsxFixture(t, map[string]string{"xl/sharedStrings.xml": fixtureSharedStrings}), "no worksheets"},
+		{"unknown shared string", xlsxFixture(t, map[string]string{
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c r="A1" t="s"><v>7</v></c></row></sheetData></worksheet>`,
+		}), "unknown shared string"},
+		{"broken xml", xlsxFixture(t, map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData>"}), "failed to parse"},
+		{"empty sheet", xlsxFixture(t, map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData/></worksheet>"}), "empty"},
+	}
+	for _, c := range cases {
+		if _, err := ReadXLSX(c.data); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	cases := []struct {
+		ref string
+		col int
+	}{
+		{"A1", 0},
+		{"C12", 2},
+		{"Z3", 25},
+		{"AA1", 26},
+		{"AB12", 27},
+		{"AZ9", 51},
+		{"BA9", 52},
+		{"XFD1048576", 16383},
+	}
+	for _, c := range cases {
+		if got := columnIndex(c.ref); got != c.col {
+			t.Errorf("columnIndex(%q) = %d, want %d", c.ref, got, c.col)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	table, err := ReadCSV(strings.NewReader("\ufefftitle, author_email\nSoil health\n,\nMaize yields,author@example.edu,extra\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"title", "author_email"}; !reflect.DeepEqual(table.Header, want) {
+		t.Errorf("header %q, want %q", table.Header, want)
+	}
+	want := [][]string{{"Soil health", ""}, {"Maize yields", "author@example.edu"}}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("rows %q, want %q", table.Rows, want)
+	}
+}
@@ -0,0 +1,451 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/ethcal"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// UserFields lists the target fields a column can be mapped to. roles holds
+// further roles besides role, separated by semicolons; org_unit is a unit
+// code or name.
+var UserFields = []string{
+	"email", "name", "role", "roles", "org_unit",
+	"academic_year", "author_type", "author_category", "academic_rank",
+	"qualification", "employment_type", "gender", "date_of_birth",
+}
+
+// What happened, or in a dry run would happen, to a user row
+const (
+	UserCreated = "created"
+	UserUpdated = "updated"
+	UserFailed  = "failed"
+)
+
+// UserOptions controls how a table is turned into users
+type UserOptions struct {
+	// Mapping maps a target field to the spreadsheet column holding it.
+	// Fields without an entry are read from a column with the same name.
+	Mapping map[string]string `json:"mapping"`
+	// Calendar is the calendar dates of birth are written in
+	Calendar string `json:"calendar"`
+	// DefaultRole is given to new users whose row has no role
+	DefaultRole string `json:"default_role"`
+	// CheckRoles returns why the importing admin may not give users these
+	// roles, or manage users holding them
+	CheckRoles func(roles []string) error `json:"-"`
+}
+
+// UserRowResult is the outcome for one spreadsheet row
+type UserRowResult struct {
+	Row       int        `json:"row"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	Action    string     `json:"action"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	Roles     []string   `json:"roles,omitempty"`
+	OrgUnitID *uuid.UUID `json:"org_unit_id,omitempty"`
+	// Placeholder accounts have no password yet and can be invited
+	Placeholder bool     `json:"placeholder"`
+	Invited     bool     `json:"invited"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// UserReport summarises a dry run or an import of users
+type UserReport struct {
+	DryRun    bool            `json:"dry_run"`
+	TotalRows int             `json:"total_rows"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Failed    int             `json:"failed"`
+	Invited   int             `json:"invited"`
+	Rows      []UserRowResult `json:"rows"`
+}
+
+type userRow struct {
+	email     string
+	name      string
+	role      string
+	roles     []string
+	orgUnit   string
+	orgUnitID *uuid.UUID
+	profile   map[string]string
+}
+
+// userProfileFields are copied to the users column of the same name, with
+// the column's length limit
+var userProfileFields = []struct {
+	name  string
+	limit int
+}{
+	{"academic_year", 50}, {"author_type", 50}, {"author_category", 50}, {"academic_rank", 50},
+	{"qualification", 50}, {"employment_type", 50}, {"gender", 20}, {"date_of_birth", 50},
+}
+
+// ImportUsers validates every row of the table and, unless dryRun is set,
+// writes the valid ones in a single transaction. Rows with a new email
+// become placeholder accounts, which are completed by accepting an
+// invitation or by registering. Rows matching an account update its
+// profile and unit, and the roles of placeholders; empty cells keep the
+// current values. Invalid rows are reported and skipped.
+func ImportUsers(ctx context.Context, db *database.Database, table *Table, opts UserOptions, dryRun bool) (*UserReport, error) {
+	if opts.Calendar == "" {
+		opts.Calendar = ethcal.CalendarGregorian
+	}
+	if opts.Calendar != ethcal.CalendarGregorian && opts.Calendar != ethcal.CalendarEthiopian {
+		return nil, fmt.Errorf("%w: calendar must be gregorian or ethiopian", ErrInvalidOptions)
+	}
+	if opts.DefaultRole == "" {
+		opts.DefaultRole = "author"
+	}
+	if opts.CheckRoles != nil {
+		if err := opts.CheckRoles([]string{opts.DefaultRole}); err != nil {
+			return nil, fmt.Errorf("%w: default_role: %v", ErrInvalidOptions, err)
+		}
+	}
+
+	columns, err := resolveColumns(table.Header, opts.Mapping, UserFields, "email")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UserReport{DryRun: dryRun, TotalRows: len(table.Rows)}
+	parsed := make([]userRow, len(table.Rows))
+	seen := map[string]int{}
+	for i, record := range table.Rows {
+		result := UserRowResult{Row: i + 2}
+		row, errs := parseUserRow(record, columns, opts)
+		if row.email != "" {
+			if first, ok := seen[row.email]; ok {
+				errs = append(errs, fmt.Sprintf("email duplicates row %d", first))
+			} else {
+				seen[row.email] = result.Row
+			}
+		}
+		result.Email = row.email
+		result.Name = row.name
+		result.Errors = errs
+		parsed[i] = row
+		report.Rows = append(report.Rows, result)
+	}
+
+	if err := resolveOrgUnits(ctx, db, report, parsed); err != nil {
+		return nil, err
+	}
+	accounts, err := existingAccounts(ctx, db, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range report.Rows {
+		result, row := &report.Rows[i], &parsed[i]
+		account, exists := accounts[row.email]
+		switch {
+		case !exists:
+			if row.name == "" {
+				result.Errors = append(result.Errors, "name: required for new users")
+			}
+			if row.role == "" {
+				row.role = opts.DefaultRole
+			}
+			result.Placeholder = true
+		case account.otherTenant:
+			result.Errors = append(result.Errors, "email belongs to an account of another tenant")
+		default:
+			result.UserID = &account.id
+			result.Placeholder = account.placeholder
+			if opts.CheckRoles != nil {
+				if err := opts.CheckRoles(account.roles); err != nil {
+					result.Errors = append(result.Errors, "account: "+err.Error())
+				}
+			}
+		}
+		// Only new users and placeholders take the roles of the row
+		if row.role != "" && (!exists || account.placeholder) {
+			row.roles = withPrimary(row.role, row.roles)
+			if opts.CheckRoles != nil {
+				if err := opts.CheckRoles(row.roles); err != nil {
+					result.Errors = append(result.Errors, "role: "+err.Error())
+				}
+			}
+		} else {
+			row.role, row.roles = "", nil
+		}
+
+		switch {
+		case len(result.Errors) > 0:
+			result.Action = UserFailed
+			result.UserID = nil
+			report.Failed++
+		case exists:
+			result.Action = UserUpdated
+			report.Updated++
+		default:
+			result.Action = UserCreated
+			report.Created++
+		}
+	}
+
+	if dryRun || report.Created+report.Updated == 0 {
+		return report, nil
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range report.Rows {
+		result := &report.Rows[i]
+		if result.Action == UserFailed {
+			continue
+		}
+		if err := writeUser(ctx, tx, &parsed[i], result); err != nil {
+			return nil, fmt.Errorf("row %d: %w", result.Row, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return report, nil
+}
+
+func parseUserRow(record []string, columns map[string]int, opts UserOptions) (userRow, []string) {
+	var errs []string
+	get := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := userRow{
+		email:   strings.ToLower(get("email")),
+		name:    get("name"),
+		role:    get("role"),
+		orgUnit: get("org_unit"),
+		profile: map[string]string{},
+	}
+	for _, role := range strings.Split(get("roles"), ";") {
+		if role = strings.TrimSpace(role); role != "" {
+			row.roles = append(row.roles, role)
+		}
+	}
+	if row.role == "" && len(row.roles) > 0 {
+		errs = append(errs, "roles: given without a role")
+	}
+	if len(row.roles) > 10 {
+		errs = append(errs, "roles: more than 10")
+	}
+
+	if row.email == "" {
+		errs = append(errs, "email: required")
+	} else if addr, err := mail.ParseAddress(row.email); err != nil || addr.Address != row.email {
+		errs = append(errs, "email: not a valid email address")
+	}
+	if len(row.name) > 255 {
+		errs = append(errs, "name: longer than 255 characters")
+	}
+
+	for _, f := range userProfileFields {
+		v := get(f.name)
+		if f.name == "date_of_birth" && v != "" {
+			t, err := parseDate(v, opts.Calendar)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("date_of_birth: %v", err))
+				continue
+			}
+			v = t.Format("2006-01-02")
+		}
+		if len(v) > f.limit {
+			errs = append(errs, fmt.Sprintf("%s: longer than %d characters", f.name, f.limit))
+		}
+		row.profile[f.name] = v
+	}
+
+	return row, errs
+}
+
+// withPrimary lists the primary role first, then the other roles without
+// repeats
+func withPrimary(primary string, others []string) []string {
+	roles := []string{primary}
+	for _, role := range others {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// resolveOrgUnits looks up the unit of each row by code, then by name
+func resolveOrgUnits(ctx context.Context, db *database.Database, report *UserReport, rows []userRow) error {
+	dbRows, err := db.Query(ctx, "SELECT id, COALESCE(code, ''), name FROM org_units")
+	if err != nil {
+		return fmt.Errorf("failed to look up units: %w", err)
+	}
+	byCode := map[string]uuid.UUID{}
+	byName := map[string][]uuid.UUID{}
+	for dbRows.Next() {
+		var id uuid.UUID
+		var code, name string
+		if err := dbRows.Scan(&id, &code, &name); err != nil {
+			dbRows.Close()
+			return fmt.Errorf("failed to read units: %w", err)
+		}
+		if code != "" {
+			byCode[strings.ToLower(code)] = id
+		}
+		byName[strings.ToLower(name)] = append(byName[strings.ToLower(name)], id)
+	}
+	dbRows.Close()
+
+	for i := range rows {
+		key := strings.ToLower(rows[i].orgUnit)
+		if key == "" {
+			continue
+		}
+		if id, ok := byCode[key]; ok {
+			rows[i].orgUnitID = &id
+			continue
+		}
+		switch ids := byName[key]; len(ids) {
+		case 0:
+			report.Rows[i].Errors = append(report.Rows[i].Errors, fmt.Sprintf("org_unit: no unit with code or name %q", rows[i].orgUnit))
+		case 1:
+			rows[i].orgUnitID = &ids[0]
+		default:
+			report.Rows[i].Errors = append(report.Rows[i].Errors, fmt.Sprintf("org_unit: several units are named %q; use the code", rows[i].orgUnit))
+		}
+	}
+	return nil
+}
+
+type existingAccount struct {
+	id          uuid.UUID
+	roles       []string
+	placeholder bool
+	otherTenant bool
+}
+
+// existingAccounts finds the accounts using the rows' emails. Emails are
+// unique across tenants, so other tenants' accounts are looked up too.
+func existingAccounts(ctx context.Context, db *database.Database, rows []userRow) (map[string]existingAccount, error) {
+	var emails []string
+	for _, row := range rows {
+		if row.email != "" {
+			emails = append(emails, row.email)
+		}
+	}
+
+	tenant, scoped := database.TenantFrom(ctx)
+	dbRows, err := db.Query(database.WithoutTenant(ctx), `
+		SELECT LOWER(email), id, roles, COALESCE(is_placeholder, FALSE), tenant_id
+		FROM users WHERE LOWER(email) = ANY($1)
+	`, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up users: %w", err)
+	}
+	defer dbRows.Close()
+
+	accounts := map[string]existingAccount{}
+	for dbRows.Next() {
+		var email string
+		var a existingAccount
+		var tenantID uuid.UUID
+		if err := dbRows.Scan(&email, &a.id, &a.roles, &a.placeholder, &tenantID); err != nil {
+			return nil, fmt.Errorf("failed to read users: %w", err)
+		}
+		a.otherTenant = scoped && tenantID != tenant
+		accounts[email] = a
+	}
+	return accounts, dbRows.Err()
+}
+
+// writeUser creates or updates the account of a valid row
+func writeUser(ctx context.Context, tx pgx.Tx, row *userRow, result *UserRowResult) error {
+	p := row.profile
+	var err error
+	if result.UserID == nil {
+		var id uuid.UUID
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (
+				email, password_hash, name, role, roles, is_verified, is_placeholder, preferences, org_unit_id,
+				academic_year, author_type, author_category, academic_rank, qualification, employment_type, gender, date_of_birth
+			)
+			VALUES ($1, '', $2, $3, $4, FALSE, TRUE, '{}', $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING id, role, roles, org_unit_id
+		`, row.email, row.name, row.role, row.roles, row.orgUnitID,
+			p["academic_year"], p["author_type"], p["author_category"], p["academic_rank"],
+			p["qualification"], p["employment_type"], p["gender"], p["date_of_birth"],
+		).Scan(&id, &result.Role, &result.Roles, &result.OrgUnitID)
+		result.UserID = &id
+	} else {
+		err = tx.QueryRow(ctx, `
+			UPDATE users SET
+				name = COALESCE(NULLIF($2, ''), name),
+				role = COALESCE(NULLIF($3, ''), role),
+				roles = CASE WHEN $3 = '' THEN roles ELSE $4 END,
+				org_unit_id = COALESCE($5, org_unit_id),
+				academic_year = COALESCE(NULLIF($6, ''), academic_year),
+				author_type = COALESCE(NULLIF($7, ''), author_type),
+				author_category = COALESCE(NULLIF($8, ''), author_category),
+				academic_rank = COALESCE(NULLIF($9, ''), academic_rank),
+				qualification = COALESCE(NULLIF($10, ''), qualification),
+				employment_type = COALESCE(NULLIF($11, ''), employment_type),
+				gender = COALESCE(NULLIF($12, ''), gender),
+				date_of_birth = COALESCE(NULLIF($13, ''), date_of_birth),
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING role, roles, org_unit_id
+		`, *result.UserID, row.name, row.role, row.roles, row.orgUnitID,
+			p["academic_year"], p["author_type"], p["author_category"], p["academic_rank"],
+			p["qualification"], p["employment_type"], p["gender"], p["date_of_birth"],
+		).Scan(&result.Role, &result.Roles, &result.OrgUnitID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write user %s: %w", row.email, err)
+	}
+	return nil
+}
+
+// WriteCSV writes the report as a spreadsheet, one line per row of the import
+func (r *UserReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"row", "email", "name", "action", "user_id", "roles", "invited", "errors"})
+	for _, row := range r.Rows {
+		userID := ""
+		if row.UserID != nil {
+			userID = row.UserID.String()
+		}
+		out.Write([]string{
+			strconv.Itoa(row.Row), csvCell(row.Email), csvCell(row.Name), row.Action, userID,
+			csvCell(strings.Join(row.Roles, ";")), strconv.FormatBool(row.Invited), csvCell(strings.Join(row.Errors, "; ")),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvCell keeps spreadsheets from running a value copied from the upload as
+// a formula
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
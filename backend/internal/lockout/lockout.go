@@ -0,0 +1,116 @@
+// Package lockout slows down and then temporarily locks out keys, such as an
+// account or a client IP, that keep failing to sign in.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Policy says how failures turn into waits. After FreeAttempts failures each
+// further one doubles the wait, starting at BaseDelay and capped at MaxDelay.
+// LockoutAfter failures lock the key for LockoutDuration, after which it
+// starts over. Failures are forgotten after Window without one.
+type Policy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// AccountPolicy guards a single account
+var AccountPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAfter:    10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// IPPolicy guards a client address, which may be shared by many users
+var IPPolicy = Policy{
+	FreeAttempts:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    100,
+	LockoutDuration: 30 * time.Minute,
+	Window:          time.Hour,
+}
+
+// MFAPolicy guards the second factor of a signed-in user or a pending
+// sign-in. Codes are short, so a few wrong ones lock the user out.
+var MFAPolicy = Policy{
+	FreeAttempts:    4,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAfter:    5,
+	LockoutDuration: 5 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// Status is where a key stands after a failure
+type Status struct {
+	Failures    int
+	LockedUntil time.Time
+	// The failure just recorded started a lockout
+	Locked bool
+}
+
+// Tracker stores failures per key. Use the memory tracker for a single
+// instance and the Postgres one when several instances share the load.
+type Tracker interface {
+	// Wait returns how long the key has to wait before its next attempt
+	Wait(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failed attempt
+	Fail(ctx context.Context, key string) (Status, error)
+	// Reset forgets the key's failures and lifts any lockout
+	Reset(ctx context.Context, key string) error
+}
+
+// record is the state kept per key
+type record struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func (p Policy) delay(failures int) time.Duration {
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+func (p Policy) wait(r record, now time.Time) time.Duration {
+	if r.lockedUntil.After(now) {
+		return r.lockedUntil.Sub(now)
+	}
+	if now.Sub(r.lastFailure) > p.Window {
+		return 0
+	}
+	return max(r.lastFailure.Add(p.delay(r.failures)).Sub(now), 0)
+}
+
+func (p Policy) fail(r record, now time.Time) (record, Status) {
+	if now.Sub(r.lastFailure) > p.Window {
+		r.failures = 0
+	}
+	r.failures++
+	r.lastFailure = now
+
+	status := Status{Failures: r.failures}
+	if r.failures >= p.LockoutAfter {
+		r.lockedUntil = now.Add(p.LockoutDuration)
+		r.failures = 0
+		status.Locked = true
+	}
+	status.LockedUntil = r.lockedUntil
+	return r, status
+}
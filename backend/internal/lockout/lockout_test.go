@@ -0,0 +1,85 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        4 * time.Second,
+	LockoutAfter:    6,
+	LockoutDuration: 10 * time.Minute,
+	Window:          time.Hour,
+}
+
+func TestDelayDoublesUpToMax(t *testing.T) {
+	want := []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for failures, d := range want {
+		if got := testPolicy.delay(failures); got != d {
+			t.Errorf("delay(%d) = %s, want %s", failures, got, d)
+		}
+	}
+}
+
+func TestFailLocksAndStartsOver(t *testing.T) {
+	now := time.Now()
+	var r record
+	var status Status
+	for i := 0; i < 6; i++ {
+		r, status = testPolicy.fail(r, now)
+		if i < 5 && status.Locked {
+			t.Fatalf("locked after %d failures", i+1)
+		}
+	}
+	if !status.Locked || !status.LockedUntil.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("not locked after 6 failures: %+v", status)
+	}
+	if w := testPolicy.wait(r, now.Add(time.Minute)); w != 9*time.Minute {
+		t.Errorf("wait during lockout = %s, want 9m", w)
+	}
+	if w := testPolicy.wait(r, now.Add(11*time.Minute)); w != 0 {
+		t.Errorf("wait after lockout = %s, want 0", w)
+	}
+
+	// A failure after the lockout counts from one again
+	r, status = testPolicy.fail(r, now.Add(11*time.Minute))
+	if status.Failures != 1 || status.Locked {
+		t.Errorf("after lockout: %+v", status)
+	}
+}
+
+func TestFailuresExpireAfterWindow(t *testing.T) {
+	now := time.Now()
+	var r record
+	for i := 0; i < 4; i++ {
+		r, _ = testPolicy.fail(r, now)
+	}
+	if w := testPolicy.wait(r, now); w != 2*time.Second {
+		t.Errorf("wait = %s, want 2s", w)
+	}
+	_, status := testPolicy.fail(r, now.Add(2*time.Hour))
+	if status.Failures != 1 {
+		t.Errorf("failures after the window = %d, want 1", status.Failures)
+	}
+}
+
+func TestMemoryTracker(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewMemoryTracker(testPolicy)
+	for i := 0; i < 3; i++ {
+		tracker.Fail(ctx, "a")
+	}
+	if w, _ := tracker.Wait(ctx, "a"); w <= 0 {
+		t.Error("no wait after three failures")
+	}
+	if w, _ := tracker.Wait(ctx, "b"); w != 0 {
+		t.Error("failures leaked to another key")
+	}
+	tracker.Reset(ctx, "a")
+	if w, _ := tracker.Wait(ctx, "a"); w != 0 {
+		t.Error("reset did not clear the wait")
+	}
+}
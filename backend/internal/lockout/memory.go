@@ -0,0 +1,54 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryTracker keeps failures in process memory
+type MemoryTracker struct {
+	policy  Policy
+	mu      sync.Mutex
+	records map[string]record
+}
+
+func NewMemoryTracker(policy Policy) *MemoryTracker {
+	return &MemoryTracker{policy: policy, records: map[string]record{}}
+}
+
+func (t *MemoryTracker) Wait(ctx context.Context, key string) (time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.policy.wait(t.records[key], time.Now()), nil
+}
+
+func (t *MemoryTracker) Fail(ctx context.Context, key string) (Status, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	r, status := t.policy.fail(t.records[key], now)
+	t.records[key] = r
+	t.prune(now)
+	return status, nil
+}
+
+func (t *MemoryTracker) Reset(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.records, key)
+	return nil
+}
+
+// prune drops keys that have nothing left to enforce once the map grows
+func (t *MemoryTracker) prune(now time.Time) {
+	if len(t.records) < 10000 {
+		return
+	}
+	for key, r := range t.records {
+		if !r.lockedUntil.After(now) && now.Sub(r.lastFailure) > t.policy.Window {
+			delete(t.records, key)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rpms-backend/internal/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresTracker keeps failures in the login_attempts table so that every
+// instance behind a load balancer sees the same counts. Trackers sharing the
+// table keep apart by the name prefixed to their keys. Counts belong to the
+// tenant of the caller's context.
+type PostgresTracker struct {
+	name   string
+	policy Policy
+	db     *database.Database
+}
+
+func NewPostgresTracker(db *database.Database, name string, policy Policy) *PostgresTracker {
+	return &PostgresTracker{name: name, policy: policy, db: db}
+}
+
+func (t *PostgresTracker) key(key string) string {
+	return t.name + ":" + key
+}
+
+func (t *PostgresTracker) Wait(ctx context.Context, key string) (time.Duration, error) {
+	r, err := scanRecord(t.db.Pool.QueryRow(ctx,
+		"SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1",
+		t.key(key)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return t.policy.wait(r, time.Now()), nil
+}
+
+func (t *PostgresTracker) Fail(ctx context.Context, key string) (Status, error) {
+	tx, err := t.db.BeginTx(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the key's row, creating it first, so failures arriving at several
+	// instances at once are counted one after the other
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 0, 'epoch')
+		ON CONFLICT (tenant_id, key) DO NOTHING
+	`, t.key(key)); err != nil {
+		return Status{}, err
+	}
+	r, err := scanRecord(tx.QueryRow(ctx,
+		"SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1 FOR UPDATE",
+		t.key(key)))
+	if err != nil {
+		return Status{}, err
+	}
+
+	now := time.Now()
+	r, status := t.policy.fail(r, now)
+	var lockedUntil *time.Time
+	if !r.lockedUntil.IsZero() {
+		lockedUntil = &r.lockedUntil
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE login_attempts SET failures = $2, last_failure_at = $3, locked_until = $4 WHERE key = $1",
+		t.key(key), r.failures, r.lastFailure, lockedUntil); err != nil {
+		return Status{}, err
+	}
+
+	// Forget keys with nothing left to enforce
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM login_attempts
+		WHERE key LIKE $1 AND last_failure_at < $2 AND (locked_until IS NULL OR locked_until < $3)
+	`, t.name+":%", now.Add(-t.policy.Window), now); err != nil {
+		return Status{}, err
+	}
+
+	return status, tx.Commit(ctx)
+}
+
+func (t *PostgresTracker) Reset(ctx context.Context, key string) error {
+	_, err := t.db.Pool.Exec(ctx, "DELETE FROM login_attempts WHERE key = $1", t.key(key))
+	return err
+}
+
+func scanRecord(row pgx.Row) (record, error) {
+	var r record
+	var lockedUntil *time.Time
+	if err := row.Scan(&r.failures, &r.lastFailure, &lockedUntil); err != nil {
+		return r, err
+	}
+	if lockedUntil != nil {
+		r.lockedUntil = *lockedUntil
+	}
+	return r, nil
+}
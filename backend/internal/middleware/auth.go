@@ -1,15 +1,28 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"rpms-backend/internal/auth"
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/models"
+	"rpms-backend/internal/rbac"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
+// APITokenValidator looks up an API token. It returns nil for tokens that
+// do not exist, are revoked or have expired.
+type APITokenValidator interface {
+	ValidateAPIToken(ctx context.Context, token string, clientIP string) (*models.APITokenAuth, error)
+}
+
+// AuthMiddleware accepts a JWT access token or an API token as the bearer
+// token
+func AuthMiddleware(jwtManager *auth.JWTManager, apiTokens APITokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,6 +40,10 @@ func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 		}
 
 		token := tokenParts[1]
+		if auth.IsAPIToken(token) {
+			authenticateAPIToken(c, apiTokens, token)
+			return
+		}
 		claims, err := jwtManager.ValidateToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -34,66 +51,179 @@ func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 			return
 		}
 
+		// A token only works for the tenant it was issued by. Tokens from
+		// before tenants existed belong to the default tenant.
+		tokenTenant := claims.TenantID
+		if tokenTenant == "" {
+			tokenTenant = database.DefaultTenantID
+		}
+		if tenantID := c.GetString("tenant_id"); tenantID != "" && tenantID != tokenTenant {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token was issued for another tenant"})
+			c.Abort()
+			return
+		}
+
 		// Set user claims in context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		roles := claims.Roles
+		if len(roles) == 0 {
+			roles = []string{claims.Role}
+		}
+		c.Set("roles", roles)
+		c.Set("mfa", claims.MFA)
 
 		c.Next()
 	}
 }
 
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
+func authenticateAPIToken(c *gin.Context, apiTokens APITokenValidator, token string) {
+	t, err := apiTokens.ValidateAPIToken(c.Request.Context(), token, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the token"})
+		c.Abort()
+		return
+	}
+	if t == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
+	}
+	if tenantID := c.GetString("tenant_id"); tenantID != "" && tenantID != t.TenantID.String() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token was issued for another tenant"})
+		c.Abort()
+		return
+	}
+
+	scope := rbac.ScopeWrite
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		scope = rbac.ScopeRead
+	}
+	if !slices.Contains(t.Scopes, scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The token lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", t.UserID.String())
+	c.Set("email", t.Email)
+	c.Set("role", t.Role)
+	c.Set("roles", t.Roles)
+	// A token counts as a second factor only if the session creating it
+	// completed one
+	c.Set("mfa", t.MFA)
+	c.Set("api_token_id", t.TokenID.String())
+	c.Set("token_scopes", t.Scopes)
+
+	c.Next()
+}
+
+// RequireSession refuses API tokens where the user must be signed in, such
+// as changing credentials, creating tokens or acting in the user's name
+func RequireSession() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
+		if c.GetString("api_token_id") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API tokens cannot be used here; please sign in"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
+
+// AccountChecker reports whether an account may still use its tokens
+type AccountChecker interface {
+	AccountActive(ctx context.Context, userID string) (bool, error)
+}
 
-		userRole, ok := role.(string)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid role format"})
+// RequireActiveAccount refuses the tokens of deactivated and deleted
+// accounts, which would otherwise work until they expire
+func RequireActiveAccount(checker AccountChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		active, err := checker.AccountActive(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the account"})
 			c.Abort()
 			return
 		}
-
-		// Check if user role is in allowed roles
-		allowed := false
-		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
-				allowed = true
-				break
-			}
-		}
-
-		if !allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "This account is no longer active"})
 			c.Abort()
 			return
 		}
-
 		c.Next()
 	}
 }
 
-func AdminOnly() gin.HandlerFunc {
-	return RoleMiddleware("admin")
+// RequireMFA refuses users holding any of the given roles whose token was
+// issued without a second factor, until they enroll and sign in again
+func RequireMFA(roles []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		held := c.GetStringSlice("roles")
+		for _, required := range roles {
+			if slices.Contains(held, required) && !c.GetBool("mfa") {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":        "Two-factor authentication is required for your role",
+					"mfa_required": true,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
 }
 
-func EditorOrAdmin() gin.HandlerFunc {
-	return RoleMiddleware("editor", "admin")
+// PermissionResolver returns the permissions a role grants in the tenant of
+// the context
+type PermissionResolver interface {
+	RolePermissions(ctx context.Context, role string) ([]string, error)
 }
 
-func AuthorOrAdmin() gin.HandlerFunc {
-	return RoleMiddleware("author", "admin")
+// LoadPermissions puts the permissions of all the user's roles in the
+// context, for RequirePermission and HasPermission
+func LoadPermissions(resolver PermissionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var permissions []string
+		for _, role := range c.GetStringSlice("roles") {
+			perms, err := resolver.RolePermissions(c.Request.Context(), role)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+				c.Abort()
+				return
+			}
+			for _, p := range perms {
+				if !slices.Contains(permissions, p) {
+					permissions = append(permissions, p)
+				}
+			}
+		}
+		// An API token lets through only the permissions it is scoped to
+		if _, ok := c.Get("token_scopes"); ok {
+			permissions = rbac.Intersect(permissions, c.GetStringSlice("token_scopes"))
+		}
+		c.Set("permissions", permissions)
+		c.Next()
+	}
 }
 
-func CoordinatorOrAdmin() gin.HandlerFunc {
-	return RoleMiddleware("coordinator", "admin")
+// RequirePermission lets through users holding any of the permissions
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range permissions {
+			if HasPermission(c, p) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
 }
 
-func EditorOrCoordinatorOrAdmin() gin.HandlerFunc {
-	return RoleMiddleware("editor", "coordinator", "admin")
+// HasPermission reports whether any of the user's roles grants the permission
+func HasPermission(c *gin.Context, permission string) bool {
+	return slices.Contains(c.GetStringSlice("permissions"), permission)
 }
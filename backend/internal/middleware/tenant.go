@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantResolver looks up the tenant a request is addressed to. It returns
+// nil when no tenant matches.
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, slug, host string) (*models.Tenant, error)
+}
+
+// TenantMiddleware picks the tenant from the X-Tenant header (a slug) or the
+// request host, falling back to the default tenant, and scopes every
+// database query of the request to it
+func TenantMiddleware(resolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		tenant, err := resolver.ResolveTenant(c.Request.Context(), strings.TrimSpace(c.GetHeader("X-Tenant")), strings.ToLower(host))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tenant"})
+			c.Abort()
+			return
+		}
+		if tenant == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown tenant"})
+			c.Abort()
+			return
+		}
+		if !tenant.Active {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This tenant is suspended"})
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant", tenant)
+		c.Set("tenant_id", tenant.ID.String())
+		c.Request = c.Request.WithContext(database.WithTenant(c.Request.Context(), tenant.ID))
+
+		c.Next()
+	}
+}
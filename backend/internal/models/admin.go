@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminUser is an account as admins see it when managing users
+type AdminUser struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	Name           string     `json:"name"`
+	Role           string     `json:"role"`
+	Roles          []string   `json:"roles"`
+	Avatar         string     `json:"avatar"`
+	IsVerified     bool       `json:"is_verified"`
+	IsPlaceholder  bool       `json:"is_placeholder"`
+	OrgUnitID      *uuid.UUID `json:"org_unit_id"`
+	ScopeOrgUnitID *uuid.UUID `json:"scope_org_unit_id"`
+	DeactivatedAt  *time.Time `json:"deactivated_at"`
+	LastLoginAt    *time.Time `json:"last_login_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// AdminUserDetail adds what only the page of one user shows
+type AdminUserDetail struct {
+	AdminUser
+	Bio         string                 `json:"bio"`
+	ORCID       string                 `json:"orcid"`
+	Preferences map[string]interface{} `json:"preferences"`
+	TOTPEnabled bool                   `json:"totp_enabled"`
+	// Seconds the account has to wait before its next sign-in attempt
+	SignInWait int `json:"sign_in_wait"`
+	// Sessions that can still be refreshed
+	ActiveSessions int `json:"active_sessions"`
+}
+
+type AdminUserPage struct {
+	Users    []AdminUser `json:"users"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+// AuditEntry records something an admin did
+type AuditEntry struct {
+	ID           uuid.UUID              `json:"id"`
+	ActorID      *uuid.UUID             `json:"actor_id"`
+	ActorName    string                 `json:"actor_name"`
+	Action       string                 `json:"action"`
+	TargetUserID *uuid.UUID             `json:"target_user_id"`
+	TargetName   string                 `json:"target_name"`
+	Details      map[string]interface{} `json:"details"`
+	IPAddress    string                 `json:"ip_address"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+type AuditLogPage struct {
+	Entries  []AuditEntry `json:"entries"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+type DeactivateUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
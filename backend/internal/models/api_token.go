@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIToken is a named, scoped credential for scripts and other systems. Only
+// its hash is stored; the token itself is shown once, on creation.
+type APIToken struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	// First characters of the token, to tell tokens apart
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedBy  *uuid.UUID `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken is a new token together with its secret
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type CreateAPITokenRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// read, write and permissions such as user.manage
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// 90 days when not given
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// APITokenAuth is who an API token acts for and what it may do
+type APITokenAuth struct {
+	TokenID  uuid.UUID
+	UserID   uuid.UUID
+	Email    string
+	Role     string
+	Roles    []string
+	Scopes   []string
+	TenantID uuid.UUID
+	// Whether the token was created from a session that completed a second
+	// factor
+	MFA bool
+}
+
+// ServiceAccount is an account for another system, such as the HR or
+// library system. It cannot sign in and works only through API tokens.
+type ServiceAccount struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Role          string     `json:"role"`
+	Roles         []string   `json:"roles"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// Tokens neither revoked nor expired
+	ActiveTokens int `json:"active_tokens"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name  string   `json:"name" binding:"required,max=255"`
+	Role  string   `json:"role" binding:"required"`
+	Roles []string `json:"roles"`
+}
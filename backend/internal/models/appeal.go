@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Appeal statuses
+const (
+	AppealPending       = "pending"
+	AppealPanelAssigned = "panel_assigned"
+	AppealUpheld        = "upheld"
+	AppealReopened      = "reopened"
+)
+
+// Appeal is an author's appeal against the rejection of a paper
+type Appeal struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	PaperID         uuid.UUID  `json:"paper_id" db:"paper_id"`
+	PaperTitle      string     `json:"paper_title" db:"-"`
+	AppellantID     uuid.UUID  `json:"appellant_id" db:"appellant_id"`
+	AppellantName   string     `json:"appellant_name" db:"-"`
+	Reason          string     `json:"reason" db:"reason"`
+	Status          string     `json:"status" db:"status"`
+	RejectedAt      time.Time  `json:"rejected_at" db:"rejected_at"`
+	PanelAssignedBy *uuid.UUID `json:"panel_assigned_by" db:"panel_assigned_by"`
+	PanelAssignedAt *time.Time `json:"panel_assigned_at" db:"panel_assigned_at"`
+	DecidedBy       *uuid.UUID `json:"decided_by" db:"decided_by"`
+	DecisionNote    string     `json:"decision_note" db:"decision_note"`
+	DecidedAt       *time.Time `json:"decided_at" db:"decided_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	Panel []AppealPanelMember `json:"panel" db:"-"`
+}
+
+type AppealPanelMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	Role   string    `json:"role"`
+}
+
+type CreateAppealRequest struct {
+	Reason string `json:"reason" binding:"required,min=50,max=10000"`
+}
+
+type AssignAppealPanelRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids" binding:"required,min=1,max=7"`
+}
+
+// AppealDecisionRequest records the panel's decision. Reopen sends the paper
+// back to under_review, uphold keeps the rejection.
+type AppealDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=reopen uphold"`
+	Note     string `json:"note" binding:"required,max=10000"`
+}
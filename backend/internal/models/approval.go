@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Approval step statuses. Steps after the current one wait; the current one
+// is pending; steps left when a round is rejected or returned are cancelled.
+const (
+	ApprovalWaiting   = "waiting"
+	ApprovalPending   = "pending"
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalReturned  = "returned"
+	ApprovalCancelled = "cancelled"
+)
+
+// ApprovalChain is the ordered list of approvals a paper of one type needs
+// before it goes on to review
+type ApprovalChain struct {
+	ID        uuid.UUID           `json:"id" db:"id"`
+	PaperType string              `json:"paper_type" db:"paper_type"`
+	Name      string              `json:"name" db:"name"`
+	Steps     []ApprovalChainStep `json:"steps" db:"-"`
+	CreatedAt time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// ApprovalChainStep names who approves at one step: a role, a fixed unit, or
+// the unit of a given kind the paper belongs to (its department, college...).
+// Role and unit combine when both are set.
+type ApprovalChainStep struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Position     int        `json:"position" db:"position"`
+	Name         string     `json:"name" db:"name"`
+	ApproverRole string     `json:"approver_role" db:"approver_role"`
+	OrgUnitID    *uuid.UUID `json:"org_unit_id" db:"org_unit_id"`
+	UnitKind     string     `json:"unit_kind" db:"unit_kind"`
+}
+
+type ApprovalStepRequest struct {
+	Name         string     `json:"name" binding:"required,max=255"`
+	ApproverRole string     `json:"approver_role" binding:"max=50"`
+	OrgUnitID    *uuid.UUID `json:"org_unit_id"`
+	UnitKind     string     `json:"unit_kind" binding:"omitempty,oneof=university college department research_center"`
+}
+
+type ApprovalChainRequest struct {
+	PaperType string                `json:"paper_type" binding:"required,max=100"`
+	Name      string                `json:"name" binding:"required,max=255"`
+	Steps     []ApprovalStepRequest `json:"steps" binding:"required,min=1,max=10,dive"`
+}
+
+// Validate checks that every step says who approves it
+func (r ApprovalChainRequest) Validate() error {
+	for i, step := range r.Steps {
+		if step.ApproverRole == "" && step.OrgUnitID == nil && step.UnitKind == "" {
+			return fmt.Errorf("step %d: give an approver role, a unit or a unit kind", i+1)
+		}
+		if step.OrgUnitID != nil && step.UnitKind != "" {
+			return errors.New("a step cannot have both a fixed unit and a unit kind")
+		}
+	}
+	return nil
+}
+
+// PaperApproval is one step of a paper's approval round
+type PaperApproval struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	PaperID      uuid.UUID  `json:"paper_id" db:"paper_id"`
+	PaperTitle   string     `json:"paper_title" db:"-"`
+	PaperType    string     `json:"paper_type" db:"-"`
+	Round        int        `json:"round" db:"round"`
+	Position     int        `json:"position" db:"position"`
+	StepName     string     `json:"step_name" db:"step_name"`
+	ApproverRole string     `json:"approver_role" db:"approver_role"`
+	OrgUnitID    *uuid.UUID `json:"org_unit_id" db:"org_unit_id"`
+	OrgUnitName  string     `json:"org_unit_name" db:"-"`
+	Status       string     `json:"status" db:"status"`
+	DecidedBy    *uuid.UUID `json:"decided_by" db:"decided_by"`
+	DeciderName  string     `json:"decided_by_name" db:"-"`
+	Comment      string     `json:"comment" db:"comment"`
+	DecidedAt    *time.Time `json:"decided_at" db:"decided_at"`
+	ActivatedAt  *time.Time `json:"activated_at" db:"activated_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ApprovalDecisionRequest approves a step, rejects the paper or returns it to
+// the author for changes. Rejecting and returning need a comment.
+type ApprovalDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject return"`
+	Comment  string `json:"comment" binding:"max=5000"`
+}
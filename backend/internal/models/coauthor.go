@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CoAuthor is an additional author of a paper. UserID is set when the
+// co-author has an RPMS account; external co-authors only carry their details.
+type CoAuthor struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	PaperID     uuid.UUID  `json:"paper_id" db:"paper_id"`
+	UserID      *uuid.UUID `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	Email       string     `json:"email" db:"email"`
+	Affiliation string     `json:"affiliation" db:"affiliation"`
+	ORCID       string     `json:"orcid" db:"orcid"`
+	Position    int        `json:"position" db:"position"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CoAuthorInput struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	Affiliation string `json:"affiliation"`
+	ORCID       string `json:"orcid"`
+}
+
+type SetCoAuthorsRequest struct {
+	CoAuthors []CoAuthorInput `json:"co_authors" binding:"dive"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeneratedDocument is an issued letter or certificate. The rendered PDF is
+// stored with it and served by the download endpoint.
+type GeneratedDocument struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	SerialNumber  string     `json:"serial_number" db:"serial_number"`
+	Kind          string     `json:"kind" db:"kind"`
+	PaperID       uuid.UUID  `json:"paper_id" db:"paper_id"`
+	RecipientID   *uuid.UUID `json:"recipient_id" db:"recipient_id"`
+	RecipientName string     `json:"recipient_name" db:"recipient_name"`
+	PaperTitle    string     `json:"paper_title" db:"paper_title"`
+	IssuedBy      *uuid.UUID `json:"issued_by" db:"issued_by"`
+	IssuedAt      time.Time  `json:"issued_at" db:"issued_at"`
+
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevocationReason string     `json:"revocation_reason,omitempty" db:"revocation_reason"`
+}
+
+type GenerateDocumentRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=acceptance_letter publication_certificate"`
+	// Issue the document to a co-author instead of the submitting author
+	CoAuthorID *uuid.UUID `json:"coauthor_id"`
+}
+
+type RevokeDocumentRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// DocumentVerification is the public answer to "is this document genuine?"
+type DocumentVerification struct {
+	Status        string     `json:"status"`
+	Message       string     `json:"message"`
+	SerialNumber  string     `json:"serial_number"`
+	Kind          string     `json:"kind,omitempty"`
+	RecipientName string     `json:"recipient_name,omitempty"`
+	PaperTitle    string     `json:"paper_title,omitempty"`
+	IssuedAt      *time.Time `json:"issued_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revocation_reason,omitempty"`
+}
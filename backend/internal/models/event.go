@@ -19,6 +19,9 @@ type Event struct {
 	CoordinatorID uuid.UUID `json:"coordinator_id" db:"coordinator_id"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+
+	// Ethiopian calendar date, only filled when the request asks for calendar=ethiopian
+	DateEthiopian string `json:"date_ethiopian,omitempty" db:"-"`
 }
 
 type CreateEventRequest struct {
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation statuses, derived from the invitation's timestamps
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// Invitation asks someone to join the tenant as staff with preset roles and
+// unit
+type Invitation struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	Name           string     `json:"name"`
+	Role           string     `json:"role"`
+	Roles          []string   `json:"roles"`
+	OrgUnitID      *uuid.UUID `json:"org_unit_id"`
+	OrgUnitName    string     `json:"org_unit_name"`
+	ScopeOrgUnitID *uuid.UUID `json:"scope_org_unit_id"`
+	InvitedBy      *uuid.UUID `json:"invited_by"`
+	InvitedByName  string     `json:"invited_by_name"`
+	Status         string     `json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uuid.UUID `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type InvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Name  string `json:"name" binding:"max=255"`
+	Role  string `json:"role" binding:"required,max=50"`
+	// Further roles besides the primary one
+	Roles          []string   `json:"roles" binding:"max=10,dive,required,max=50"`
+	OrgUnitID      *uuid.UUID `json:"org_unit_id"`
+	ScopeOrgUnitID *uuid.UUID `json:"scope_org_unit_id"`
+}
+
+// InvitationPreview is what the invitee sees before accepting
+type InvitationPreview struct {
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role"`
+	Roles       []string  `json:"roles"`
+	OrgUnitName string    `json:"org_unit_name"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// AcceptInvitationRequest sets the invitee's password and completes their
+// profile
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+	Name     string `json:"name" binding:"required,max=255"`
+
+	AcademicRank   string `json:"academic_rank"`
+	Qualification  string `json:"qualification"`
+	EmploymentType string `json:"employment_type"`
+	Gender         string `json:"gender"`
+	DateOfBirth    string `json:"date_of_birth"`
+	Bio            string `json:"bio"`
+}
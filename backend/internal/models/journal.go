@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Journal struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Title        string    `json:"title" db:"title"`
+	TitleAmharic string    `json:"title_amharic" db:"title_amharic"`
+	ISSN         string    `json:"issn" db:"issn"`
+	EISSN        string    `json:"e_issn" db:"e_issn"`
+	JournalType  string    `json:"journal_type" db:"journal_type"`
+	Publisher    string    `json:"publisher" db:"publisher"`
+	Description  string    `json:"description" db:"description"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+
+	Volumes []JournalVolume `json:"volumes,omitempty" db:"-"`
+}
+
+type JournalVolume struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	JournalID uuid.UUID `json:"journal_id" db:"journal_id"`
+	Number    int       `json:"volume_number" db:"volume_number"`
+	Year      int       `json:"year" db:"year"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	Issues []JournalIssue `json:"issues,omitempty" db:"-"`
+}
+
+type JournalIssue struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	VolumeID        uuid.UUID  `json:"volume_id" db:"volume_id"`
+	Number          int        `json:"issue_number" db:"issue_number"`
+	Title           string     `json:"title" db:"title"`
+	Status          string     `json:"status" db:"status"`
+	PublicationDate *time.Time `json:"publication_date" db:"publication_date"`
+	PublishedAt     *time.Time `json:"published_at" db:"published_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Filled when a single issue is fetched
+	JournalID    *uuid.UUID   `json:"journal_id,omitempty" db:"-"`
+	JournalTitle string       `json:"journal_title,omitempty" db:"-"`
+	VolumeNumber int          `json:"volume_number,omitempty" db:"-"`
+	Contents     []IssuePaper `json:"contents,omitempty" db:"-"`
+}
+
+// IssuePaper is an entry in an issue's table of contents
+type IssuePaper struct {
+	PaperID    uuid.UUID `json:"paper_id"`
+	Position   int       `json:"position"`
+	Title      string    `json:"title"`
+	AuthorName string    `json:"author_name"`
+	Status     string    `json:"status"`
+	PageStart  *int      `json:"page_start"`
+	PageEnd    *int      `json:"page_end"`
+}
+
+type JournalRequest struct {
+	Title        string `json:"title" binding:"required,max=500"`
+	TitleAmharic string `json:"title_amharic"`
+	ISSN         string `json:"issn" binding:"max=9"`
+	EISSN        string `json:"e_issn" binding:"max=9"`
+	JournalType  string `json:"journal_type" binding:"omitempty,oneof=International National Local"`
+	Publisher    string `json:"publisher"`
+	Description  string `json:"description"`
+}
+
+type CreateVolumeRequest struct {
+	Number int `json:"volume_number" binding:"required,min=1"`
+	Year   int `json:"year" binding:"required,min=1900"`
+}
+
+type CreateIssueRequest struct {
+	Number          int        `json:"issue_number" binding:"required,min=1"`
+	Title           string     `json:"title"`
+	PublicationDate *time.Time `json:"publication_date"`
+}
+
+type IssueContentEntry struct {
+	PaperID   uuid.UUID `json:"paper_id" binding:"required"`
+	PageStart *int      `json:"page_start"`
+	PageEnd   *int      `json:"page_end"`
+}
+
+// SetIssueContentsRequest lists the papers of an issue in table-of-contents order
+type SetIssueContentsRequest struct {
+	Papers []IssueContentEntry `json:"papers" binding:"dive"`
+}
+
+// Validate checks the page ranges: each must be well formed and follow the
+// previous entry without overlapping it.
+func (r SetIssueContentsRequest) Validate() error {
+	seen := map[uuid.UUID]bool{}
+	lastPage := 0
+	for i, entry := range r.Papers {
+		if seen[entry.PaperID] {
+			return fmt.Errorf("paper %s is listed more than once", entry.PaperID)
+		}
+		seen[entry.PaperID] = true
+
+		if (entry.PageStart == nil) != (entry.PageEnd == nil) {
+			return fmt.Errorf("entry %d: give both page_start and page_end, or neither", i+1)
+		}
+		if entry.PageStart == nil {
+			continue
+		}
+		start, end := *entry.PageStart, *entry.PageEnd
+		if start < 1 || end < start {
+			return fmt.Errorf("entry %d: invalid page range %d-%d", i+1, start, end)
+		}
+		if start <= lastPage {
+			return fmt.Errorf("entry %d: pages %d-%d overlap the previous paper", i+1, start, end)
+		}
+		lastPage = end
+	}
+	return nil
+}
+
+type PublishIssueRequest struct {
+	// Defaults to the issue's planned publication date, or today
+	PublicationDate *time.Time `json:"publication_date"`
+}
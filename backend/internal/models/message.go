@@ -28,6 +28,7 @@ type Contact struct {
 	Name        string   `json:"name"`
 	Email       string   `json:"email"`
 	Role        string   `json:"role"`
+	Roles       []string `json:"roles"`
 	Avatar      string   `json:"avatar"`
 	UnreadCount int      `json:"unread_count"`
 	LastMessage *Message `json:"last_message,omitempty"`
@@ -0,0 +1,37 @@
+package models
+
+// MFAChallengeResponse is what login returns instead of a session when the
+// account has two-factor authentication on
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	// Seconds left to complete the second step
+	ExpiresIn int `json:"expires_in"`
+}
+
+// MFAVerifyRequest completes a login with an authenticator or recovery code
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest confirms a two-factor change with a current code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPSetupResponse carries a new authenticator secret. OTPAuthURL is what
+// the QR code encodes, for apps that take it as a link instead.
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	// PNG data URL of the QR code
+	QRCode string `json:"qr_code"`
+}
+
+type MFAStatus struct {
+	Enabled bool `json:"enabled"`
+	// The user's role has to use a second factor
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
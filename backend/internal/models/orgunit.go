@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgUnit is a node in the university's organizational hierarchy:
+// university, college, department or research center
+type OrgUnit struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ParentID    *uuid.UUID `json:"parent_id" db:"parent_id"`
+	Kind        string     `json:"kind" db:"kind"`
+	Name        string     `json:"name" db:"name"`
+	NameAmharic string     `json:"name_amharic" db:"name_amharic"`
+	Code        string     `json:"code" db:"code"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	Children []OrgUnit `json:"children,omitempty" db:"-"`
+}
+
+type OrgUnitRequest struct {
+	ParentID    *uuid.UUID `json:"parent_id"`
+	Kind        string     `json:"kind" binding:"required,oneof=university college department research_center"`
+	Name        string     `json:"name" binding:"required,max=255"`
+	NameAmharic string     `json:"name_amharic" binding:"max=255"`
+	Code        string     `json:"code" binding:"max=50"`
+}
+
+// SetUserOrgUnitRequest places a user in a unit and, for editors and
+// coordinators, limits what they see to a unit and everything below it.
+// Null values clear the assignment.
+type SetUserOrgUnitRequest struct {
+	OrgUnitID      *uuid.UUID `json:"org_unit_id"`
+	ScopeOrgUnitID *uuid.UUID `json:"scope_org_unit_id"`
+}
+
+type SetPaperOrgUnitRequest struct {
+	OrgUnitID *uuid.UUID `json:"org_unit_id"`
+}
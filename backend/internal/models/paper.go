@@ -28,6 +28,7 @@ type Paper struct {
 	JournalType             string     `json:"journal_type" db:"journal_type"`
 	JournalName             string     `json:"journal_name" db:"journal_name"`
 	IndigenousKnowledge     bool       `json:"indigenous_knowledge" db:"indigenous_knowledge"`
+	DOI                     string     `json:"doi" db:"doi"`
 
 	// Research Project Fields
 	FiscalYear               string  `json:"fiscal_year" db:"fiscal_year"`
@@ -48,6 +49,24 @@ type Paper struct {
 	ProducedPrototype        string  `json:"produced_prototype" db:"produced_prototype"`
 	HetrilCollaboration      string  `json:"hetril_collaboration" db:"hetril_collaboration"`
 	SubmittedToIncubator     string  `json:"submitted_to_incubator" db:"submitted_to_incubator"`
+
+	// An approved paper is published automatically at ScheduledPublishAt.
+	// Until EmbargoUntil only its metadata is public, not the full text.
+	ScheduledPublishAt *time.Time `json:"scheduled_publish_at" db:"scheduled_publish_at"`
+	EmbargoUntil       *time.Time `json:"embargo_until" db:"embargo_until"`
+
+	// Unit the paper belongs to; papers without one count under the author's unit
+	OrgUnitID *uuid.UUID `json:"org_unit_id" db:"org_unit_id"`
+
+	RejectedAt       *time.Time `json:"rejected_at" db:"rejected_at"`
+	WithdrawnAt      *time.Time `json:"withdrawn_at" db:"withdrawn_at"`
+	WithdrawalReason string     `json:"withdrawal_reason" db:"withdrawal_reason"`
+	RetractedAt      *time.Time `json:"retracted_at" db:"retracted_at"`
+	RetractionNotice string     `json:"retraction_notice" db:"retraction_notice"`
+
+	// Ethiopian calendar dates, only filled when the request asks for calendar=ethiopian
+	CreatedAtEthiopian       string `json:"created_at_ethiopian,omitempty" db:"-"`
+	PublicationDateEthiopian string `json:"publication_date_ethiopian,omitempty" db:"-"`
 }
 
 type CreatePaperRequest struct {
@@ -80,6 +99,10 @@ type UpdatePaperRequest struct {
 	JournalType             string    `json:"journal_type"`
 	JournalName             string    `json:"journal_name"`
 	IndigenousKnowledge     bool      `json:"indigenous_knowledge"`
+	DOI                     string    `json:"doi"`
+
+	// Ethiopian publication date (YYYY-MM-DD), takes precedence over PublicationDate when set
+	PublicationDateEthiopian string `json:"publication_date_ethiopian"`
 
 	// Research Project Fields
 	FiscalYear               string  `json:"fiscal_year"`
@@ -158,3 +181,37 @@ func (p *Paper) CanSubmit() bool {
 func (p *Paper) CanReview() bool {
 	return p.IsSubmitted() || p.IsUnderReview()
 }
+
+// ISCEDBands lists the ISCED-F 2013 broad fields used for publication_isced_band
+var ISCEDBands = map[string]string{
+	"00": "Generic programmes and qualifications",
+	"01": "Education",
+	"02": "Arts and humanities",
+	"03": "Social sciences, journalism and information",
+	"04": "Business, administration and law",
+	"05": "Natural sciences, mathematics and statistics",
+	"06": "Information and Communication Technologies",
+	"07": "Engineering, manufacturing and construction",
+	"08": "Agriculture, forestry, fisheries and veterinary",
+	"09": "Health and welfare",
+	"10": "Services",
+}
+
+// PaperStatuses lists every status a paper can be in
+var PaperStatuses = []string{"draft", "submitted", "under_review", "approved", "rejected", "published", "recommended_for_publication", "withdrawn", "retracted"}
+
+// SchedulePaperRequest sets or clears (with null) a paper's scheduled
+// publication time and full-text embargo
+type SchedulePaperRequest struct {
+	PublishAt    *time.Time `json:"publish_at"`
+	EmbargoUntil *time.Time `json:"embargo_until"`
+}
+
+type WithdrawPaperRequest struct {
+	Reason string `json:"reason" binding:"max=2000"`
+}
+
+// RetractPaperRequest carries the public notice shown alongside a retracted paper
+type RetractPaperRequest struct {
+	Notice string `json:"notice" binding:"required,min=20,max=5000"`
+}
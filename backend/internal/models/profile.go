@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResearcherProfile is a researcher's record assembled for their profile page
+// and CV. The public version only carries published items and leaves the
+// personal fields empty; the private version is the complete promotion dossier.
+type ResearcherProfile struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email,omitempty"`
+	Avatar         string    `json:"avatar"`
+	Bio            string    `json:"bio"`
+	AcademicRank   string    `json:"academic_rank"`
+	Qualification  string    `json:"qualification"`
+	AuthorCategory string    `json:"author_category"`
+	ORCID          string    `json:"orcid,omitempty"`
+	EmploymentType string    `json:"employment_type,omitempty"`
+	Gender         string    `json:"gender,omitempty"`
+	DateOfBirth    string    `json:"date_of_birth,omitempty"`
+
+	Public      bool      `json:"public"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	Publications []ProfilePublication `json:"publications"`
+	Projects     []ProfileProject     `json:"projects"`
+	Reviews      []ProfileReview      `json:"reviews"`
+	Events       []ProfileEvent       `json:"events"`
+}
+
+type ProfilePublication struct {
+	ID              uuid.UUID  `json:"id"`
+	Title           string     `json:"title"`
+	Status          string     `json:"status"`
+	PublicationID   string     `json:"publication_id,omitempty"`
+	PublicationType string     `json:"publication_type,omitempty"`
+	JournalName     string     `json:"journal_name,omitempty"`
+	PublicationDate *time.Time `json:"publication_date,omitempty"`
+	DOI             string     `json:"doi,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ProfileProject is a paper that received research funding
+type ProfileProject struct {
+	ID               uuid.UUID `json:"id"`
+	Title            string    `json:"title"`
+	Status           string    `json:"status"`
+	FiscalYear       string    `json:"fiscal_year"`
+	ResearchType     string    `json:"research_type,omitempty"`
+	CompletionStatus string    `json:"completion_status,omitempty"`
+	AllocatedBudget  float64   `json:"allocated_budget"`
+	ExternalBudget   float64   `json:"external_budget"`
+	NRFFund          float64   `json:"nrf_fund"`
+}
+
+// TotalBudget is the sum of every funding source of the project
+func (p ProfileProject) TotalBudget() float64 {
+	return p.AllocatedBudget + p.ExternalBudget + p.NRFFund
+}
+
+type ProfileReview struct {
+	PaperID        uuid.UUID `json:"paper_id"`
+	PaperTitle     string    `json:"paper_title"`
+	Recommendation string    `json:"recommendation,omitempty"`
+	ReviewedAt     time.Time `json:"reviewed_at"`
+}
+
+type ProfileEvent struct {
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	Category string    `json:"category"`
+	Status   string    `json:"status"`
+	Date     time.Time `json:"date"`
+	Location string    `json:"location"`
+}
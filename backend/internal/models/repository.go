@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RepositoryRecord is the public, export-ready view of a published paper
+type RepositoryRecord struct {
+	ID                      uuid.UUID      `json:"id"`
+	PublicationID           string         `json:"publication_id"`
+	Title                   string         `json:"title"`
+	PublicationTitleAmharic string         `json:"publication_title_amharic,omitempty"`
+	Abstract                string         `json:"abstract"`
+	Type                    string         `json:"type"`
+	PublicationType         string         `json:"publication_type"`
+	JournalName             string         `json:"journal_name"`
+	Volume                  string         `json:"volume,omitempty"`
+	Issue                   string         `json:"issue,omitempty"`
+	Pages                   string         `json:"pages,omitempty"`
+	PublicationDate         *time.Time     `json:"publication_date"`
+	DOI                     string         `json:"doi,omitempty"`
+	ISCEDBand               string         `json:"publication_isced_band,omitempty"`
+	FileUrl                 string         `json:"file_url,omitempty"`
+	EmbargoUntil            *time.Time     `json:"embargo_until,omitempty"`
+	Retracted               bool           `json:"retracted"`
+	RetractedAt             *time.Time     `json:"retracted_at,omitempty"`
+	RetractionNotice        string         `json:"retraction_notice,omitempty"`
+	Authors                 []RecordAuthor `json:"authors"`
+}
+
+// RecordAuthor is an author as it appears in citations and exports
+type RecordAuthor struct {
+	Name        string `json:"name"`
+	Affiliation string `json:"affiliation,omitempty"`
+	ORCID       string `json:"orcid,omitempty"`
+	ORCIDURI    string `json:"orcid_uri,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role is a named set of permissions. Built-in roles are defined in code and
+// have no ID; custom roles belong to a tenant.
+type Role struct {
+	ID          *uuid.UUID `json:"id,omitempty" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Label       string     `json:"label" db:"label"`
+	Description string     `json:"description" db:"description"`
+	Permissions []string   `json:"permissions" db:"permissions"`
+	BuiltIn     bool       `json:"built_in"`
+	// Users holding the role in the tenant
+	UserCount int        `json:"user_count"`
+	CreatedAt *time.Time `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// RoleRequest creates or changes a custom role; the name cannot change
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"max=50"`
+	Label       string   `json:"label" binding:"required,max=255"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required,max=50"`
+}
+
+// SetUserRolesRequest replaces every role of a user; the first one becomes
+// the primary role
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1,max=10,dive,required,max=50"`
+}
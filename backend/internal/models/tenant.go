@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tenant is a university sharing this deployment. Its users, papers, events,
+// news and messages are invisible to other tenants. Empty branding fields
+// fall back to the DOC_* settings of the deployment.
+type Tenant struct {
+	ID                    uuid.UUID `json:"id" db:"id"`
+	Slug                  string    `json:"slug" db:"slug"`
+	Name                  string    `json:"name" db:"name"`
+	Domain                string    `json:"domain" db:"domain"`
+	PublicationIDPrefix   string    `json:"publication_id_prefix" db:"publication_id_prefix"`
+	UniversityName        string    `json:"university_name" db:"university_name"`
+	UniversityNameAmharic string    `json:"university_name_amharic" db:"university_name_amharic"`
+	OfficeName            string    `json:"office_name" db:"office_name"`
+	OfficeNameAmharic     string    `json:"office_name_amharic" db:"office_name_amharic"`
+	Address               string    `json:"address" db:"address"`
+	LogoPath              string    `json:"logo_path" db:"logo_path"`
+	EmailSenderName       string    `json:"email_sender_name" db:"email_sender_name"`
+	EmailSenderAddress    string    `json:"email_sender_address" db:"email_sender_address"`
+	Active                bool      `json:"active" db:"active"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type TenantRequest struct {
+	Slug                  string `json:"slug" binding:"required,max=50"`
+	Name                  string `json:"name" binding:"required,max=255"`
+	Domain                string `json:"domain" binding:"max=255"`
+	PublicationIDPrefix   string `json:"publication_id_prefix" binding:"required,max=20"`
+	UniversityName        string `json:"university_name" binding:"max=255"`
+	UniversityNameAmharic string `json:"university_name_amharic" binding:"max=255"`
+	OfficeName            string `json:"office_name" binding:"max=255"`
+	OfficeNameAmharic     string `json:"office_name_amharic" binding:"max=255"`
+	Address               string `json:"address"`
+	LogoPath              string `json:"logo_path"`
+	EmailSenderName       string `json:"email_sender_name" binding:"max=255"`
+	EmailSenderAddress    string `json:"email_sender_address" binding:"omitempty,email,max=255"`
+	Active                *bool  `json:"active"`
+}
+
+// CreateTenantAdminRequest adds an admin to a tenant
+type CreateTenantAdminRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Name     string `json:"name" binding:"required,max=255"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ type User struct {
 	PasswordHash     string                 `json:"-" db:"password_hash"`
 	Name             string                 `json:"name" db:"name"`
 	Role             string                 `json:"role" db:"role"`
+	Roles            []string               `json:"roles" db:"roles"`
 	Avatar           string                 `json:"avatar" db:"avatar"`
 	Bio              string                 `json:"bio" db:"bio"`
 	Preferences      map[string]interface{} `json:"preferences" db:"preferences"`
@@ -29,13 +31,24 @@ type User struct {
 	EmploymentType string `json:"employment_type" db:"employment_type"`
 	Gender         string `json:"gender" db:"gender"`
 	DateOfBirth    string `json:"date_of_birth" db:"date_of_birth"`
+
+	// ORCID iD in 0000-0000-0000-0000 form
+	ORCID string `json:"orcid" db:"orcid"`
+
+	// Unit the user belongs to, and for editors and coordinators the unit
+	// their lists, reports and notifications are limited to
+	OrgUnitID      *uuid.UUID `json:"org_unit_id" db:"org_unit_id"`
+	ScopeOrgUnitID *uuid.UUID `json:"scope_org_unit_id" db:"scope_org_unit_id"`
+
+	// Tenant (university) the user belongs to
+	TenantID uuid.UUID `json:"tenant_id" db:"tenant_id"`
 }
 
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
-	Role     string `json:"role" binding:"required,oneof=author editor admin coordinator"`
+	Role     string `json:"role" binding:"required,max=50"`
 
 	// Author Profile Fields (Optional for non-authors, but we'll handle validation in handler or bind if needed)
 	AcademicYear   string `json:"academic_year"`
@@ -53,6 +66,25 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LDAPLoginRequest signs in with a directory account name (or email) and
+// password
+type LDAPLoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SSOExchangeRequest trades the one-time code of an OpenID Connect sign-in
+// for a session
+type SSOExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SSOProviders says which single sign-on options the deployment offers
+type SSOProviders struct {
+	OIDC bool `json:"oidc"`
+	LDAP bool `json:"ldap"`
+}
+
 type VerifyEmailRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Code  string `json:"code" binding:"required"`
@@ -63,8 +95,27 @@ type ResendCodeRequest struct {
 }
 
 type LoginResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User         User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// Seconds until Token expires
+	ExpiresIn int `json:"expires_in"`
+	// The role needs two-factor authentication and the user has not
+	// enrolled; other endpoints refuse the token until they do
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type UpdateProfileRequest struct {
@@ -72,6 +123,7 @@ type UpdateProfileRequest struct {
 	Avatar      string                 `json:"avatar"`
 	Bio         string                 `json:"bio"`
 	Preferences map[string]interface{} `json:"preferences"`
+	ORCID       string                 `json:"orcid"`
 }
 
 type ChangePasswordRequest struct {
@@ -79,22 +131,31 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
+// AllRoles returns every role of the user; users loaded without their
+// roles column only have the primary one
+func (u *User) AllRoles() []string {
+	if len(u.Roles) == 0 {
+		return []string{u.Role}
+	}
+	return u.Roles
+}
+
 func (u *User) IsRole(role string) bool {
-	return u.Role == role
+	return slices.Contains(u.AllRoles(), role)
 }
 
 func (u *User) IsAuthor() bool {
-	return u.Role == "author"
+	return u.IsRole("author")
 }
 
 func (u *User) IsEditor() bool {
-	return u.Role == "editor"
+	return u.IsRole("editor")
 }
 
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return u.IsRole("admin")
 }
 
 func (u *User) IsCoordinator() bool {
-	return u.Role == "coordinator"
+	return u.IsRole("coordinator")
 }
@@ -0,0 +1,65 @@
+package orcid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ORCID iDs are 16 characters written in four dash separated blocks.
+// The last character is an ISO 7064 MOD 11-2 check digit, where 10 is written as X.
+
+const baseURI = "https://orcid.org/"
+
+// Normalize accepts an ORCID iD with or without the https://orcid.org/ prefix
+// and dashes, validates its checksum and returns it as 0000-0000-0000-0000.
+func Normalize(raw string) (string, error) {
+	id := strings.TrimSpace(raw)
+	for _, prefix := range []string{"https://orcid.org/", "http://orcid.org/", "orcid.org/"} {
+		if strings.HasPrefix(strings.ToLower(id), prefix) {
+			id = id[len(prefix):]
+			break
+		}
+	}
+	id = strings.ToUpper(strings.ReplaceAll(id, "-", ""))
+
+	if len(id) != 16 {
+		return "", fmt.Errorf("invalid ORCID iD %q: expected 16 characters", raw)
+	}
+	for i, ch := range id[:15] {
+		if ch < '0' || ch > '9' {
+			return "", fmt.Errorf("invalid ORCID iD %q: unexpected character at position %d", raw, i+1)
+		}
+	}
+	if CheckDigit(id[:15]) != id[15] {
+		return "", fmt.Errorf("invalid ORCID iD %q: checksum does not match", raw)
+	}
+
+	return id[0:4] + "-" + id[4:8] + "-" + id[8:12] + "-" + id[12:16], nil
+}
+
+// Validate reports whether raw is a well formed ORCID iD with a valid checksum
+func Validate(raw string) error {
+	_, err := Normalize(raw)
+	return err
+}
+
+// CheckDigit computes the ISO 7064 MOD 11-2 check character for the 15 base digits
+func CheckDigit(baseDigits string) byte {
+	total := 0
+	for _, ch := range baseDigits {
+		total = (total + int(ch-'0')) * 2
+	}
+	result := (12 - total%11) % 11
+	if result == 10 {
+		return 'X'
+	}
+	return byte('0' + result)
+}
+
+// URI returns the canonical https form of a normalized ORCID iD
+func URI(id string) string {
+	if id == "" {
+		return ""
+	}
+	return baseURI + id
+}
@@ -0,0 +1,27 @@
+package orcid
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	valid := map[string]string{
+		"0000-0002-1825-0097":                   "0000-0002-1825-0097",
+		"https://orcid.org/0000-0001-5109-3700": "0000-0001-5109-3700",
+		"000000021694233x":                      "0000-0002-1694-233X",
+	}
+	for in, want := range valid {
+		got, err := Normalize(in)
+		if err != nil {
+			t.Errorf("Normalize(%q) returned error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"0000-0002-1825-0098", "0000-0002-1825", "ABCD-0002-1825-0097", ""} {
+		if _, err := Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) accepted an invalid iD", in)
+		}
+	}
+}
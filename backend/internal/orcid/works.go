@@ -0,0 +1,178 @@
+package orcid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Work is the part of an ORCID work record RPMS keeps when importing
+type Work struct {
+	PutCode      int64      `json:"put_code"`
+	Title        string     `json:"title"`
+	JournalTitle string     `json:"journal_title"`
+	Abstract     string     `json:"abstract"`
+	Type         string     `json:"type"`
+	DOI          string     `json:"doi"`
+	Published    *time.Time `json:"published"`
+	Contributors []string   `json:"contributors"`
+}
+
+// The ORCID JSON schema wraps most scalars as {"value": ...}
+type valueString struct {
+	Value string `json:"value"`
+}
+
+type workJSON struct {
+	PutCode json.Number `json:"put-code"`
+	Title   *struct {
+		Title *valueString `json:"title"`
+	} `json:"title"`
+	JournalTitle     *valueString `json:"journal-title"`
+	ShortDescription string       `json:"short-description"`
+	Type             string       `json:"type"`
+	PublicationDate  *struct {
+		Year  *valueString `json:"year"`
+		Month *valueString `json:"month"`
+		Day   *valueString `json:"day"`
+	} `json:"publication-date"`
+	ExternalIDs *struct {
+		ExternalID []struct {
+			Type  string `json:"external-id-type"`
+			Value string `json:"external-id-value"`
+		} `json:"external-id"`
+	} `json:"external-ids"`
+	Contributors *struct {
+		Contributor []struct {
+			CreditName *valueString `json:"credit-name"`
+		} `json:"contributor"`
+	} `json:"contributors"`
+}
+
+type worksGroup struct {
+	Summaries []workJSON `json:"work-summary"`
+}
+
+// exportJSON covers the shapes ORCID hands out: the /works listing, a full
+// record, and a bulk /works/{put-codes} response.
+type exportJSON struct {
+	Group             []worksGroup `json:"group"`
+	ActivitiesSummary *struct {
+		Works *struct {
+			Group []worksGroup `json:"group"`
+		} `json:"works"`
+	} `json:"activities-summary"`
+	Bulk []struct {
+		Work *workJSON `json:"work"`
+	} `json:"bulk"`
+}
+
+// ParseWorks reads an ORCID works JSON export. It works entirely offline on the
+// file's contents. Works listed under several sources are returned once.
+func ParseWorks(r io.Reader) ([]Work, error) {
+	var doc exportJSON
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse ORCID works JSON: %w", err)
+	}
+
+	var raw []workJSON
+	groups := doc.Group
+	if doc.ActivitiesSummary != nil && doc.ActivitiesSummary.Works != nil {
+		groups = append(groups, doc.ActivitiesSummary.Works.Group...)
+	}
+	for _, g := range groups {
+		// Every summary in a group describes the same work; the first is the preferred source
+		if len(g.Summaries) > 0 {
+			raw = append(raw, g.Summaries[0])
+		}
+	}
+	for _, b := range doc.Bulk {
+		if b.Work != nil {
+			raw = append(raw, *b.Work)
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no works found in ORCID export")
+	}
+
+	works := make([]Work, 0, len(raw))
+	for _, w := range raw {
+		works = append(works, w.toWork())
+	}
+	return works, nil
+}
+
+func (w workJSON) toWork() Work {
+	work := Work{
+		Abstract: strings.TrimSpace(w.ShortDescription),
+		Type:     w.Type,
+	}
+	if pc, err := w.PutCode.Int64(); err == nil {
+		work.PutCode = pc
+	}
+	if w.Title != nil && w.Title.Title != nil {
+		work.Title = strings.TrimSpace(w.Title.Title.Value)
+	}
+	if w.JournalTitle != nil {
+		work.JournalTitle = strings.TrimSpace(w.JournalTitle.Value)
+	}
+	if w.ExternalIDs != nil {
+		for _, id := range w.ExternalIDs.ExternalID {
+			if strings.EqualFold(id.Type, "doi") {
+				work.DOI = strings.TrimSpace(id.Value)
+				break
+			}
+		}
+	}
+	if w.Contributors != nil {
+		for _, c := range w.Contributors.Contributor {
+			if c.CreditName != nil && c.CreditName.Value != "" {
+				work.Contributors = append(work.Contributors, c.CreditName.Value)
+			}
+		}
+	}
+	if d := w.PublicationDate; d != nil && d.Year != nil {
+		year, err := strconv.Atoi(d.Year.Value)
+		if err == nil {
+			month, day := 1, 1
+			if d.Month != nil {
+				if m, err := strconv.Atoi(d.Month.Value); err == nil && m >= 1 && m <= 12 {
+					month = m
+				}
+			}
+			if d.Day != nil {
+				if v, err := strconv.Atoi(d.Day.Value); err == nil && v >= 1 && v <= 31 {
+					day = v
+				}
+			}
+			t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+			work.Published = &t
+		}
+	}
+	return work
+}
+
+// PublicationType maps an ORCID work type onto the publication types used by RPMS
+func PublicationType(workType string) string {
+	switch workType {
+	case "journal-article", "journal-issue":
+		return "Journal Article"
+	case "conference-paper", "conference-abstract", "conference-poster":
+		return "Conference Paper"
+	case "book", "edited-book":
+		return "Book"
+	case "book-chapter":
+		return "Book Chapter"
+	case "dissertation", "dissertation-thesis":
+		return "Thesis"
+	default:
+		// Left blank for an editor to classify
+		return ""
+	}
+}
@@ -0,0 +1,196 @@
+// Package orgunit holds the rules of the organizational hierarchy
+// (university, colleges, departments and research centers) and rolls
+// figures up through it.
+package orgunit
+
+import (
+	"fmt"
+	"sort"
+
+	"rpms-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Unit kinds
+const (
+	KindUniversity     = "university"
+	KindCollege        = "college"
+	KindDepartment     = "department"
+	KindResearchCenter = "research_center"
+)
+
+// parents lists the kinds each kind may sit under. A university is the root.
+var parents = map[string][]string{
+	KindUniversity:     nil,
+	KindCollege:        {KindUniversity},
+	KindDepartment:     {KindCollege},
+	KindResearchCenter: {KindUniversity, KindCollege},
+}
+
+// CheckParent reports whether a unit of the given kind may sit under a parent
+// of parentKind. An empty parentKind means the unit has no parent.
+func CheckParent(kind, parentKind string) error {
+	allowed, ok := parents[kind]
+	if !ok {
+		return fmt.Errorf("unknown unit kind %q", kind)
+	}
+	if parentKind == "" {
+		if len(allowed) > 0 {
+			return fmt.Errorf("a %s needs a parent unit", Label(kind))
+		}
+		return nil
+	}
+	for _, k := range allowed {
+		if k == parentKind {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("a %s cannot have a parent unit", Label(kind))
+	}
+	return fmt.Errorf("a %s cannot be placed under a %s", Label(kind), Label(parentKind))
+}
+
+// Label returns the human readable name of a kind
+func Label(kind string) string {
+	if kind == KindResearchCenter {
+		return "research center"
+	}
+	return kind
+}
+
+// Subtree returns root and every unit below it
+func Subtree(units []models.OrgUnit, root uuid.UUID) []uuid.UUID {
+	children := childIndex(units)
+	ids := []uuid.UUID{root}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// Ancestor returns the unit of the given kind that id is or sits under, or nil
+func Ancestor(units []models.OrgUnit, id uuid.UUID, kind string) *uuid.UUID {
+	byID := make(map[uuid.UUID]models.OrgUnit, len(units))
+	for _, u := range units {
+		byID[u.ID] = u
+	}
+	// Bounded by the number of units in case the data has a cycle
+	for i := 0; i <= len(units); i++ {
+		u, ok := byID[id]
+		if !ok {
+			return nil
+		}
+		if u.Kind == kind {
+			return &u.ID
+		}
+		if u.ParentID == nil {
+			return nil
+		}
+		id = *u.ParentID
+	}
+	return nil
+}
+
+// Tree nests units under their parents, sorted by name. Units whose parent is
+// not in the list become roots.
+func Tree(units []models.OrgUnit) []models.OrgUnit {
+	byID := make(map[uuid.UUID]models.OrgUnit, len(units))
+	for _, u := range units {
+		byID[u.ID] = u
+	}
+	children := childIndex(units)
+
+	var build func(id uuid.UUID) models.OrgUnit
+	build = func(id uuid.UUID) models.OrgUnit {
+		u := byID[id]
+		u.Children = nil
+		for _, child := range children[id] {
+			u.Children = append(u.Children, build(child))
+		}
+		return u
+	}
+
+	roots := []models.OrgUnit{}
+	for _, u := range sortedUnits(units) {
+		if u.ParentID == nil || !hasUnit(byID, *u.ParentID) {
+			roots = append(roots, build(u.ID))
+		}
+	}
+	return roots
+}
+
+// Totals are the figures reported for a unit
+type Totals struct {
+	Researchers     int     `json:"researchers"`
+	Papers          int     `json:"papers"`
+	Published       int     `json:"published"`
+	AllocatedBudget float64 `json:"allocated_budget"`
+	ExternalBudget  float64 `json:"external_budget"`
+	NRFFund         float64 `json:"nrf_fund"`
+}
+
+func (t *Totals) Add(o Totals) {
+	t.Researchers += o.Researchers
+	t.Papers += o.Papers
+	t.Published += o.Published
+	t.AllocatedBudget += o.AllocatedBudget
+	t.ExternalBudget += o.ExternalBudget
+	t.NRFFund += o.NRFFund
+}
+
+// Report is a unit with its own figures and the figures of its whole subtree
+type Report struct {
+	ID       uuid.UUID  `json:"id"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	Kind     string     `json:"kind"`
+	Name     string     `json:"name"`
+	Code     string     `json:"code"`
+	Own      Totals     `json:"own"`
+	Total    Totals     `json:"total"`
+	Children []Report   `json:"children,omitempty"`
+}
+
+// RollUp builds the report tree for units from the figures recorded directly
+// against each unit
+func RollUp(units []models.OrgUnit, own map[uuid.UUID]Totals) []Report {
+	var build func(u models.OrgUnit) Report
+	build = func(u models.OrgUnit) Report {
+		r := Report{ID: u.ID, ParentID: u.ParentID, Kind: u.Kind, Name: u.Name, Code: u.Code, Own: own[u.ID]}
+		r.Total = r.Own
+		for _, child := range u.Children {
+			cr := build(child)
+			r.Total.Add(cr.Total)
+			r.Children = append(r.Children, cr)
+		}
+		return r
+	}
+
+	reports := []Report{}
+	for _, root := range Tree(units) {
+		reports = append(reports, build(root))
+	}
+	return reports
+}
+
+func childIndex(units []models.OrgUnit) map[uuid.UUID][]uuid.UUID {
+	children := map[uuid.UUID][]uuid.UUID{}
+	for _, u := range sortedUnits(units) {
+		if u.ParentID != nil {
+			children[*u.ParentID] = append(children[*u.ParentID], u.ID)
+		}
+	}
+	return children
+}
+
+func sortedUnits(units []models.OrgUnit) []models.OrgUnit {
+	sorted := append([]models.OrgUnit(nil), units...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func hasUnit(byID map[uuid.UUID]models.OrgUnit, id uuid.UUID) bool {
+	_, ok := byID[id]
+	return ok
+}
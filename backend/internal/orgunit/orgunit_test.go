@@ -0,0 +1,96 @@
+package orgunit
+
+import (
+	"testing"
+
+	"rpms-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func sampleUnits() (uni, college, dept, center models.OrgUnit) {
+	uni = models.OrgUnit{ID: uuid.New(), Kind: KindUniversity, Name: "St. Mary's University"}
+	college = models.OrgUnit{ID: uuid.New(), ParentID: &uni.ID, Kind: KindCollege, Name: "College of Business"}
+	dept = models.OrgUnit{ID: uuid.New(), ParentID: &college.ID, Kind: KindDepartment, Name: "Accounting"}
+	center = models.OrgUnit{ID: uuid.New(), ParentID: &uni.ID, Kind: KindResearchCenter, Name: "Centre for Distance Learning"}
+	return
+}
+
+func TestCheckParent(t *testing.T) {
+	cases := []struct {
+		kind, parent string
+		ok           bool
+	}{
+		{KindUniversity, "", true},
+		{KindUniversity, KindCollege, false},
+		{KindCollege, KindUniversity, true},
+		{KindCollege, "", false},
+		{KindDepartment, KindCollege, true},
+		{KindDepartment, KindUniversity, false},
+		{KindResearchCenter, KindUniversity, true},
+		{KindResearchCenter, KindCollege, true},
+		{KindResearchCenter, KindDepartment, false},
+		{"faculty", KindUniversity, false},
+	}
+	for _, tc := range cases {
+		if err := CheckParent(tc.kind, tc.parent); (err == nil) != tc.ok {
+			t.Errorf("CheckParent(%q, %q) = %v", tc.kind, tc.parent, err)
+		}
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	uni, college, dept, center := sampleUnits()
+	units := []models.OrgUnit{dept, center, college, uni}
+
+	if got := Subtree(units, college.ID); len(got) != 2 || got[0] != college.ID || got[1] != dept.ID {
+		t.Errorf("Subtree(college) = %v", got)
+	}
+	if got := Subtree(units, uni.ID); len(got) != 4 {
+		t.Errorf("Subtree(university) has %d units, want 4", len(got))
+	}
+}
+
+func TestRollUp(t *testing.T) {
+	uni, college, dept, center := sampleUnits()
+	units := []models.OrgUnit{uni, college, dept, center}
+	own := map[uuid.UUID]Totals{
+		college.ID: {Papers: 1},
+		dept.ID:    {Researchers: 3, Papers: 4, Published: 2, AllocatedBudget: 1000},
+		center.ID:  {Papers: 2, NRFFund: 500},
+	}
+
+	reports := RollUp(units, own)
+	if len(reports) != 1 {
+		t.Fatalf("got %d roots, want 1", len(reports))
+	}
+	root := reports[0]
+	if root.Total.Papers != 7 || root.Total.Published != 2 || root.Total.AllocatedBudget != 1000 || root.Total.NRFFund != 500 {
+		t.Errorf("university totals = %+v", root.Total)
+	}
+	if root.Own.Papers != 0 {
+		t.Errorf("university own totals = %+v", root.Own)
+	}
+	// Children are sorted by name
+	if len(root.Children) != 2 || root.Children[0].ID != center.ID || root.Children[1].Total.Papers != 5 {
+		t.Errorf("unexpected children: %+v", root.Children)
+	}
+}
+
+func TestAncestor(t *testing.T) {
+	uni, college, dept, center := sampleUnits()
+	units := []models.OrgUnit{uni, college, dept, center}
+
+	if got := Ancestor(units, dept.ID, KindCollege); got == nil || *got != college.ID {
+		t.Errorf("college of the department = %v", got)
+	}
+	if got := Ancestor(units, dept.ID, KindDepartment); got == nil || *got != dept.ID {
+		t.Errorf("a department is its own department, got %v", got)
+	}
+	if got := Ancestor(units, center.ID, KindCollege); got != nil {
+		t.Errorf("a university research center has no college, got %v", got)
+	}
+	if got := Ancestor(units, uuid.New(), KindUniversity); got != nil {
+		t.Errorf("unknown unit should have no ancestor, got %v", got)
+	}
+}
@@ -0,0 +1,149 @@
+// Package profile renders researcher profiles and CVs as HTML and PDF.
+package profile
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"rpms-backend/internal/models"
+)
+
+var funcs = template.FuncMap{
+	"date":  formatDate,
+	"birr":  FormatBirr,
+	"label": label,
+}
+
+var htmlTemplate = template.Must(template.New("profile").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - Researcher Profile</title>
+<style>
+body { font-family: "Segoe UI", Arial, sans-serif; max-width: 860px; margin: 2rem auto; color: #222; line-height: 1.5; }
+header { display: flex; gap: 1.5rem; align-items: center; border-bottom: 2px solid #1e3a8a; padding-bottom: 1rem; }
+header img { width: 110px; height: 110px; border-radius: 50%; object-fit: cover; }
+h1 { margin: 0; color: #1e3a8a; }
+h2 { color: #1e3a8a; border-bottom: 1px solid #ddd; padding-bottom: .25rem; margin-top: 2rem; }
+.muted { color: #666; }
+.retracted { color: #b91c1c; font-weight: bold; font-size: .8rem; }
+table { width: 100%; border-collapse: collapse; font-size: .95rem; }
+th, td { text-align: left; padding: .4rem; border-bottom: 1px solid #eee; vertical-align: top; }
+td.num, th.num { text-align: right; }
+footer { margin-top: 2rem; font-size: .8rem; color: #888; }
+</style>
+</head>
+<body>
+<header>
+{{if .Avatar}}<img src="{{.Avatar}}" alt="{{.Name}}">{{end}}
+<div>
+<h1>{{.Name}}</h1>
+{{if .AcademicRank}}<div>{{.AcademicRank}}</div>{{end}}
+{{if .Qualification}}<div class="muted">{{.Qualification}}</div>{{end}}
+{{if .ORCID}}<div>ORCID: <a href="https://orcid.org/{{.ORCID}}">{{.ORCID}}</a></div>{{end}}
+{{if not .Public}}<div class="muted">{{.Email}}{{if .EmploymentType}} · {{.EmploymentType}}{{end}}{{if .Gender}} · {{.Gender}}{{end}}{{if .DateOfBirth}} · Born {{.DateOfBirth}}{{end}}</div>{{end}}
+</div>
+</header>
+
+{{if .Bio}}<h2>Biography</h2>
+<p>{{.Bio}}</p>{{end}}
+
+<h2>Publications</h2>
+{{if .Publications}}<table>
+<tr><th>Title</th><th>Venue</th><th>Date</th>{{if not .Public}}<th>Status</th>{{end}}</tr>
+{{range .Publications}}<tr>
+<td>{{if eq .Status "retracted"}}<span class="retracted">RETRACTED</span> {{end}}{{.Title}}{{if .DOI}}<br><a class="muted" href="https://doi.org/{{.DOI}}">doi:{{.DOI}}</a>{{end}}</td>
+<td>{{.JournalName}}{{if .PublicationType}}<br><span class="muted">{{.PublicationType}}</span>{{end}}</td>
+<td>{{if .PublicationDate}}{{date .PublicationDate}}{{end}}</td>
+{{if not $.Public}}<td>{{label .Status}}</td>{{end}}
+</tr>
+{{end}}</table>{{else}}<p class="muted">No publications.</p>{{end}}
+
+<h2>Funded Projects</h2>
+{{if .Projects}}<table>
+<tr><th>Project</th><th>Fiscal year</th><th class="num">Allocated</th><th class="num">External</th><th class="num">NRF</th><th class="num">Total</th></tr>
+{{range .Projects}}<tr>
+<td>{{.Title}}{{if .CompletionStatus}}<br><span class="muted">{{.CompletionStatus}}</span>{{end}}</td>
+<td>{{.FiscalYear}}</td>
+<td class="num">{{birr .AllocatedBudget}}</td>
+<td class="num">{{birr .ExternalBudget}}</td>
+<td class="num">{{birr .NRFFund}}</td>
+<td class="num">{{birr .TotalBudget}}</td>
+</tr>
+{{end}}</table>{{else}}<p class="muted">No funded projects.</p>{{end}}
+
+<h2>Reviews Performed</h2>
+{{if .Reviews}}<table>
+<tr><th>Paper</th><th>Date</th>{{if not .Public}}<th>Recommendation</th>{{end}}</tr>
+{{range .Reviews}}<tr>
+<td>{{.PaperTitle}}</td>
+<td>{{date .ReviewedAt}}</td>
+{{if not $.Public}}<td>{{label .Recommendation}}</td>{{end}}
+</tr>
+{{end}}</table>{{else}}<p class="muted">No reviews.</p>{{end}}
+
+<h2>Events Organized</h2>
+{{if .Events}}<table>
+<tr><th>Event</th><th>Category</th><th>Date</th><th>Location</th></tr>
+{{range .Events}}<tr>
+<td>{{.Title}}</td>
+<td>{{.Category}}</td>
+<td>{{date .Date}}</td>
+<td>{{.Location}}</td>
+</tr>
+{{end}}</table>{{else}}<p class="muted">No events.</p>{{end}}
+
+<footer>Generated by RPMS on {{date .GeneratedAt}}{{if not .Public}} · Complete record for promotion dossier{{end}}</footer>
+</body>
+</html>
+`))
+
+// RenderHTML writes the profile as a standalone HTML page
+func RenderHTML(w io.Writer, p *models.ResearcherProfile) error {
+	return htmlTemplate.Execute(w, p)
+}
+
+func formatDate(v interface{}) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format("2 Jan 2006")
+	case *time.Time:
+		if t != nil {
+			return t.Format("2 Jan 2006")
+		}
+	}
+	return ""
+}
+
+// FormatBirr formats an amount in Ethiopian Birr with thousands separators
+func FormatBirr(amount float64) string {
+	s := fmt.Sprintf("%.2f", amount)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+
+	var sb strings.Builder
+	for i, ch := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(ch)
+	}
+	out := "ETB " + sb.String() + frac
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
+
+// label turns a stored value such as "under_review" into "Under review"
+func label(s string) string {
+	s = strings.ReplaceAll(s, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
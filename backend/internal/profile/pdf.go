@@ -0,0 +1,244 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"rpms-backend/internal/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Avatar is an optional profile picture to place in the PDF header
+type Avatar struct {
+	Data []byte
+	// Type is the gofpdf image type: "PNG", "JPG" or "GIF"
+	Type string
+}
+
+// AvatarFromBytes detects the image type of downloaded avatar data. It returns
+// nil for formats the PDF writer cannot embed.
+func AvatarFromBytes(data []byte) *Avatar {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return &Avatar{Data: data, Type: "PNG"}
+	case "image/jpeg":
+		return &Avatar{Data: data, Type: "JPG"}
+	case "image/gif":
+		return &Avatar{Data: data, Type: "GIF"}
+	}
+	return nil
+}
+
+const (
+	pdfMargin = 15.0
+	pdfWidth  = 210.0 - 2*pdfMargin
+)
+
+// RenderPDF writes the profile as an A4 CV
+func RenderPDF(w io.Writer, p *models.ResearcherProfile, avatar *Avatar) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(p.Name+" - Curriculum Vitae", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	footer := "Generated by RPMS on " + formatDate(p.GeneratedAt)
+	if !p.Public {
+		footer += " - complete record for promotion dossier"
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(pdfWidth-30, 5, tr(footer), "", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	// Header
+	textX := pdfMargin
+	if avatar != nil {
+		opts := gofpdf.ImageOptions{ImageType: avatar.Type}
+		pdf.RegisterImageOptionsReader("avatar", opts, bytes.NewReader(avatar.Data))
+		if pdf.Ok() {
+			pdf.ImageOptions("avatar", pdfMargin, pdfMargin, 28, 28, false, opts, 0, "")
+			textX += 34
+		} else {
+			// An unreadable avatar should not stop the CV from rendering
+			pdf.ClearError()
+		}
+	}
+	pdf.SetXY(textX, pdfMargin)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.SetTextColor(30, 58, 138)
+	pdf.CellFormat(0, 10, tr(p.Name), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(34, 34, 34)
+	pdf.SetFont("Helvetica", "", 11)
+	for _, line := range []string{p.AcademicRank, p.Qualification, orcidLine(p.ORCID)} {
+		if line != "" {
+			pdf.SetX(textX)
+			pdf.CellFormat(0, 6, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	if !p.Public {
+		pdf.SetX(textX)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(102, 102, 102)
+		details := p.Email
+		for _, v := range []string{p.EmploymentType, p.Gender, bornLine(p.DateOfBirth)} {
+			if v != "" {
+				details += "  |  " + v
+			}
+		}
+		pdf.CellFormat(0, 5, tr(details), "", 1, "L", false, 0, "")
+		pdf.SetTextColor(34, 34, 34)
+	}
+	if avatar != nil && pdf.GetY() < pdfMargin+30 {
+		pdf.SetY(pdfMargin + 30)
+	}
+	pdf.Ln(2)
+	pdf.SetDrawColor(30, 58, 138)
+	pdf.Line(pdfMargin, pdf.GetY(), pdfMargin+pdfWidth, pdf.GetY())
+
+	section := func(title string) {
+		pdf.Ln(5)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.SetTextColor(30, 58, 138)
+		pdf.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+		pdf.SetTextColor(34, 34, 34)
+		pdf.SetFont("Helvetica", "", 10)
+	}
+	empty := func(text string) {
+		pdf.SetTextColor(102, 102, 102)
+		pdf.CellFormat(0, 6, text, "", 1, "L", false, 0, "")
+		pdf.SetTextColor(34, 34, 34)
+	}
+
+	if p.Bio != "" {
+		section("Biography")
+		pdf.MultiCell(0, 5, tr(p.Bio), "", "L", false)
+	}
+
+	section("Publications")
+	if len(p.Publications) == 0 {
+		empty("No publications.")
+	}
+	for i, pub := range p.Publications {
+		title := pub.Title
+		if pub.Status == "retracted" {
+			title = "RETRACTED: " + title
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, 5, tr(itemNumber(i)+title), "", "L", false)
+		pdf.SetFont("Helvetica", "", 9)
+		details := pub.JournalName
+		if pub.PublicationType != "" {
+			details = joinNonEmpty(", ", details, pub.PublicationType)
+		}
+		details = joinNonEmpty(", ", details, formatDate(pub.PublicationDate))
+		if pub.DOI != "" {
+			details = joinNonEmpty(", ", details, "doi:"+pub.DOI)
+		}
+		if !p.Public {
+			details = joinNonEmpty(" - ", details, label(pub.Status))
+		}
+		if details != "" {
+			pdf.MultiCell(0, 5, tr(details), "", "L", false)
+		}
+		pdf.Ln(1)
+	}
+
+	section("Funded Projects")
+	if len(p.Projects) == 0 {
+		empty("No funded projects.")
+	} else {
+		widths := []float64{70, 20, 22.5, 22.5, 22.5, 22.5}
+		header := []string{"Project", "Fiscal year", "Allocated", "External", "NRF", "Total"}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 235, 245)
+		for i, h := range header {
+			align := "R"
+			if i < 2 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 6, h, "B", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+		for _, proj := range p.Projects {
+			title := proj.Title
+			if r := []rune(title); len(r) > 45 {
+				title = string(r[:42]) + "..."
+			}
+			cells := []string{title, proj.FiscalYear, FormatBirr(proj.AllocatedBudget), FormatBirr(proj.ExternalBudget), FormatBirr(proj.NRFFund), FormatBirr(proj.TotalBudget())}
+			for i, v := range cells {
+				align := "R"
+				if i < 2 {
+					align = "L"
+				}
+				pdf.CellFormat(widths[i], 6, tr(v), "B", 0, align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+
+	section("Reviews Performed")
+	if len(p.Reviews) == 0 {
+		empty("No reviews.")
+	}
+	for i, r := range p.Reviews {
+		line := itemNumber(i) + r.PaperTitle + " (" + formatDate(r.ReviewedAt) + ")"
+		if !p.Public && r.Recommendation != "" {
+			line += " - " + label(r.Recommendation)
+		}
+		pdf.MultiCell(0, 5, tr(line), "", "L", false)
+	}
+
+	section("Events Organized")
+	if len(p.Events) == 0 {
+		empty("No events.")
+	}
+	for i, e := range p.Events {
+		line := itemNumber(i) + e.Title + " - " + joinNonEmpty(", ", e.Category, formatDate(e.Date), e.Location)
+		pdf.MultiCell(0, 5, tr(line), "", "L", false)
+	}
+
+	return pdf.Output(w)
+}
+
+func itemNumber(i int) string {
+	return strconv.Itoa(i+1) + ". "
+}
+
+func orcidLine(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "ORCID: https://orcid.org/" + id
+}
+
+func bornLine(dob string) string {
+	if dob == "" {
+		return ""
+	}
+	return "Born " + dob
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	out := ""
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if out != "" {
+			out += sep
+		}
+		out += p
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"rpms-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func sampleProfile(public bool) *models.ResearcherProfile {
+	published := time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)
+	return &models.ResearcherProfile{
+		ID:            uuid.New(),
+		Name:          "Abebe Kebede",
+		Email:         "abebe@example.edu.et",
+		AcademicRank:  "Associate Professor",
+		Qualification: "PhD",
+		Bio:           "Works on <soil> science.",
+		DateOfBirth:   "1980-01-01",
+		Public:        public,
+		GeneratedAt:   published,
+		Publications: []models.ProfilePublication{
+			{ID: uuid.New(), Title: "Teff yields in Tigray", Status: "published", JournalName: "EJAS", PublicationDate: &published, DOI: "10.1000/xyz"},
+		},
+		Projects: []models.ProfileProject{
+			{ID: uuid.New(), Title: "Irrigation study", FiscalYear: "2016 EFY", AllocatedBudget: 150000, NRFFund: 2500.5},
+		},
+		Reviews: []models.ProfileReview{{PaperID: uuid.New(), PaperTitle: "Coffee rust", Recommendation: "minor_revision", ReviewedAt: published}},
+		Events:  []models.ProfileEvent{{ID: uuid.New(), Title: "Research week", Category: "Conference", Date: published, Location: "Mekelle"}},
+	}
+}
+
+func TestFormatBirr(t *testing.T) {
+	cases := map[float64]string{
+		0:         "ETB 0.00",
+		999.5:     "ETB 999.50",
+		1000:      "ETB 1,000.00",
+		1234567.8: "ETB 1,234,567.80",
+		-2500:     "-ETB 2,500.00",
+	}
+	for in, want := range cases {
+		if got := FormatBirr(in); got != want {
+			t.Errorf("FormatBirr(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, sampleProfile(true)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Abebe Kebede", "Teff yields in Tigray", "ETB 152,500.50", "Research week", "&lt;soil&gt;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("public HTML missing %q", want)
+		}
+	}
+	for _, hidden := range []string{"abebe@example.edu.et", "Minor revision", "1980-01-01"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("public HTML leaks %q", hidden)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderHTML(&buf, sampleProfile(false)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Minor revision") {
+		t.Error("private HTML should include review recommendations")
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderPDF(&buf, sampleProfile(false), &Avatar{Data: []byte("not an image"), Type: "PNG"}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("output is not a PDF")
+	}
+}
@@ -0,0 +1,204 @@
+// Package rbac defines the permissions the API checks and the built-in roles
+// that grant them. Tenants add their own roles on top, stored in the roles
+// table, each with any set of these permissions.
+package rbac
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// Permissions
+const (
+	PaperSubmit      = "paper.submit"
+	PaperViewAll     = "paper.view_all"
+	PaperManageAll   = "paper.manage_all"
+	PaperEditDetails = "paper.edit_details"
+	PaperReview      = "paper.review"
+	PaperPublish     = "paper.publish"
+	PaperRetract     = "paper.retract"
+	PaperImport      = "paper.import"
+
+	AppealDecide = "appeal.decide"
+	AppealManage = "appeal.manage"
+
+	DocumentGenerate = "document.generate"
+	DocumentRevoke   = "document.revoke"
+
+	JournalManage       = "journal.manage"
+	EventPublish        = "event.publish"
+	NewsPublish         = "news.publish"
+	ReportView          = "report.view"
+	OrgUnitManage       = "orgunit.manage"
+	ApprovalChainManage = "approval_chain.manage"
+	// Decide approval steps meant for other roles or units
+	ApprovalDecideAny = "approval.decide_any"
+	// Ignore the user's scope unit and see every unit
+	ScopeAllUnits = "scope.all_units"
+
+	UserManage   = "user.manage"
+	RoleManage   = "role.manage"
+	TenantManage = "tenant.manage"
+)
+
+// Permission describes a permission for the admin screens
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions lists every permission
+var Permissions = []Permission{
+	{PaperSubmit, "Submit papers and manage one's own submissions"},
+	{PaperViewAll, "See every paper with its appeals and documents"},
+	{PaperManageAll, "Manage co-authors and imports of any author's papers"},
+	{PaperEditDetails, "Edit publication details and the unit of papers"},
+	{PaperReview, "Review papers and recommend them for publication"},
+	{PaperPublish, "Schedule papers and publish journal issues"},
+	{PaperRetract, "Retract published papers"},
+	{PaperImport, "Import papers in bulk"},
+	{AppealDecide, "Decide appeals as a panel member"},
+	{AppealManage, "See every appeal and assign appeal panels"},
+	{DocumentGenerate, "Generate acceptance letters and certificates"},
+	{DocumentRevoke, "Revoke generated documents"},
+	{JournalManage, "Manage journals, volumes and issues"},
+	{EventPublish, "Create and publish events"},
+	{NewsPublish, "Create and publish news"},
+	{ReportView, "View fiscal year and unit reports"},
+	{OrgUnitManage, "Manage the organizational hierarchy"},
+	{ApprovalChainManage, "Configure approval chains"},
+	{ApprovalDecideAny, "Decide any pending approval step"},
+	{ScopeAllUnits, "See every unit regardless of one's scope unit"},
+	{UserManage, "Create staff accounts, assign roles and unlock users"},
+	{RoleManage, "Create and change custom roles"},
+	{TenantManage, "Manage tenants of the deployment"},
+}
+
+// Built-in roles
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleCoordinator = "coordinator"
+	RoleAdmin       = "admin"
+	RoleSuperAdmin  = "super_admin"
+)
+
+// BuiltinRoles maps the roles defined in code to their permissions. They
+// cannot be changed or deleted, and custom roles cannot take their names.
+var BuiltinRoles = map[string][]string{
+	RoleAuthor: {PaperSubmit},
+	RoleEditor: {
+		PaperViewAll, PaperReview, PaperEditDetails, PaperPublish, AppealDecide,
+		DocumentGenerate, JournalManage, ReportView,
+	},
+	RoleCoordinator: {
+		PaperViewAll, PaperEditDetails, DocumentGenerate, EventPublish, NewsPublish, ReportView,
+	},
+	RoleAdmin:      tenantPermissions(),
+	RoleSuperAdmin: {TenantManage},
+}
+
+// BuiltinLabels names the built-in roles for display
+var BuiltinLabels = map[string]string{
+	RoleAuthor:      "Author",
+	RoleEditor:      "Editor",
+	RoleCoordinator: "Coordinator",
+	RoleAdmin:       "Admin",
+	RoleSuperAdmin:  "Super admin",
+}
+
+// tenantPermissions is every permission that stays within a tenant
+func tenantPermissions() []string {
+	var names []string
+	for _, p := range Permissions {
+		if p.Name != TenantManage {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// IsBuiltin reports whether the role is defined in code
+func IsBuiltin(role string) bool {
+	_, ok := BuiltinRoles[role]
+	return ok
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// ValidateRole checks the name and permissions of a custom role. Managing
+// tenants stays with the built-in super admin.
+func ValidateRole(name string, permissions []string) error {
+	if !roleNamePattern.MatchString(name) {
+		return fmt.Errorf("role names use lowercase letters, digits and underscores")
+	}
+	if IsBuiltin(name) {
+		return fmt.Errorf("%q is a built-in role", name)
+	}
+	for _, p := range permissions {
+		if p == TenantManage {
+			return fmt.Errorf("custom roles cannot manage tenants")
+		}
+		if !Known(p) {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	return nil
+}
+
+// Known reports whether the permission exists
+func Known(permission string) bool {
+	return slices.ContainsFunc(Permissions, func(p Permission) bool { return p.Name == permission })
+}
+
+// Normalize sorts permissions and removes duplicates
+func Normalize(permissions []string) []string {
+	out := slices.Clone(permissions)
+	sort.Strings(out)
+	return slices.Compact(out)
+}
+
+// Covers reports whether held includes every permission in wanted, so that
+// users cannot hand out more than they have
+func Covers(held, wanted []string) bool {
+	for _, p := range wanted {
+		if !slices.Contains(held, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// API token scopes. A token makes read requests with ScopeRead and any
+// other request with ScopeWrite; its permission scopes are what it lets
+// through of its owner's permissions.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// ValidateScopes checks the scopes of an API token
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("a token needs at least one scope")
+	}
+	for _, s := range scopes {
+		if s != ScopeRead && s != ScopeWrite && !Known(s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+// Intersect returns the permissions in held that allowed also lists
+func Intersect(held, allowed []string) []string {
+	var out []string
+	for _, p := range held {
+		if slices.Contains(allowed, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package rbac
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuiltinRolesUseKnownPermissions(t *testing.T) {
+	for role, perms := range BuiltinRoles {
+		for _, p := range perms {
+			if !Known(p) {
+				t.Errorf("role %s grants unknown permission %s", role, p)
+			}
+		}
+	}
+	if slices.Contains(BuiltinRoles[RoleAdmin], TenantManage) {
+		t.Error("tenant admins can manage tenants")
+	}
+	if !Covers(BuiltinRoles[RoleAdmin], BuiltinRoles[RoleEditor]) {
+		t.Error("admins lack an editor permission")
+	}
+}
+
+func TestValidateRole(t *testing.T) {
+	cases := []struct {
+		name  string
+		perms []string
+		ok    bool
+	}{
+		{"ethics_committee", []string{PaperReview, AppealDecide}, true},
+		{"dean", nil, true},
+		{"editor", []string{PaperReview}, false},
+		{"Ethics Committee", nil, false},
+		{"rogue", []string{TenantManage}, false},
+		{"typo", []string{"paper.reviw"}, false},
+	}
+	for _, c := range cases {
+		if err := ValidateRole(c.name, c.perms); (err == nil) != c.ok {
+			t.Errorf("ValidateRole(%q, %v) = %v", c.name, c.perms, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got := Normalize([]string{NewsPublish, EventPublish, NewsPublish})
+	if !slices.Equal(got, []string{EventPublish, NewsPublish}) {
+		t.Errorf("Normalize = %v", got)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	cases := []struct {
+		scopes []string
+		ok     bool
+	}{
+		{[]string{ScopeRead}, true},
+		{[]string{ScopeRead, ScopeWrite, UserManage}, true},
+		{nil, false},
+		{[]string{"admin"}, false},
+		{[]string{ScopeWrite, "user.mange"}, false},
+	}
+	for _, c := range cases {
+		if err := ValidateScopes(c.scopes); (err == nil) != c.ok {
+			t.Errorf("ValidateScopes(%v) = %v", c.scopes, err)
+		}
+	}
+	if got := Intersect([]string{PaperSubmit, UserManage, ReportView}, []string{ScopeRead, UserManage, TenantManage}); !slices.Equal(got, []string{UserManage}) {
+		t.Errorf("Intersect = %v", got)
+	}
+}
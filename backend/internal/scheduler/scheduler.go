@@ -0,0 +1,139 @@
+// Package scheduler runs background jobs against the database, such as
+// publishing papers whose scheduled publication time has come.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"rpms-backend/internal/database"
+	"rpms-backend/internal/ethcal"
+
+	"github.com/google/uuid"
+)
+
+type Scheduler struct {
+	db       *database.Database
+	interval time.Duration
+}
+
+func New(db *database.Database, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Scheduler{db: db, interval: interval}
+}
+
+// Start runs the jobs every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.PublishScheduledPapers(ctx); err != nil {
+			log.Printf("[scheduler] publishing scheduled papers: %v", err)
+		} else if n > 0 {
+			log.Printf("[scheduler] published %d scheduled papers", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduledPaper is a paper with a scheduled publication time
+type scheduledPaper struct {
+	id, authorID    uuid.UUID
+	tenantID        uuid.UUID
+	title           string
+	status          string
+	publishAt       time.Time
+	publicationDate *time.Time
+}
+
+// publication is what a paper gets when the scheduler publishes it
+type publication struct {
+	date       time.Time
+	fiscalYear string
+}
+
+// duePublication says whether the paper is to be published at now. Only
+// approved papers are; others were published, withdrawn or sent back since
+// they were scheduled. The paper keeps its publication date if it has one,
+// otherwise it gets the scheduled time.
+func duePublication(p scheduledPaper, now time.Time) (publication, bool) {
+	if p.status != "approved" || p.publishAt.After(now) {
+		return publication{}, false
+	}
+	date := p.publishAt
+	if p.publicationDate != nil {
+		date = *p.publicationDate
+	}
+	return publication{date: date, fiscalYear: ethcal.FiscalYearLabel(ethcal.FiscalYear(date))}, true
+}
+
+// PublishScheduledPapers publishes every approved paper whose scheduled time
+// has passed, in every tenant. Rows locked by another instance are skipped.
+func (s *Scheduler) PublishScheduledPapers(ctx context.Context) (int, error) {
+	now := time.Now()
+	tx, err := s.db.BeginTx(database.WithoutTenant(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, author_id, tenant_id, title, status, scheduled_publish_at, publication_date
+		FROM papers
+		WHERE status = 'approved' AND scheduled_publish_at <= $1
+		FOR UPDATE SKIP LOCKED
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var scheduled []scheduledPaper
+	for rows.Next() {
+		var p scheduledPaper
+		if err := rows.Scan(&p.id, &p.authorID, &p.tenantID, &p.title, &p.status, &p.publishAt, &p.publicationDate); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		scheduled = append(scheduled, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, p := range scheduled {
+		pub, due := duePublication(p, now)
+		if !due {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE papers
+			SET status = 'published', publication_date = $2, fiscal_year = $3, scheduled_publish_at = NULL, updated_at = NOW()
+			WHERE id = $1
+		`, p.id, pub.date, pub.fiscalYear); err != nil {
+			return 0, fmt.Errorf("paper %s: %w", p.id, err)
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO notifications (user_id, message, paper_id, tenant_id) VALUES ($1, $2, $3, $4)",
+			p.authorID, fmt.Sprintf("Your paper '%s' has been published", p.title), p.id, p.tenantID); err != nil {
+			return 0, fmt.Errorf("paper %s: %w", p.id, err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"rpms-backend/internal/ethcal"
+)
+
+func TestDuePublication(t *testing.T) {
+	now := time.Date(2024, 7, 8, 9, 0, 0, 0, time.UTC)
+	earlier := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		paper scheduledPaper
+		due   bool
+		date  time.Time
+		// Ethiopian fiscal year of the publication
+		year int
+	}{
+		{"due", scheduledPaper{status: "approved", publishAt: now.Add(-time.Minute)}, true, now.Add(-time.Minute), 2017},
+		{"due now", scheduledPaper{status: "approved", publishAt: now}, true, now, 2017},
+		{"due with publication date", scheduledPaper{status: "approved", publishAt: now.Add(-time.Hour), publicationDate: &earlier}, true, earlier, 2016},
+		{"not yet due", scheduledPaper{status: "approved", publishAt: now.Add(time.Minute)}, false, time.Time{}, 0},
+		{"already published", scheduledPaper{status: "published", publishAt: now.Add(-time.Hour)}, false, time.Time{}, 0},
+		{"withdrawn", scheduledPaper{status: "withdrawn", publishAt: now.Add(-time.Hour)}, false, time.Time{}, 0},
+		{"back under review", scheduledPaper{status: "under_review", publishAt: now.Add(-time.Hour)}, false, time.Time{}, 0},
+	}
+	for _, c := range cases {
+		pub, due := duePublication(c.paper, now)
+		if due != c.due || !pub.date.Equal(c.date) {
+			t.Errorf("%s: due %v on %v, want %v on %v", c.name, due, pub.date, c.due, c.date)
+			continue
+		}
+		if due {
+			if want := ethcal.FiscalYearLabel(c.year); pub.fiscalYear != want {
+				t.Errorf("%s: fiscal year %q, want %q", c.name, pub.fiscalYear, want)
+			}
+		}
+	}
+}
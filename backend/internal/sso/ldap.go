@@ -0,0 +1,135 @@
+package sso
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig describes the directory and where users are found in it
+type LDAPConfig struct {
+	// Server URL such as ldaps://ldap.example.edu or ldap://ldap.example.edu:389
+	URL string
+	// Upgrade an ldap:// connection with StartTLS
+	StartTLS bool
+	// Account used to search for users; anonymous when empty
+	BindDN       string
+	BindPassword string
+	// Subtree users are searched in
+	BaseDN string
+	// Filter finding a user by the name they sign in with, every %s being
+	// replaced by it; (|(uid=%s)(mail=%s)) by default
+	UserFilter string
+	// Attributes holding the email, the display name and the groups;
+	// mail, cn and memberOf by default
+	EmailAttribute string
+	NameAttribute  string
+	GroupAttribute string
+}
+
+// LDAP signs users in by binding as them
+type LDAP struct {
+	config LDAPConfig
+}
+
+// NewLDAP returns a client for the directory
+func NewLDAP(cfg LDAPConfig) *LDAP {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(|(uid=%s)(mail=%s))"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "cn"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	return &LDAP{config: cfg}
+}
+
+// Authenticate finds the user and checks the password by binding as them
+func (l *LDAP) Authenticate(username, password string) (*Identity, error) {
+	username = strings.TrimSpace(username)
+	// An empty password would be an unauthenticated bind, which directories
+	// accept without checking anything
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if l.config.BindDN != "" {
+		if err := conn.Bind(l.config.BindDN, l.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind as the search account: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(l.config.UserFilter, "%s", ldap.EscapeFilter(username))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false, filter,
+		[]string{l.config.EmailAttribute, l.config.NameAttribute, l.config.GroupAttribute}, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search for the user: %w", err)
+	}
+	// Nobody, or more than one account, answers to the name
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind as the user: %w", err)
+	}
+
+	return &Identity{
+		Provider: ProviderLDAP,
+		Subject:  entry.DN,
+		Email:    strings.ToLower(strings.TrimSpace(entry.GetAttributeValue(l.config.EmailAttribute))),
+		Name:     strings.TrimSpace(entry.GetAttributeValue(l.config.NameAttribute)),
+		Groups:   entry.GetAttributeValues(l.config.GroupAttribute),
+	}, nil
+}
+
+func (l *LDAP) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(l.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+
+	conn, err := ldap.DialURL(l.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the directory: %w", err)
+	}
+	conn.SetTimeout(10 * time.Second)
+
+	if l.config.StartTLS {
+		if u.Scheme == "ldaps" {
+			conn.Close()
+			return nil, errors.New("StartTLS is for ldap:// URLs; ldaps:// is already encrypted")
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	return conn, nil
+}
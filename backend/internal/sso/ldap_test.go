@@ -0,0 +1,223 @@
+package sso
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+type testEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// testLDAPServer is an in-process directory answering simple binds and
+// searches with equality, and and or filters
+type testLDAPServer struct {
+	listener net.Listener
+	entries  []testEntry
+}
+
+func newTestLDAPServer(t *testing.T, entries ...testEntry) *testLDAPServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testLDAPServer{listener: l, entries: entries}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testLDAPServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if dn == "" && password == "" {
+				code = ldap.LDAPResultSuccess
+			}
+			for _, e := range s.entries {
+				if e.dn == dn && e.password != "" && e.password == password {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			conn.Write(ldapResponse(id, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationSearchRequest:
+			filter := op.Children[6]
+			for _, e := range s.entries {
+				if matchFilter(filter, e) {
+					conn.Write(searchEntry(id, e).Bytes())
+				}
+			}
+			conn.Write(ldapResponse(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+		default:
+			return
+		}
+	}
+}
+
+func matchFilter(f *ber.Packet, e testEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if !matchFilter(child, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			if matchFilter(child, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterEqualityMatch:
+		attr, value := f.Children[0].Data.String(), f.Children[1].Data.String()
+		for name, values := range e.attrs {
+			if strings.EqualFold(name, attr) && slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	p.AppendChild(op)
+	return p
+}
+
+func ldapResponse(id int64, tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return ldapMessage(id, op)
+}
+
+func searchEntry(id int64, e testEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range e.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return ldapMessage(id, op)
+}
+
+var testDirectory = []testEntry{
+	{
+		dn:       "cn=search,dc=example,dc=edu",
+		password: "search-secret",
+	},
+	{
+		dn:       "uid=abebe,ou=people,dc=example,dc=edu",
+		password: "correct horse",
+		attrs: map[string][]string{
+			"uid":      {"abebe"},
+			"mail":     {"Abebe@example.edu"},
+			"cn":       {"Abebe Kebede"},
+			"memberOf": {"cn=rpms-editors,ou=groups,dc=example,dc=edu", "cn=staff,ou=groups,dc=example,dc=edu"},
+		},
+	},
+	// Two accounts answer to the same email
+	{dn: "uid=twin1,ou=people,dc=example,dc=edu", password: "x", attrs: map[string][]string{"uid": {"twin1"}, "mail": {"twin@example.edu"}}},
+	{dn: "uid=twin2,ou=people,dc=example,dc=edu", password: "x", attrs: map[string][]string{"uid": {"twin2"}, "mail": {"twin@example.edu"}}},
+}
+
+func newTestLDAP(server *testLDAPServer) *LDAP {
+	return NewLDAP(LDAPConfig{
+		URL:          server.URL(),
+		BindDN:       "cn=search,dc=example,dc=edu",
+		BindPassword: "search-secret",
+		BaseDN:       "dc=example,dc=edu",
+	})
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	l := newTestLDAP(newTestLDAPServer(t, testDirectory...))
+
+	for _, username := range []string{"abebe", "abebe@example.edu"} {
+		id, err := l.Authenticate(username, "correct horse")
+		if err != nil {
+			t.Fatalf("Authenticate(%q): %v", username, err)
+		}
+		if id.Provider != ProviderLDAP || id.Subject != "uid=abebe,ou=people,dc=example,dc=edu" ||
+			id.Email != "abebe@example.edu" || id.Name != "Abebe Kebede" || len(id.Groups) != 2 {
+			t.Errorf("identity = %+v", id)
+		}
+	}
+}
+
+func TestLDAPRejectsBadCredentials(t *testing.T) {
+	l := newTestLDAP(newTestLDAPServer(t, testDirectory...))
+
+	tests := []struct{ username, password string }{
+		{"abebe", "wrong"},
+		{"nobody", "correct horse"},
+		// The server accepts anonymous binds, which must not count as a password
+		{"abebe", ""},
+		{"", ""},
+		// Filter syntax in the name is matched literally
+		{"*", "x"},
+		{"abebe)(uid=*", "correct horse"},
+		// Ambiguous names sign nobody in
+		{"twin@example.edu", "x"},
+	}
+	for _, tt := range tests {
+		if _, err := l.Authenticate(tt.username, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%q, %q) = %v, want ErrInvalidCredentials", tt.username, tt.password, err)
+		}
+	}
+}
+
+func TestLDAPSearchAccountMustBind(t *testing.T) {
+	server := newTestLDAPServer(t, testDirectory...)
+	l := NewLDAP(LDAPConfig{URL: server.URL(), BindDN: "cn=search,dc=example,dc=edu", BindPassword: "wrong", BaseDN: "dc=example,dc=edu"})
+
+	_, err := l.Authenticate("abebe", "correct horse")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want a configuration error", err)
+	}
+}
@@ -0,0 +1,357 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrEmailNotVerified is returned when the provider does not vouch for the
+// user's email, so it cannot be trusted to pick their account
+var ErrEmailNotVerified = errors.New("the identity provider has not verified this email address")
+
+// OIDCConfig describes the OpenID Connect provider and this client's
+// registration with it
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// This API's callback URL, as registered with the provider
+	RedirectURL string
+	// Scopes to request; openid, email and profile by default
+	Scopes []string
+	// Claim listing the user's groups; groups by default
+	GroupsClaim string
+	// Accept emails from a provider that leaves out the email_verified
+	// claim. Only for providers that verify every email they hand out.
+	TrustUnverifiedEmail bool
+}
+
+// OIDC signs users in with the authorization code flow and PKCE. The
+// provider's configuration and keys are fetched when first needed, so the
+// API starts even while the provider is down.
+type OIDC struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	endpoints   *oidcEndpoints
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+type oidcEndpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDC returns a client for the provider. A nil http client uses one with
+// a 10 second timeout.
+func NewOIDC(cfg OIDCConfig, client *http.Client) *OIDC {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDC{config: cfg, client: client}
+}
+
+// NewVerifier returns a random PKCE code verifier. It also serves for the
+// state and nonce of a sign-in.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge is the S256 PKCE challenge of a verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider page to send the user to. The state
+// comes back on the callback; the nonce comes back in the ID token.
+func (o *OIDC) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	endpoints, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.config.ClientID},
+		"redirect_uri":          {o.config.RedirectURL},
+		"scope":                 {strings.Join(o.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(endpoints.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return endpoints.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades the code of a callback for the user's identity, checking
+// the ID token's signature, issuer, audience, expiry and nonce
+func (o *OIDC) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	endpoints, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {o.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+
+	var tokens struct {
+		AccessToken      string `json:"access_token"`
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := o.getJSON(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if status != http.StatusOK {
+		if tokens.Error == "invalid_grant" {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", status, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("the provider returned no ID token")
+	}
+
+	claims, err := o.verifyIDToken(ctx, endpoints, tokens.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("invalid ID token: nonce does not match")
+	}
+
+	// Providers may leave the email and groups to the userinfo endpoint
+	if _, ok := claims["email"]; !ok && endpoints.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		info, err := o.userinfo(ctx, endpoints.UserinfoEndpoint, tokens.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if info["sub"] != claims["sub"] {
+			return nil, errors.New("userinfo is for another subject")
+		}
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	return o.identity(claims)
+}
+
+func (o *OIDC) identity(claims jwt.MapClaims) (*Identity, error) {
+	str := func(key string) string {
+		v, _ := claims[key].(string)
+		return strings.TrimSpace(v)
+	}
+	verified, ok := claims["email_verified"].(bool)
+	if !verified && (ok || !o.config.TrustUnverifiedEmail) {
+		return nil, ErrEmailNotVerified
+	}
+
+	id := &Identity{
+		Provider: ProviderOIDC,
+		Subject:  str("sub"),
+		Email:    strings.ToLower(str("email")),
+		Name:     str("name"),
+	}
+	if id.Name == "" {
+		id.Name = strings.TrimSpace(str("given_name") + " " + str("family_name"))
+	}
+	switch groups := claims[o.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	case string:
+		id.Groups = strings.Fields(groups)
+	}
+	if id.Subject == "" {
+		return nil, errors.New("the ID token has no subject")
+	}
+	return id, nil
+}
+
+func (o *OIDC) verifyIDToken(ctx context.Context, endpoints *oidcEndpoints, raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.key(ctx, endpoints, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(endpoints.Issuer),
+		jwt.WithAudience(o.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	return claims, err
+}
+
+// key returns the provider's signing key with the ID. Keys are fetched
+// again for an unknown ID, at most once a minute, to follow key rotation.
+func (o *OIDC) key(ctx context.Context, endpoints *oidcEndpoints, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lookup := func() *rsa.PublicKey {
+		if kid == "" && len(o.keys) == 1 {
+			for _, k := range o.keys {
+				return k
+			}
+		}
+		return o.keys[kid]
+	}
+	if k := lookup(); k != nil {
+		return k, nil
+	}
+	if time.Since(o.keysFetched) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoints.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := o.getJSON(req, &set)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing keys (status %d): %v", status, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	o.keys, o.keysFetched = keys, time.Now()
+
+	if k := lookup(); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (o *OIDC) userinfo(ctx context.Context, endpoint, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	info := map[string]interface{}{}
+	status, err := o.getJSON(req, &info)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("userinfo request failed (status %d): %v", status, err)
+	}
+	return info, nil
+}
+
+// discover fetches the provider's configuration once
+func (o *OIDC) discover(ctx context.Context) (*oidcEndpoints, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.endpoints != nil {
+		return o.endpoints, nil
+	}
+
+	issuer := strings.TrimSuffix(o.config.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints oidcEndpoints
+	status, err := o.getJSON(req, &endpoints)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the provider configuration (status %d): %v", status, err)
+	}
+	if strings.TrimSuffix(endpoints.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("the provider says its issuer is %q, not %q", endpoints.Issuer, o.config.Issuer)
+	}
+	if endpoints.AuthorizationEndpoint == "" || endpoints.TokenEndpoint == "" || endpoints.JWKSURI == "" {
+		return nil, errors.New("the provider configuration lacks an endpoint")
+	}
+	o.endpoints = &endpoints
+	return o.endpoints, nil
+}
+
+func (o *OIDC) getJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
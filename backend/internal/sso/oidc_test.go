@@ -0,0 +1,258 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIdP is a stand-in OpenID Connect provider. It approves every
+// authorization request and issues ID tokens with the claims it is given.
+type testIdP struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+
+	mu    sync.Mutex
+	codes map[string]url.Values // code -> authorization request
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{key: key, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "use": "sig", "kid": "test-key",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		code := "code-" + q.Get("state")
+		idp.mu.Lock()
+		idp.codes[code] = q
+		idp.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		auth, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+
+		id, secret, _ := r.BasicAuth()
+		switch {
+		case id != "rpms" || secret != "s3cret":
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		case !ok || auth.Get("code_challenge_method") != "S256" ||
+			codeChallenge(r.PostForm.Get("code_verifier")) != auth.Get("code_challenge") ||
+			r.PostForm.Get("redirect_uri") != auth.Get("redirect_uri"):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   "rpms",
+			"sub":   "user-42",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": auth.Get("nonce"),
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(t, claims),
+		})
+	})
+	return idp
+}
+
+func (idp *testIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// authorize follows the authorization URL as a browser would and returns
+// the code and state of the callback
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(callback.String(), "https://rpms.example.edu/callback?") {
+		t.Fatalf("redirected to %s", callback)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func newTestOIDC(idp *testIdP) *OIDC {
+	return NewOIDC(OIDCConfig{
+		Issuer:       idp.URL,
+		ClientID:     "rpms",
+		ClientSecret: "s3cret",
+		RedirectURL:  "https://rpms.example.edu/callback",
+	}, nil)
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.claims = jwt.MapClaims{
+		"email": "Abebe@Example.edu", "email_verified": true, "name": "Abebe Kebede",
+		"groups": []string{"staff", "rpms-editors"},
+	}
+	o := newTestOIDC(idp)
+	ctx := context.Background()
+
+	verifier, _ := NewVerifier()
+	authURL, err := o.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	id, err := o.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Provider != ProviderOIDC || id.Subject != "user-42" || id.Email != "abebe@example.edu" || id.Name != "Abebe Kebede" {
+		t.Errorf("identity = %+v", id)
+	}
+	if len(id.Groups) != 2 || id.Groups[1] != "rpms-editors" {
+		t.Errorf("groups = %q", id.Groups)
+	}
+
+	// A code works once
+	if _, err := o.Exchange(ctx, code, verifier, "nonce-1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("second exchange: %v", err)
+	}
+}
+
+func TestOIDCRejectsWrongVerifier(t *testing.T) {
+	idp := newTestIdP(t)
+	o := newTestOIDC(idp)
+	ctx := context.Background()
+
+	verifier, _ := NewVerifier()
+	authURL, _ := o.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	code, _ := authorize(t, authURL)
+
+	other, _ := NewVerifier()
+	if _, err := o.Exchange(ctx, code, other, "nonce-1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("exchange with another verifier: %v", err)
+	}
+}
+
+func TestOIDCRejectsBadIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+		forged bool
+		want   error
+	}{
+		{name: "wrong nonce", nonce: "another-nonce"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "someone-else"}},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example"}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "unverified email", claims: jwt.MapClaims{"email": "a@example.edu", "email_verified": false}, want: ErrEmailNotVerified},
+		{name: "email not marked verified", claims: jwt.MapClaims{"email": "a@example.edu"}, want: ErrEmailNotVerified},
+		{name: "forged signature", forged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			idp.claims = tt.claims
+			if tt.forged {
+				idp.key = otherKey
+			}
+			o := newTestOIDC(idp)
+			ctx := context.Background()
+
+			verifier, _ := NewVerifier()
+			authURL, _ := o.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+			code, _ := authorize(t, authURL)
+			nonce := "nonce-1"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			_, err := o.Exchange(ctx, code, verifier, nonce)
+			if err == nil {
+				t.Fatal("exchange succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCTrustUnverifiedEmail(t *testing.T) {
+	o := NewOIDC(OIDCConfig{TrustUnverifiedEmail: true}, nil)
+	id, err := o.identity(jwt.MapClaims{"sub": "user-42", "email": "A@example.edu"})
+	if err != nil || id.Email != "a@example.edu" {
+		t.Fatalf("identity = %+v, %v", id, err)
+	}
+	// A provider that says the email is unverified is still refused
+	if _, err := o.identity(jwt.MapClaims{"email": "a@example.edu", "email_verified": false}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("err = %v, want %v", err, ErrEmailNotVerified)
+	}
+}
@@ -0,0 +1,86 @@
+// Package sso signs users in through the university directory: an OpenID
+// Connect provider or an LDAP server.
+package sso
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Providers an identity can come from
+const (
+	ProviderOIDC = "oidc"
+	ProviderLDAP = "ldap"
+)
+
+// ErrInvalidCredentials is returned when the directory rejects the user
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Identity is a user as the directory describes them
+type Identity struct {
+	Provider string
+	// Subject identifies the user at the provider and does not change
+	// when their email does
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// GroupRole gives members of a directory group an RPMS role
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// GroupRoles maps directory groups to roles. Earlier entries win the
+// primary role.
+type GroupRoles []GroupRole
+
+// ParseGroupRoles reads group:role pairs separated by semicolons, such as
+// "cn=rpms-editors,ou=groups,dc=example,dc=edu:editor;rpms-admins:admin".
+// The role follows the last colon, so groups may be distinguished names.
+func ParseGroupRoles(raw string) (GroupRoles, error) {
+	var mapping GroupRoles
+	for _, pair := range strings.Split(raw, ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 || i == len(pair)-1 {
+			return nil, fmt.Errorf("group role %q is not group:role", pair)
+		}
+		mapping = append(mapping, GroupRole{
+			Group: strings.TrimSpace(pair[:i]),
+			Role:  strings.TrimSpace(pair[i+1:]),
+		})
+	}
+	return mapping, nil
+}
+
+// Roles returns the roles the groups map to, without repeats, in the order
+// of the mapping. Groups match case-insensitively, either whole or, for a
+// distinguished name such as cn=editors,ou=groups, by its first value.
+func (m GroupRoles) Roles(groups []string) []string {
+	member := map[string]bool{}
+	for _, g := range groups {
+		g = strings.ToLower(strings.TrimSpace(g))
+		member[g] = true
+		if name, _, ok := strings.Cut(g, ","); ok || strings.Contains(g, "=") {
+			if _, value, ok := strings.Cut(name, "="); ok {
+				member[strings.TrimSpace(value)] = true
+			}
+		}
+	}
+
+	var roles []string
+	seen := map[string]bool{}
+	for _, gr := range m {
+		if member[strings.ToLower(gr.Group)] && !seen[gr.Role] {
+			seen[gr.Role] = true
+			roles = append(roles, gr.Role)
+		}
+	}
+	return roles
+}
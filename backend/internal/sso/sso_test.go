@@ -0,0 +1,42 @@
+package sso
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGroupRoles(t *testing.T) {
+	mapping, err := ParseGroupRoles("rpms-admins:admin; cn=RPMS Editors,ou=groups,dc=example,dc=edu:editor ;editors:editor;staff:coordinator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping) != 4 || mapping[1].Group != "cn=RPMS Editors,ou=groups,dc=example,dc=edu" || mapping[1].Role != "editor" {
+		t.Fatalf("parsed %+v", mapping)
+	}
+
+	tests := []struct {
+		groups []string
+		want   []string
+	}{
+		{nil, nil},
+		{[]string{"students"}, nil},
+		{[]string{"STAFF", "rpms-admins"}, []string{"admin", "coordinator"}},
+		// A distinguished name matches whole or by its first value
+		{[]string{"cn=rpms editors,ou=Groups,dc=example,dc=edu"}, []string{"editor"}},
+		{[]string{"cn=staff,ou=groups,dc=example,dc=edu"}, []string{"coordinator"}},
+		{[]string{"cn=editors,ou=groups", "editors"}, []string{"editor"}},
+	}
+	for _, tt := range tests {
+		if got := mapping.Roles(tt.groups); !slices.Equal(got, tt.want) {
+			t.Errorf("Roles(%q) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
+
+func TestParseGroupRolesRejectsPairsWithoutRole(t *testing.T) {
+	for _, raw := range []string{"admins", "admins:", ":admin"} {
+		if _, err := ParseGroupRoles(raw); err == nil {
+			t.Errorf("ParseGroupRoles(%q) succeeded", raw)
+		}
+	}
+}
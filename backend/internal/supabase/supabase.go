@@ -205,7 +205,14 @@ func (s *Client) SignIn(email, password string) (*SignInResponse, error) {
 	if resp.StatusCode != http.StatusOK {
 		var errResp map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("supabase signin failed: %v", errResp)
+
+		msg := "unknown error"
+		if m, ok := errResp["msg"].(string); ok {
+			msg = m
+		} else if m, ok := errResp["error_description"].(string); ok {
+			msg = m
+		}
+		return nil, &SupabaseError{StatusCode: resp.StatusCode, Message: msg}
 	}
 
 	var result SignInResponse
@@ -262,3 +269,53 @@ func (s *Client) AdminCreateUser(email, password string, userMetadata map[string
 
 	return &result, nil
 }
+
+type AdminUpdateUserRequest struct {
+	Password     string                 `json:"password,omitempty"`
+	UserMetadata map[string]interface{} `json:"user_metadata,omitempty"`
+	// How long the user cannot sign in, e.g. "876000h", or "none" to lift a ban
+	BanDuration string `json:"ban_duration,omitempty"`
+}
+
+func (s *Client) AdminUpdatePassword(userID, password string) error {
+	return s.AdminUpdateUser(userID, AdminUpdateUserRequest{Password: password})
+}
+
+// AdminSetBanned bans a user from signing in indefinitely, or lifts the ban
+func (s *Client) AdminSetBanned(userID string, banned bool) error {
+	duration := "none"
+	if banned {
+		duration = "876000h"
+	}
+	return s.AdminUpdateUser(userID, AdminUpdateUserRequest{BanDuration: duration})
+}
+
+// AdminUpdateUser changes the fields of a user set in the request
+func (s *Client) AdminUpdateUser(userID string, update AdminUpdateUserRequest) error {
+	url := fmt.Sprintf("%s/auth/v1/admin/users/%s", s.config.Supabase.URL, userID)
+	reqBody, _ := json.Marshal(update)
+
+	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+
+	// Use Service Role Key for Admin operations
+	req.Header.Set("apikey", s.config.Supabase.ServiceRoleKey)
+	req.Header.Set("Authorization", "Bearer "+s.config.Supabase.ServiceRoleKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("supabase admin update user failed (status %d): %v", resp.StatusCode, errResp)
+	}
+
+	return nil
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"rpms-backend/internal/api"
 	"rpms-backend/internal/config"
 	"rpms-backend/internal/database"
+	"rpms-backend/internal/scheduler"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -28,6 +31,9 @@ func main() {
 
 	// Initialize configuration
 	cfg := config.New()
+	if err := cfg.RequireDocSigningKey(); err != nil {
+		log.Fatal(err)
+	}
 
 	// Check if we're in demo mode (no database)
 	demoMode := os.Getenv("DEMO_MODE") == "true"
@@ -46,10 +52,23 @@ func main() {
 		}
 		defer db.Close()
 
+		// Tenants are kept apart by row level security alone
+		if err := db.RequireRLS(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+
 		// Run database migrations
 		if err := database.RunMigrations(db); err != nil {
 			log.Fatal("Failed to run migrations:", err)
 		}
+
+		// Publish scheduled papers in the background
+		interval, err := time.ParseDuration(cfg.SchedulerInterval)
+		if err != nil {
+			log.Printf("Invalid SCHEDULER_INTERVAL %q, using 1m", cfg.SchedulerInterval)
+			interval = time.Minute
+		}
+		go scheduler.New(db, interval).Start(context.Background())
 	}
 
 	// Initialize Gin router